@@ -0,0 +1,108 @@
+package app
+
+import (
+	"cleancode/config"
+	"cleancode/lib/databases"
+	"cleancode/lib/search"
+	"cleancode/middlewares"
+	"cleancode/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+// App holds everything one running instance of the API needs. Nothing is
+// shared between apps, so several can be served from one process, each on
+// its own database.
+type App struct {
+	Config config.Config
+	DB     *gorm.DB
+	Logger echo.Logger
+	Index  search.BookIndex
+
+	Books         databases.BookRepository
+	Copies        databases.BookCopyRepository
+	Authors       databases.AuthorRepository
+	Loans         databases.LoanRepository
+	Reservations  databases.ReservationRepository
+	Fines         databases.FineRepository
+	Reviews       databases.ReviewRepository
+	Lists         databases.ReadingListRepository
+	Users         databases.UserRepository
+	RefreshTokens databases.RefreshTokenRepository
+	Tokens        *middlewares.TokenManager
+}
+
+// New wires the GORM repositories, the book search index and the token
+// manager for cfg around db. An index that is empty, such as a new in-memory
+// one, is filled from db. The logger can be replaced before the app is
+// routed.
+func New(cfg config.Config, db *gorm.DB) (*App, error) {
+	tokens, err := middlewares.NewTokenManager(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := openIndex(cfg.SearchIndexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	books := databases.NewGormBookRepository(db, index)
+	count, err := index.Count()
+	if err == nil && count == 0 {
+		err = books.Reindex()
+	}
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	return &App{
+		Config: cfg,
+		DB:     db,
+		Logger: log.New("cleancode"),
+		Index:  index,
+
+		Books:         books,
+		Copies:        databases.NewGormBookCopyRepository(db, cfg.Loans.HoldPeriod),
+		Authors:       databases.NewGormAuthorRepository(db, index),
+		Loans:         databases.NewGormLoanRepository(db, loanPolicy(cfg.Loans, cfg.Fines)),
+		Reservations:  databases.NewGormReservationRepository(db, cfg.Loans.HoldPeriod),
+		Fines:         databases.NewGormFineRepository(db, finePolicy(cfg.Fines)),
+		Reviews:       databases.NewGormReviewRepository(db),
+		Lists:         databases.NewGormReadingListRepository(db),
+		Users:         databases.NewGormUserRepository(db),
+		RefreshTokens: databases.NewGormRefreshTokenRepository(db),
+		Tokens:        tokens,
+	}, nil
+}
+
+// Close releases the search index. The database is left open for its owner.
+func (a *App) Close() error {
+	return a.Index.Close()
+}
+
+func openIndex(path string) (search.BookIndex, error) {
+	if path == "" {
+		return search.NewMemoryIndex()
+	}
+	return search.OpenIndex(path)
+}
+
+func loanPolicy(cfg config.LoanConfig, fines config.FineConfig) databases.LoanPolicy {
+	return databases.LoanPolicy{
+		Period:     cfg.Period,
+		Limit:      cfg.Limit,
+		HoldPeriod: cfg.HoldPeriod,
+		Fines:      finePolicy(fines),
+	}
+}
+
+func finePolicy(cfg config.FineConfig) databases.FinePolicy {
+	return databases.FinePolicy{
+		Fees:  models.FeeSchedule{PerDay: cfg.PerDay, Cap: cfg.Cap},
+		Limit: cfg.Limit,
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"cleancode/config"
+	"cleancode/lib/databases"
+	"cleancode/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var configTest = config.Config{
+	JWT: config.JWTConfig{
+		Secret:          "0123456789abcdef0123456789abcdef",
+		Issuer:          "cleancode-test",
+		AccessTokenTTL:  time.Hour * 1,
+		RefreshTokenTTL: time.Hour * 24,
+	},
+}
+
+func TestNewWiresDependencies(t *testing.T) {
+	a, err := New(configTest, config.InitDbTest())
+	if assert.NoError(t, err) {
+		assert.Equal(t, configTest, a.Config)
+		assert.NotNil(t, a.Logger)
+		assert.NotNil(t, a.Index)
+		assert.NotNil(t, a.Books)
+		assert.NotNil(t, a.Users)
+		assert.NotNil(t, a.RefreshTokens)
+		assert.NotNil(t, a.Tokens)
+	}
+}
+
+func TestNewAppsAreIndependent(t *testing.T) {
+	first, err := New(configTest, config.InitDbTest())
+	assert.NoError(t, err)
+	second, err := New(configTest, config.InitDbTest())
+	assert.NoError(t, err)
+
+	assert.NotSame(t, first.Tokens, second.Tokens)
+	assert.NotSame(t, first.Books, second.Books)
+}
+
+func TestNewRejectsInvalidJWTConfig(t *testing.T) {
+	cfg := configTest
+	cfg.JWT.SigningKeyFile = "testdata/missing.pem"
+
+	_, err := New(cfg, config.InitDbTest())
+	assert.Error(t, err)
+}
+
+func TestNewIndexesStoredBooks(t *testing.T) {
+	db := config.InitDbTest()
+	db.Create(&models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")})
+
+	cfg := configTest
+	cfg.SearchIndexPath = filepath.Join(t.TempDir(), "search")
+	a, err := New(cfg, db)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	matches, _, err := a.Books.Search("chemistry", databases.ListOptions{})
+	if assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "chemistry", matches[0].Book.Title)
+	}
+	assert.NoError(t, a.Close())
+
+	// a stored index is reused as it is
+	db.Create(&models.Book{Title: "chemistry basics", Author: "alta", Published_at: models.MustParseDate("2021-01-01")})
+	a, err = New(cfg, db)
+	if assert.NoError(t, err) {
+		count, err := a.Index.Count()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), count)
+		assert.NoError(t, a.Close())
+	}
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// minSecretLength is the shortest HS256 secret accepted, matching the size of
+// the SHA-256 output so the key is not the weakest link.
+const minSecretLength = 32
+
+// JWTConfig describes how access tokens are signed. Tokens are signed with
+// the PEM private key in SigningKeyFile when it is set and with the HS256
+// Secret otherwise; VerificationKeyFiles hold retired public keys that are
+// still accepted during a key rotation.
+type JWTConfig struct {
+	Secret               string        `yaml:"secret" toml:"secret"`
+	SigningKeyFile       string        `yaml:"signing_key_file" toml:"signing_key_file"`
+	VerificationKeyFiles []string      `yaml:"verification_key_files" toml:"verification_key_files"`
+	Issuer               string        `yaml:"issuer" toml:"issuer"`
+	Audience             string        `yaml:"audience" toml:"audience"`
+	AccessTokenTTL       time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL      time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+}
+
+// LoanConfig is the lending policy: how long a book may be kept, how many
+// books one user may have out at once and how long a returned copy is held
+// for the user who reserved it.
+type LoanConfig struct {
+	Period     time.Duration `yaml:"period" toml:"period"`
+	Limit      int           `yaml:"limit" toml:"limit"`
+	HoldPeriod time.Duration `yaml:"hold_period" toml:"hold_period"`
+}
+
+// FineConfig is the fee schedule for overdue loans, in the library's
+// currency: PerDay for every started day a loan is overdue, up to Cap per
+// loan, or without a cap when Cap is zero. Members owing more than Limit
+// cannot check out books. Amounts are written as decimal strings, such as
+// "0.25", so they are read exactly.
+type FineConfig struct {
+	PerDay decimal.Decimal `yaml:"per_day" toml:"per_day"`
+	Cap    decimal.Decimal `yaml:"cap" toml:"cap"`
+	Limit  decimal.Decimal `yaml:"limit" toml:"limit"`
+}
+
+type Config struct {
+	ListenAddress string     `yaml:"listen_address" toml:"listen_address"`
+	DatabaseDSN   string     `yaml:"database_dsn" toml:"database_dsn"`
+	BcryptCost    int        `yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	JWT           JWTConfig  `yaml:"jwt" toml:"jwt"`
+	Loans         LoanConfig `yaml:"loans" toml:"loans"`
+	Fines         FineConfig `yaml:"fines" toml:"fines"`
+
+	// SearchIndexPath is the directory of the book search index. When it is
+	// empty the index is kept in memory and rebuilt on every start.
+	SearchIndexPath string `yaml:"search_index_path" toml:"search_index_path"`
+}
+
+func Default() Config {
+	return Config{
+		ListenAddress: ":8000",
+		BcryptCost:    bcrypt.DefaultCost,
+		JWT: JWTConfig{
+			Issuer:          "cleancode",
+			AccessTokenTTL:  time.Hour * 1,
+			RefreshTokenTTL: time.Hour * 24 * 30,
+		},
+		Loans: LoanConfig{
+			Period:     time.Hour * 24 * 14,
+			Limit:      5,
+			HoldPeriod: time.Hour * 24 * 3,
+		},
+		Fines: FineConfig{
+			PerDay: decimal.New(25, -2),
+			Cap:    decimal.New(10, 0),
+			Limit:  decimal.New(5, 0),
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, then the YAML or TOML file
+// named by CONFIG_FILE, then environment variables, each overriding the one
+// before, and validates the result.
+func Load() (Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := LoadFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func LoadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, cfg)
+	case ".toml":
+		err = toml.Unmarshal(content, cfg)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	setString(&cfg.ListenAddress, "LISTEN_ADDRESS")
+	setString(&cfg.DatabaseDSN, "CONNECTION")
+	setString(&cfg.SearchIndexPath, "SEARCH_INDEX_PATH")
+	setString(&cfg.JWT.Secret, "JWT_SECRET")
+	setString(&cfg.JWT.Issuer, "JWT_ISSUER")
+	setString(&cfg.JWT.Audience, "JWT_AUDIENCE")
+	setString(&cfg.JWT.SigningKeyFile, "JWT_SIGNING_KEY_FILE")
+
+	if value := os.Getenv("JWT_VERIFICATION_KEY_FILES"); value != "" {
+		cfg.JWT.VerificationKeyFiles = strings.Split(value, ",")
+	}
+
+	if value := os.Getenv("BCRYPT_COST"); value != "" {
+		cost, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("BCRYPT_COST: %w", err)
+		}
+		cfg.BcryptCost = cost
+	}
+
+	if value := os.Getenv("JWT_ACCESS_TOKEN_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("JWT_ACCESS_TOKEN_TTL: %w", err)
+		}
+		cfg.JWT.AccessTokenTTL = ttl
+	}
+
+	if value := os.Getenv("JWT_REFRESH_TOKEN_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("JWT_REFRESH_TOKEN_TTL: %w", err)
+		}
+		cfg.JWT.RefreshTokenTTL = ttl
+	}
+
+	if value := os.Getenv("LOAN_PERIOD"); value != "" {
+		period, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("LOAN_PERIOD: %w", err)
+		}
+		cfg.Loans.Period = period
+	}
+
+	if value := os.Getenv("LOAN_LIMIT"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("LOAN_LIMIT: %w", err)
+		}
+		cfg.Loans.Limit = limit
+	}
+
+	if value := os.Getenv("HOLD_PERIOD"); value != "" {
+		period, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("HOLD_PERIOD: %w", err)
+		}
+		cfg.Loans.HoldPeriod = period
+	}
+
+	for key, target := range map[string]*decimal.Decimal{
+		"FINE_PER_DAY": &cfg.Fines.PerDay,
+		"FINE_CAP":     &cfg.Fines.Cap,
+		"FINE_LIMIT":   &cfg.Fines.Limit,
+	} {
+		if value := os.Getenv(key); value != "" {
+			amount, err := decimal.NewFromString(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			*target = amount
+		}
+	}
+
+	return nil
+}
+
+func setString(target *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*target = value
+	}
+}
+
+func (c Config) Validate() error {
+	var problems []string
+
+	if c.ListenAddress == "" {
+		problems = append(problems, "listen address is required")
+	}
+	if c.DatabaseDSN == "" {
+		problems = append(problems, "database DSN is required")
+	} else if _, _, err := ParseDSN(c.DatabaseDSN); err != nil {
+		problems = append(problems, "database DSN: "+err.Error())
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		problems = append(problems, fmt.Sprintf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.JWT.SigningKeyFile == "" && c.JWT.Secret == "" {
+		problems = append(problems, "JWT secret or signing key file is required")
+	}
+	if c.JWT.Secret != "" && len(c.JWT.Secret) < minSecretLength {
+		problems = append(problems, fmt.Sprintf("JWT secret must be at least %d bytes", minSecretLength))
+	}
+	if c.JWT.Issuer == "" {
+		problems = append(problems, "JWT issuer is required")
+	}
+	if c.JWT.AccessTokenTTL <= 0 {
+		problems = append(problems, "JWT access token TTL must be positive")
+	}
+	if c.JWT.RefreshTokenTTL <= c.JWT.AccessTokenTTL {
+		problems = append(problems, "JWT refresh token TTL must be longer than the access token TTL")
+	}
+	if c.Loans.Period <= 0 {
+		problems = append(problems, "loan period must be positive")
+	}
+	if c.Loans.Limit <= 0 {
+		problems = append(problems, "loan limit must be positive")
+	}
+	if c.Loans.HoldPeriod <= 0 {
+		problems = append(problems, "hold period must be positive")
+	}
+	if c.Fines.PerDay.IsNegative() {
+		problems = append(problems, "fine per day must not be negative")
+	}
+	if c.Fines.Cap.IsNegative() {
+		problems = append(problems, "fine cap must not be negative")
+	}
+	if c.Fines.Limit.IsNegative() {
+		problems = append(problems, "fine limit must not be negative")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+const secretTest = "0123456789abcdef0123456789abcdef"
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("CONNECTION", "user:pass@tcp(localhost:3306)/library")
+	t.Setenv("JWT_SECRET", secretTest)
+	t.Setenv("JWT_ISSUER", "library")
+	t.Setenv("JWT_AUDIENCE", "library-api")
+	t.Setenv("JWT_ACCESS_TOKEN_TTL", "15m")
+	t.Setenv("JWT_REFRESH_TOKEN_TTL", "168h")
+	t.Setenv("LISTEN_ADDRESS", ":9000")
+	t.Setenv("BCRYPT_COST", "12")
+	t.Setenv("SEARCH_INDEX_PATH", "/var/lib/library/search")
+	t.Setenv("LOAN_PERIOD", "504h")
+	t.Setenv("LOAN_LIMIT", "3")
+	t.Setenv("HOLD_PERIOD", "48h")
+	t.Setenv("FINE_PER_DAY", "0.10")
+	t.Setenv("FINE_CAP", "0")
+	t.Setenv("FINE_LIMIT", "2.5")
+
+	cfg, err := Load()
+	if assert.NoError(t, err) {
+		assert.Equal(t, ":9000", cfg.ListenAddress)
+		assert.Equal(t, "/var/lib/library/search", cfg.SearchIndexPath)
+		assert.Equal(t, "user:pass@tcp(localhost:3306)/library", cfg.DatabaseDSN)
+		assert.Equal(t, 12, cfg.BcryptCost)
+		assert.Equal(t, secretTest, cfg.JWT.Secret)
+		assert.Equal(t, "library", cfg.JWT.Issuer)
+		assert.Equal(t, "library-api", cfg.JWT.Audience)
+		assert.Equal(t, 15*time.Minute, cfg.JWT.AccessTokenTTL)
+		assert.Equal(t, 168*time.Hour, cfg.JWT.RefreshTokenTTL)
+		assert.Equal(t, 504*time.Hour, cfg.Loans.Period)
+		assert.Equal(t, 3, cfg.Loans.Limit)
+		assert.Equal(t, 48*time.Hour, cfg.Loans.HoldPeriod)
+		assert.Equal(t, "0.1", cfg.Fines.PerDay.String())
+		assert.True(t, cfg.Fines.Cap.IsZero())
+		assert.Equal(t, "2.5", cfg.Fines.Limit.String())
+	}
+}
+
+func TestLoadFileThenEnv(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `
+listen_address: ":7000"
+database_dsn: "from-file"
+jwt:
+  secret: "` + secretTest + `"
+  issuer: "file-issuer"
+  access_token_ttl: 30m
+loans:
+  limit: 8
+fines:
+  per_day: "0.30"
+`,
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `
+listen_address = ":7000"
+database_dsn = "from-file"
+
+[jwt]
+secret = "` + secretTest + `"
+issuer = "file-issuer"
+access_token_ttl = "30m"
+
+[loans]
+limit = 8
+
+[fines]
+per_day = "0.30"
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		path := filepath.Join(t.TempDir(), testCase.file)
+		if err := os.WriteFile(path, []byte(testCase.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Setenv("CONFIG_FILE", path)
+		t.Setenv("CONNECTION", "")
+		t.Setenv("JWT_SECRET", "")
+		t.Setenv("JWT_ISSUER", "env-issuer")
+		t.Setenv("JWT_AUDIENCE", "")
+		t.Setenv("JWT_ACCESS_TOKEN_TTL", "")
+		t.Setenv("JWT_REFRESH_TOKEN_TTL", "")
+		t.Setenv("LISTEN_ADDRESS", "")
+		t.Setenv("BCRYPT_COST", "")
+		t.Setenv("LOAN_PERIOD", "")
+		t.Setenv("LOAN_LIMIT", "")
+		t.Setenv("HOLD_PERIOD", "")
+		t.Setenv("FINE_PER_DAY", "")
+		t.Setenv("FINE_CAP", "")
+		t.Setenv("FINE_LIMIT", "")
+
+		cfg, err := Load()
+		if assert.NoError(t, err, testCase.name) {
+			assert.Equal(t, ":7000", cfg.ListenAddress, testCase.name)
+			assert.Equal(t, "from-file", cfg.DatabaseDSN, testCase.name)
+			assert.Equal(t, "env-issuer", cfg.JWT.Issuer, testCase.name)
+			assert.Equal(t, 30*time.Minute, cfg.JWT.AccessTokenTTL, testCase.name)
+			assert.Equal(t, 8, cfg.Loans.Limit, testCase.name)
+			assert.Equal(t, 14*24*time.Hour, cfg.Loans.Period, testCase.name)
+			assert.True(t, decimal.RequireFromString("0.3").Equal(cfg.Fines.PerDay), testCase.name)
+			assert.True(t, decimal.New(5, 0).Equal(cfg.Fines.Limit), testCase.name)
+		}
+	}
+}
+
+func TestLoadUnsupportedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CONFIG_FILE", path)
+	_, err := Load()
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	valid := Default()
+	valid.DatabaseDSN = "dsn"
+	valid.JWT.Secret = secretTest
+	assert.NoError(t, valid.Validate())
+
+	testCases := []struct {
+		name   string
+		modify func(cfg *Config)
+	}{
+		{"missing dsn", func(cfg *Config) { cfg.DatabaseDSN = "" }},
+		{"short secret", func(cfg *Config) { cfg.JWT.Secret = "legal" }},
+		{"missing issuer", func(cfg *Config) { cfg.JWT.Issuer = "" }},
+		{"zero ttl", func(cfg *Config) { cfg.JWT.AccessTokenTTL = 0 }},
+		{"refresh ttl too short", func(cfg *Config) { cfg.JWT.RefreshTokenTTL = cfg.JWT.AccessTokenTTL }},
+		{"bcrypt cost", func(cfg *Config) { cfg.BcryptCost = 100 }},
+		{"listen address", func(cfg *Config) { cfg.ListenAddress = "" }},
+		{"zero loan period", func(cfg *Config) { cfg.Loans.Period = 0 }},
+		{"zero loan limit", func(cfg *Config) { cfg.Loans.Limit = 0 }},
+		{"zero hold period", func(cfg *Config) { cfg.Loans.HoldPeriod = 0 }},
+		{"negative fine per day", func(cfg *Config) { cfg.Fines.PerDay = decimal.New(-1, 0) }},
+		{"negative fine limit", func(cfg *Config) { cfg.Fines.Limit = decimal.New(-1, 0) }},
+	}
+
+	for _, testCase := range testCases {
+		cfg := valid
+		testCase.modify(&cfg)
+		assert.Error(t, cfg.Validate(), testCase.name)
+	}
+}
@@ -1,47 +1,66 @@
 package config
 
 import (
-	"cleancode/models"
+	"cleancode/lib/migrations"
 	"os"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-var Db *gorm.DB
-
-func InitDb() {
-	connection := os.Getenv("CONNECTION")
-
-	var err error
-	Db, err = gorm.Open(mysql.Open(connection), &gorm.Config{})
+// OpenDatabase connects to the database at connection, with the driver chosen
+// by ParseDSN, and refuses a schema with pending migrations. Each call
+// returns a new connection pool.
+func OpenDatabase(connection string) (*gorm.DB, error) {
+	db, err := Connect(connection)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	InitMigrate()
+	if err := InitMigrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
 }
 
-func InitMigrate() {
-	Db.AutoMigrate(&models.User{})
-	Db.AutoMigrate(&models.Book{})
+// InitMigrate checks that every migration has been applied; the schema
+// itself is only changed by the "migrate" command.
+func InitMigrate(db *gorm.DB) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	return migrator.RequireCurrent()
 }
 
-func InitDbTest() {
-	connection := os.Getenv("CONNECTION")
+// InitDbTest opens the database named by TEST_CONNECTION with empty tables.
+// Without it each call gets its own in-memory SQLite database.
+func InitDbTest() *gorm.DB {
+	connection := os.Getenv("TEST_CONNECTION")
+	if connection == "" {
+		connection = TestDSN()
+	}
+	return InitDbTestWith(connection)
+}
 
-	var err error
-	Db, err = gorm.Open(mysql.Open(connection), &gorm.Config{})
+// InitDbTestWith opens connection with empty tables, for tests that target a
+// particular database.
+func InitDbTestWith(connection string) *gorm.DB {
+	db, err := Connect(connection)
 	if err != nil {
 		panic(err)
 	}
-	InitMigrateTest()
+	InitMigrateTest(db)
+	return db
 }
 
-func InitMigrateTest() {
-	Db.Migrator().DropTable(&models.User{})
-	Db.AutoMigrate(&models.User{})
-
-	Db.Migrator().DropTable(&models.Book{})
-	Db.AutoMigrate(&models.Book{})
+// InitMigrateTest rolls back and reapplies every migration, which also
+// exercises the down migrations on each test run.
+func InitMigrateTest(db *gorm.DB) {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		panic(err)
+	}
+	if err := migrator.Reset(); err != nil {
+		panic(err)
+	}
 }
@@ -1,3 +1,3 @@
 package constants
 
-const SECRET_JWT = "legal"
+const TOKEN_TYPE = "Bearer"
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"cleancode/constants"
+	"cleancode/lib/databases"
+	"cleancode/middlewares"
+	"cleancode/models"
+	"cleancode/response"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuthController struct {
+	Users         databases.UserRepository
+	RefreshTokens databases.RefreshTokenRepository
+	Tokens        *middlewares.TokenManager
+}
+
+func NewAuthController(users databases.UserRepository, refreshTokens databases.RefreshTokenRepository, tokens *middlewares.TokenManager) *AuthController {
+	return &AuthController{Users: users, RefreshTokens: refreshTokens, Tokens: tokens}
+}
+
+func (h *AuthController) Refresh(c echo.Context) error {
+	input := models.RefreshTokenInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	refreshToken, userId, err := h.RefreshTokens.Rotate(input.RefreshToken, h.Tokens.RefreshLifetime())
+	if err != nil {
+		return err
+	}
+
+	// the role is read again so a changed role applies from the next refresh
+	user, err := h.Users.GetByID(userId)
+	if errors.Is(err, databases.ErrNotFound) {
+		return databases.ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := h.Tokens.CreateToken(int(user.ID), user.Role)
+	if err != nil {
+		return err
+	}
+
+	pair := response.NewTokenResponse(accessToken, refreshToken, constants.TOKEN_TYPE, h.Tokens.Lifetime())
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", pair))
+}
+
+func (h *AuthController) Logout(c echo.Context) error {
+	input := models.RefreshTokenInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	if err := h.RefreshTokens.Revoke(input.RefreshToken); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", "logged out"))
+}
+
+// JWKS publishes the public keys access tokens can be verified with, so other
+// services never need the signing secret.
+func (h *AuthController) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.Tokens.Keys().JWKS())
+}
+
+// issueTokens creates the access and refresh token pair returned on login.
+func issueTokens(tokens *middlewares.TokenManager, refreshTokens databases.RefreshTokenRepository, user models.User) (response.TokenResponse, error) {
+	accessToken, err := tokens.CreateToken(int(user.ID), user.Role)
+	if err != nil {
+		return response.TokenResponse{}, err
+	}
+
+	refreshToken, err := refreshTokens.Create(user.ID, "", tokens.RefreshLifetime())
+	if err != nil {
+		return response.TokenResponse{}, err
+	}
+
+	return response.NewTokenResponse(accessToken, refreshToken, constants.TOKEN_TYPE, tokens.Lifetime()), nil
+}
+
+func isRefreshTokenError(err error) bool {
+	return errors.Is(err, databases.ErrRefreshTokenInvalid) || errors.Is(err, databases.ErrRefreshTokenReused)
+}
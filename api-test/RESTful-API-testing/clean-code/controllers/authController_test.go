@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"bytes"
+	"cleancode/models"
+	"cleancode/response"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type TokenResponse struct {
+	Message string
+	Data    response.TokenResponse
+}
+
+func LoginForTokens(t *testing.T, e *echo.Echo, db *gorm.DB) response.TokenResponse {
+	InsertDataUserForGetUsers(db)
+
+	body, err := json.Marshal(UserRequest{Email: "alta@gmail.com", Password: "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := HandleTest(e, c, InitUserControllerTest(db).Login); err != nil {
+		t.Fatal(err)
+	}
+
+	var login TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	return login.Data
+}
+
+func PostRefreshToken(e *echo.Echo, handler echo.HandlerFunc, refreshToken string) (*httptest.ResponseRecorder, TokenResponse) {
+	body, _ := json.Marshal(models.RefreshTokenInput{RefreshToken: refreshToken})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBuffer(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	HandleTest(e, c, handler)
+
+	var tokens TokenResponse
+	json.Unmarshal(rec.Body.Bytes(), &tokens)
+	return rec, tokens
+}
+
+func TestRefreshTokenController(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	login := LoginForTokens(t, e, db)
+
+	rec, refreshed := PostRefreshToken(e, InitAuthControllerTest(db).Refresh, login.RefreshToken)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "success", refreshed.Message)
+	assert.NotEmpty(t, refreshed.Data.AccessToken)
+	assert.NotEmpty(t, refreshed.Data.RefreshToken)
+	assert.NotEqual(t, login.RefreshToken, refreshed.Data.RefreshToken)
+	assert.Equal(t, "Bearer", refreshed.Data.TokenType)
+
+	stored := []models.RefreshToken{}
+	db.Find(&stored)
+	for _, token := range stored {
+		assert.NotEqual(t, login.RefreshToken, token.TokenHash)
+		assert.NotEqual(t, refreshed.Data.RefreshToken, token.TokenHash)
+	}
+}
+
+func TestRefreshTokenControllerReuseRevokesFamily(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	login := LoginForTokens(t, e, db)
+	refresh := InitAuthControllerTest(db).Refresh
+
+	rec, refreshed := PostRefreshToken(e, refresh, login.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec, reused := PostRefreshToken(e, refresh, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "invalid refresh token", reused.Message)
+
+	rec, _ = PostRefreshToken(e, refresh, refreshed.Data.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRefreshTokenControllerInvalid(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	refresh := InitAuthControllerTest(db).Refresh
+
+	rec, result := PostRefreshToken(e, refresh, "not-a-token")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "invalid refresh token", result.Message)
+
+	rec, result = PostRefreshToken(e, refresh, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "validation failed", result.Message)
+}
+
+func TestRefreshTokenControllerExpired(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	login := LoginForTokens(t, e, db)
+
+	db.Model(&models.RefreshToken{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Minute))
+
+	rec, _ := PostRefreshToken(e, InitAuthControllerTest(db).Refresh, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestLogoutController(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	login := LoginForTokens(t, e, db)
+
+	rec, result := PostRefreshToken(e, InitAuthControllerTest(db).Logout, login.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "success", result.Message)
+
+	rec, _ = PostRefreshToken(e, InitAuthControllerTest(db).Refresh, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, _ = PostRefreshToken(e, InitAuthControllerTest(db).Logout, "not-a-token")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, _ = PostRefreshToken(e, InitAuthControllerTest(db).Logout, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestJWKSController(t *testing.T) {
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	type JWKSResponse struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+
+	if assert.NoError(t, HandleTest(e, c, InitAuthControllerTest(nil).JWKS)) {
+		var jwks JWKSResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &jwks)
+		if err != nil {
+			assert.Error(t, err, "error")
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotNil(t, jwks.Keys)
+		assert.Empty(t, jwks.Keys)
+		assert.NotContains(t, rec.Body.String(), jwtConfigTest.Secret)
+	}
+}
+
+func TestPasswordChangeRevokesRefreshTokens(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	login := LoginForTokens(t, e, db)
+	refresh := InitAuthControllerTest(db).Refresh
+
+	rec, refreshed := PostRefreshToken(e, refresh, login.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	user := models.User{}
+	if err := db.Where("email = ?", "alta@gmail.com").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/jwt/users/:id", bytes.NewBufferString(`{"password":"changed123"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec = httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(fmt.Sprint(user.ID))
+
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).UpdateUser))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec, _ = PostRefreshToken(e, refresh, refreshed.Data.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/models"
+	"cleancode/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuthorController struct {
+	Authors databases.AuthorRepository
+}
+
+func NewAuthorController(authors databases.AuthorRepository) *AuthorController {
+	return &AuthorController{Authors: authors}
+}
+
+func (h *AuthorController) GetAllAuthors(c echo.Context) error {
+	query := models.AuthorListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	filter := databases.AuthorFilter{Name: query.Name}
+	authors, page, err := h.Authors.List(filter, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputAuthors(authors), page))
+}
+
+func (h *AuthorController) GetSingleAuthor(c echo.Context) error {
+	authorId, err := parseID(c, "invalid author id")
+	if err != nil {
+		return err
+	}
+
+	author, err := h.Authors.GetByID(authorId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", author.Output()))
+}
+
+func (h *AuthorController) GetAuthorBooks(c echo.Context) error {
+	authorId, err := parseID(c, "invalid author id")
+	if err != nil {
+		return err
+	}
+
+	query := models.BookListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	books, page, err := h.Authors.Books(authorId, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputBooks(books), page))
+}
+
+func (h *AuthorController) CreateAuthor(c echo.Context) error {
+	input := models.CreateAuthorInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	author := input.Author()
+
+	if err := h.Authors.Create(&author); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", author.Output()))
+}
+
+func (h *AuthorController) UpdateAuthor(c echo.Context) error {
+	authorId, err := parseID(c, "invalid author id")
+	if err != nil {
+		return err
+	}
+
+	input := models.UpdateAuthorInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	author, err := h.Authors.Update(authorId, input.Author())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", author.Output()))
+}
+
+func (h *AuthorController) DeleteAuthor(c echo.Context) error {
+	authorId, err := parseID(c, "invalid author id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.Authors.Delete(authorId); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", "deleted"))
+}
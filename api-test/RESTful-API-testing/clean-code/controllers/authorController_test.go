@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"cleancode/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorControllerWithFakeRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		id           string
+		body         string
+		handler      func(h *AuthorController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"get all authors", http.MethodGet, "", "", func(h *AuthorController) echo.HandlerFunc { return h.GetAllAuthors }, http.StatusOK, "success"},
+		{"get single author", http.MethodGet, "1", "", func(h *AuthorController) echo.HandlerFunc { return h.GetSingleAuthor }, http.StatusOK, "success"},
+		{"get missing author", http.MethodGet, "9", "", func(h *AuthorController) echo.HandlerFunc { return h.GetSingleAuthor }, http.StatusNotFound, "author not found"},
+		{"get invalid id", http.MethodGet, "abc", "", func(h *AuthorController) echo.HandlerFunc { return h.GetSingleAuthor }, http.StatusBadRequest, "invalid author id"},
+		{"get author books", http.MethodGet, "1", "", func(h *AuthorController) echo.HandlerFunc { return h.GetAuthorBooks }, http.StatusOK, "success"},
+		{"get missing author books", http.MethodGet, "9", "", func(h *AuthorController) echo.HandlerFunc { return h.GetAuthorBooks }, http.StatusNotFound, "author not found"},
+		{"create author", http.MethodPost, "", `{"name":"newton","bio":"physicist"}`, func(h *AuthorController) echo.HandlerFunc { return h.CreateAuthor }, http.StatusCreated, "success"},
+		{"create nameless author", http.MethodPost, "", `{"bio":"physicist"}`, func(h *AuthorController) echo.HandlerFunc { return h.CreateAuthor }, http.StatusUnprocessableEntity, "validation failed"},
+		{"update author", http.MethodPut, "2", `{"name":"alta"}`, func(h *AuthorController) echo.HandlerFunc { return h.UpdateAuthor }, http.StatusOK, "success"},
+		{"update missing author", http.MethodPut, "9", `{"name":"alta"}`, func(h *AuthorController) echo.HandlerFunc { return h.UpdateAuthor }, http.StatusNotFound, "author not found"},
+		{"delete author", http.MethodDelete, "2", "", func(h *AuthorController) echo.HandlerFunc { return h.DeleteAuthor }, http.StatusOK, "success"},
+		{"delete author with books", http.MethodDelete, "1", "", func(h *AuthorController) echo.HandlerFunc { return h.DeleteAuthor }, http.StatusConflict, "author still has books"},
+		{"delete missing author", http.MethodDelete, "9", "", func(h *AuthorController) echo.HandlerFunc { return h.DeleteAuthor }, http.StatusNotFound, "author not found"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			authors := newFakeAuthorRepository(models.Author{Name: "urnik"}, models.Author{Name: "budi"})
+			authors.books[1] = []models.Book{{Title: "chemistry", Author: "urnik"}}
+			h := NewAuthorController(authors)
+
+			rec, message := serveWithFake(t, testCase.handler(h), testCase.method, "/authors", testCase.body, 0, "", "id", testCase.id)
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, message)
+		})
+	}
+}
+
+func TestCreateBookControllerWithAuthorIds(t *testing.T) {
+	books := newFakeBookRepository()
+
+	body := `{"title":"sicp","authorIds":[2,1],"publishedAt":"1985-01-01"}`
+	rec, _ := serveWithFake(t, NewBookController(books).CreateBook, http.MethodPost, "/books", body, 0, "")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	if assert.Len(t, books.books, 1) {
+		var ids []uint
+		for _, author := range books.books[1].Authors {
+			ids = append(ids, author.ID)
+		}
+		assert.Equal(t, []uint{2, 1}, ids)
+	}
+}
@@ -5,97 +5,116 @@ import (
 	"cleancode/models"
 	"cleancode/response"
 	"net/http"
-	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
 
-func GetAllBooksController(c echo.Context) error {
-	books, rowAffected, err := databases.GetAllBooks()
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("failed"))
+type BookController struct {
+	Books databases.BookRepository
+}
+
+func NewBookController(books databases.BookRepository) *BookController {
+	return &BookController{Books: books}
+}
+
+func (h *BookController) GetAllBooks(c echo.Context) error {
+	query := models.BookListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
 	}
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponseBook("failed"))
+	filter := databases.BookFilter{
+		Title:           query.Title,
+		Author:          query.Author,
+		PublishedAfter:  query.PublishedAfter,
+		PublishedBefore: query.PublishedBefore,
+	}
+	books, page, err := h.Books.List(filter, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", books))
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputBooks(books), page))
 }
 
-func GetSingleBookController(c echo.Context) error {
-	bookId, errorId := strconv.Atoi(c.Param("id"))
-	if errorId != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("invalid book id"))
+func (h *BookController) SearchBooks(c echo.Context) error {
+	query := models.BookSearchQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
 	}
 
-	book, rowAffected, err := databases.GetSingleBook(bookId)
+	matches, page, err := h.Books.Search(query.Q, databases.ListOptions{Limit: query.Limit, Page: query.Page})
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("failed"))
+		return err
 	}
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponseBook("failed"))
+	results := make([]response.BookSearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, response.NewBookSearchResult(match))
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", book))
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", results, page))
 }
 
-func CreateBookControllers(c echo.Context) error {
-	var book models.Book
-	c.Bind(&book)
+func (h *BookController) GetSingleBook(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
+	}
 
-	newBook, err := databases.CreateNewBook(&book)
+	book, err := h.Books.GetByID(bookId)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("failed"))
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", newBook))
+	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", book.Output()))
 }
 
-func DeleteBookController(c echo.Context) error {
-	bookId, errorId := strconv.Atoi(c.Param("id"))
-	if errorId != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("invalid book id"))
+func (h *BookController) CreateBook(c echo.Context) error {
+	input := models.CreateBookInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
 	}
 
-	message, rowAffected, err := databases.DeleteBook(bookId)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("failed"))
-	}
+	book := input.Book()
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponseBook("failed"))
+	if err := h.Books.Create(&book); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", message))
+	return c.JSON(http.StatusCreated, response.SuccessResponseBook("success", book.Output()))
 }
 
-func UpdateBookController(c echo.Context) error {
-	bookId, errorId := strconv.Atoi(c.Param("id"))
-	if errorId != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("invalid book id"))
+func (h *BookController) DeleteBook(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
 	}
 
-	newBook := models.Book{}
-	c.Bind(&newBook)
+	if err := h.Books.Delete(bookId); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", "deleted"))
+}
 
-	updatedBook, rowAffected, err := databases.UpdateBook(bookId, newBook)
+func (h *BookController) UpdateBook(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponseBook("failed"))
+		return err
 	}
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponseBook("failed"))
+	input := models.UpdateBookInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", updatedBook))
-}
-
-func DeleteBookTesting() echo.HandlerFunc {
-	return DeleteBookController
-}
+	updatedBook, err := h.Books.Update(bookId, input.Book())
+	if err != nil {
+		return err
+	}
 
-func UpdateBookTesting() echo.HandlerFunc {
-	return UpdateBookController
+	return c.JSON(http.StatusOK, response.SuccessResponseBook("success", updatedBook.Output()))
 }
@@ -3,34 +3,39 @@ package controllers
 import (
 	"bytes"
 	"cleancode/config"
-	"cleancode/constants"
-	"cleancode/middlewares"
+	"cleancode/lib/validation"
 	"cleancode/models"
+	"cleancode/response"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
-func InitEchoTestAPIBook() *echo.Echo {
-	config.InitDbTest()
+func InitEchoTestAPIBook() (*echo.Echo, *gorm.DB) {
+	db := config.InitDbTest()
 	e := echo.New()
-	return e
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = validation.New()
+	return e, db
 }
 
-func InsertDataBookForGetBooks() error {
+func InsertDataBookForGetBooks(db *gorm.DB) error {
 	book := models.Book{
 		Title:        "chemistry",
 		Author:       "urnik",
-		Published_at: "2021",
+		Published_at: models.MustParseDate("2021-01-01"),
 	}
 
-	err := config.Db.Save(&book).Error
+	err := db.Save(&book).Error
 	if err != nil {
 		return err
 	}
@@ -50,8 +55,8 @@ func TestGetAllBooksController(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
 
 	request := httptest.NewRequest(http.MethodGet, "/", nil)
 	record := httptest.NewRecorder()
@@ -64,7 +69,7 @@ func TestGetAllBooksController(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, GetAllBooksController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetAllBooks)) {
 		body := record.Body.String()
 		var book BookResponse
 		err := json.Unmarshal([]byte(body), &book)
@@ -91,7 +96,7 @@ func TestGetAllBooksControllerNilFailed(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPIBook()
+	e, db := InitEchoTestAPIBook()
 
 	request := httptest.NewRequest(http.MethodGet, "/", nil)
 	record := httptest.NewRecorder()
@@ -104,7 +109,7 @@ func TestGetAllBooksControllerNilFailed(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, GetAllBooksController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetAllBooks)) {
 		body := record.Body.String()
 		var book BookResponse
 		err := json.Unmarshal([]byte(body), &book)
@@ -113,7 +118,7 @@ func TestGetAllBooksControllerNilFailed(t *testing.T) {
 		}
 
 		assert.Equal(t, testCases.expectedCode, record.Code)
-		assert.Equal(t, "failed", book.Message)
+		assert.Equal(t, "success", book.Message)
 	}
 
 }
@@ -128,11 +133,11 @@ func TestGetAllBooksControllerFailed(t *testing.T) {
 	testCases := Expected{
 		name:         "success get all books",
 		path:         "/books",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPIBook()
-	config.Db.Migrator().DropTable(&models.Book{})
+	e, db := InitEchoTestAPIBook()
+	db.Migrator().DropTable(&models.Book{})
 
 	request := httptest.NewRequest(http.MethodGet, "/", nil)
 	record := httptest.NewRecorder()
@@ -145,7 +150,7 @@ func TestGetAllBooksControllerFailed(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, GetAllBooksController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetAllBooks)) {
 		body := record.Body.String()
 		var book BookResponse
 		err := json.Unmarshal([]byte(body), &book)
@@ -154,7 +159,7 @@ func TestGetAllBooksControllerFailed(t *testing.T) {
 		}
 
 		assert.Equal(t, testCases.expectedCode, record.Code)
-		assert.Equal(t, "failed", book.Message)
+		assert.Equal(t, "internal server error", book.Message)
 	}
 
 }
@@ -166,15 +171,15 @@ func TestCreateBookController(t *testing.T) {
 	}
 	testCases := Expected{
 		name:         "success create new book",
-		expectedCode: http.StatusOK,
+		expectedCode: http.StatusCreated,
 	}
 
-	e := InitEchoTestAPIBook()
+	e, db := InitEchoTestAPIBook()
 
 	book := models.Book{
 		Title:        "math",
 		Author:       "urnik",
-		Published_at: "2013",
+		Published_at: models.MustParseDate("2013-05-01"),
 	}
 
 	body, err := json.Marshal(book)
@@ -193,7 +198,7 @@ func TestCreateBookController(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, CreateBookControllers(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).CreateBook)) {
 		body := rec.Body.String()
 		var books BookResponse
 
@@ -217,13 +222,15 @@ func TestCreateBookControllerFailed(t *testing.T) {
 	}
 	testCases := Expected{
 		name:         "failed create new book",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPIBook()
-	config.Db.Migrator().DropTable(&models.Book{})
+	e, db := InitEchoTestAPIBook()
+	db.Migrator().DropTable(&models.Book{})
 
-	req := httptest.NewRequest(http.MethodPost, "/books", nil)
+	body := `{"title":"math","author":"urnik","publishedAt":"2013-05-01"}`
+	req := httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 
 	c := e.NewContext(req, rec)
@@ -233,7 +240,7 @@ func TestCreateBookControllerFailed(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, CreateBookControllers(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).CreateBook)) {
 		body := rec.Body.String()
 		var books BookResponse
 
@@ -243,7 +250,7 @@ func TestCreateBookControllerFailed(t *testing.T) {
 		}
 
 		assert.Equal(t, testCases.expectedCode, rec.Code)
-		assert.Equal(t, "failed", books.Message)
+		assert.Equal(t, "internal server error", books.Message)
 	}
 
 }
@@ -261,8 +268,8 @@ func TestGetSingleBookControllerSuccess(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
 
 	req := httptest.NewRequest(http.MethodGet, "/users/:id", nil)
 	rec := httptest.NewRecorder()
@@ -276,7 +283,7 @@ func TestGetSingleBookControllerSuccess(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, GetSingleBookController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetSingleBook)) {
 		body := rec.Body.String()
 		var book BookResponse
 		err := json.Unmarshal([]byte(body), &book)
@@ -298,11 +305,11 @@ func TestGetSingleBookControllerFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "failed to get single book",
 		id:           "1",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPIBook()
-	config.Db.Migrator().DropTable(&models.Book{})
+	e, db := InitEchoTestAPIBook()
+	db.Migrator().DropTable(&models.Book{})
 
 	req := httptest.NewRequest(http.MethodGet, "/users/:id", nil)
 	rec := httptest.NewRecorder()
@@ -316,7 +323,7 @@ func TestGetSingleBookControllerFailed(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, GetSingleBookController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetSingleBook)) {
 		body := rec.Body.String()
 		var book BookResponse
 		err := json.Unmarshal([]byte(body), &book)
@@ -324,7 +331,7 @@ func TestGetSingleBookControllerFailed(t *testing.T) {
 			assert.Error(t, err, "error")
 		}
 		assert.Equal(t, testCase.expectedCode, rec.Code)
-		assert.Equal(t, "failed", book.Message)
+		assert.Equal(t, "internal server error", book.Message)
 	}
 }
 
@@ -341,8 +348,8 @@ func TestGetSingleBookControllerInvalidId(t *testing.T) {
 		expectedCode: http.StatusBadRequest,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
 
 	req := httptest.NewRequest(http.MethodGet, "/users/:id", nil)
 	rec := httptest.NewRecorder()
@@ -356,7 +363,7 @@ func TestGetSingleBookControllerInvalidId(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, GetSingleBookController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetSingleBook)) {
 		body := rec.Body.String()
 		var book BookResponse
 		err := json.Unmarshal([]byte(body), &book)
@@ -378,11 +385,11 @@ func TestGetSingleBookControllerNilFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "failed to get single book",
 		id:           "2",
-		expectedCode: http.StatusOK,
+		expectedCode: http.StatusNotFound,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
 
 	req := httptest.NewRequest(http.MethodGet, "/users/:id", nil)
 	rec := httptest.NewRecorder()
@@ -396,7 +403,7 @@ func TestGetSingleBookControllerNilFailed(t *testing.T) {
 		Data    models.Book
 	}
 
-	if assert.NoError(t, GetSingleBookController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetSingleBook)) {
 		body := rec.Body.String()
 		var book BookResponse
 		err := json.Unmarshal([]byte(body), &book)
@@ -404,7 +411,7 @@ func TestGetSingleBookControllerNilFailed(t *testing.T) {
 			assert.Error(t, err, "error")
 		}
 		assert.Equal(t, testCase.expectedCode, rec.Code)
-		assert.Equal(t, "failed", book.Message)
+		assert.Equal(t, "book not found", book.Message)
 	}
 }
 
@@ -421,21 +428,21 @@ func TestDeleteBookControllerSuccess(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -449,7 +456,7 @@ func TestDeleteBookControllerSuccess(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).DeleteBook))
 
 	type BookResponse struct {
 		Message string
@@ -476,24 +483,24 @@ func TestDeleteBookControllerNilFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "failed delete book",
 		id:           "22",
-		expectedCode: http.StatusOK,
+		expectedCode: http.StatusNotFound,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -507,7 +514,7 @@ func TestDeleteBookControllerNilFailed(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).DeleteBook))
 
 	type BookResponse struct {
 		Message string
@@ -521,7 +528,7 @@ func TestDeleteBookControllerNilFailed(t *testing.T) {
 	}
 
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "failed", book.Message)
+	assert.Equal(t, "book not found", book.Message)
 }
 
 func TestDeleteBookControllerInvalidId(t *testing.T) {
@@ -537,21 +544,21 @@ func TestDeleteBookControllerInvalidId(t *testing.T) {
 		expectedCode: http.StatusBadRequest,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -565,7 +572,7 @@ func TestDeleteBookControllerInvalidId(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).DeleteBook))
 
 	type BookResponse struct {
 		Message string
@@ -592,24 +599,24 @@ func TestDeleteBookControllerFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "failed delete book",
 		id:           "1",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPIBook()
-	config.Db.Migrator().DropTable(&models.Book{})
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	db.Migrator().DropTable(&models.Book{})
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -623,7 +630,7 @@ func TestDeleteBookControllerFailed(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).DeleteBook))
 
 	type BookResponse struct {
 		Message string
@@ -637,7 +644,7 @@ func TestDeleteBookControllerFailed(t *testing.T) {
 	}
 
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "failed", book.Message)
+	assert.Equal(t, "internal server error", book.Message)
 }
 
 func TestUpdateBookControllerSuccess(t *testing.T) {
@@ -653,21 +660,21 @@ func TestUpdateBookControllerSuccess(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -675,7 +682,7 @@ func TestUpdateBookControllerSuccess(t *testing.T) {
 	Newbook := models.Book{
 		Title:        "mathematics",
 		Author:       "lukman",
-		Published_at: "2013",
+		Published_at: models.MustParseDate("2013-05-01"),
 	}
 
 	body, err := json.Marshal(Newbook)
@@ -692,7 +699,7 @@ func TestUpdateBookControllerSuccess(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(UpdateBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).UpdateBook))
 
 	type BookResponse struct {
 		Message string
@@ -722,24 +729,24 @@ func TestUpdateBookControllerNilFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "failed to update book",
 		id:           "22",
-		expectedCode: http.StatusOK,
+		expectedCode: http.StatusNotFound,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -753,7 +760,7 @@ func TestUpdateBookControllerNilFailed(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(UpdateBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).UpdateBook))
 
 	type BookResponse struct {
 		Message string
@@ -767,7 +774,7 @@ func TestUpdateBookControllerNilFailed(t *testing.T) {
 	}
 
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "failed", book.Message)
+	assert.Equal(t, "book not found", book.Message)
 }
 
 func TestUpdateBookControllerInvalidId(t *testing.T) {
@@ -783,21 +790,21 @@ func TestUpdateBookControllerInvalidId(t *testing.T) {
 		expectedCode: http.StatusBadRequest,
 	}
 
-	e := InitEchoTestAPIBook()
-	InsertDataBookForGetBooks()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -811,7 +818,7 @@ func TestUpdateBookControllerInvalidId(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(UpdateBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).UpdateBook))
 
 	type BookResponse struct {
 		Message string
@@ -838,24 +845,24 @@ func TestUpdateBookControllerFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "invalid book id",
 		id:           "1",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPIBook()
-	config.Db.Migrator().DropTable(&models.Book{})
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPIBook()
+	db.Migrator().DropTable(&models.Book{})
+	InsertDataUserForGetUsers(db)
 
 	dummyData := models.User{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
 	user := models.User{}
-	result := config.Db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("Email = ? AND Password = ?", dummyData.Email, dummyData.Password).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err1 := middlewares.CreateToken(int(user.ID))
+	token, err1 := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -869,7 +876,7 @@ func TestUpdateBookControllerFailed(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(UpdateBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).UpdateBook))
 
 	type BookResponse struct {
 		Message string
@@ -883,5 +890,332 @@ func TestUpdateBookControllerFailed(t *testing.T) {
 	}
 
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "failed", book.Message)
+	assert.Equal(t, "internal server error", book.Message)
+}
+
+func TestBookControllerWithFakeRepository(t *testing.T) {
+	type Expected struct {
+		name         string
+		method       string
+		id           string
+		body         string
+		handler      func(h *BookController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}
+
+	testCases := []Expected{
+		{"get all books", http.MethodGet, "", "", func(h *BookController) echo.HandlerFunc { return h.GetAllBooks }, http.StatusOK, "success"},
+		{"get single book", http.MethodGet, "1", "", func(h *BookController) echo.HandlerFunc { return h.GetSingleBook }, http.StatusOK, "success"},
+		{"get missing book", http.MethodGet, "9", "", func(h *BookController) echo.HandlerFunc { return h.GetSingleBook }, http.StatusNotFound, "book not found"},
+		{"get invalid id", http.MethodGet, "abc", "", func(h *BookController) echo.HandlerFunc { return h.GetSingleBook }, http.StatusBadRequest, "invalid book id"},
+		{"create book", http.MethodPost, "", `{"title":"physics","author":"newton","publishedAt":"1687-07-05"}`, func(h *BookController) echo.HandlerFunc { return h.CreateBook }, http.StatusCreated, "success"},
+		{"update book", http.MethodPut, "1", `{"title":"organic chemistry"}`, func(h *BookController) echo.HandlerFunc { return h.UpdateBook }, http.StatusOK, "success"},
+		{"update missing book", http.MethodPut, "9", `{"title":"organic chemistry"}`, func(h *BookController) echo.HandlerFunc { return h.UpdateBook }, http.StatusNotFound, "book not found"},
+		{"delete book", http.MethodDelete, "1", "", func(h *BookController) echo.HandlerFunc { return h.DeleteBook }, http.StatusOK, "success"},
+		{"delete missing book", http.MethodDelete, "9", "", func(h *BookController) echo.HandlerFunc { return h.DeleteBook }, http.StatusNotFound, "book not found"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			books := newFakeBookRepository(models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")})
+			h := NewBookController(books)
+
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler
+			e.Validator = validation.New()
+			req := httptest.NewRequest(testCase.method, "/books", strings.NewReader(testCase.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(testCase.id)
+
+			HandleTest(e, c, testCase.handler(h))
+
+			var result struct {
+				Message string
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, result.Message)
+		})
+	}
+}
+
+func TestBookControllerRepositoryFailure(t *testing.T) {
+	books := newFakeBookRepository()
+	books.err = errors.New("connection refused")
+	h := NewBookController(books)
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = validation.New()
+	req := httptest.NewRequest(http.MethodGet, "/books", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	HandleTest(e, c, h.GetAllBooks)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "connection refused")
+}
+
+func TestCreateBookControllerInvalid(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedFields map[string]string
+	}{
+		{"empty body", `{}`, map[string]string{"title": "required", "author": "required_without", "authorIds": "required_without", "publishedAt": "required"}},
+		{"no such author id", `{"title":"math","authorIds":[0],"publishedAt":"2013-05-01"}`, map[string]string{"authorIds[0]": "min"}},
+		{"year only", `{"title":"math","author":"urnik","publishedAt":"2013"}`, map[string]string{"publishedAt": "date"}},
+		{"title too long", `{"title":"` + strings.Repeat("a", 256) + `","author":"urnik","publishedAt":"2013-05-01"}`, map[string]string{"title": "max"}},
+		{"bad isbn checksum", `{"title":"math","author":"urnik","publishedAt":"2013-05-01","isbn":"978-0-306-40615-8"}`, map[string]string{"isbn": "isbn"}},
+		{"details", `{"title":"math","author":"urnik","publishedAt":"2013-05-01","pages":-1,"coverUrl":"ftp://example.com/a.jpg","language":"???","tags":["` + strings.Repeat("a", 51) + `"]}`,
+			map[string]string{"pages": "min", "coverUrl": "http_url", "language": "bcp47_language_tag", "tags[0]": "max"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			books := newFakeBookRepository()
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler
+			e.Validator = validation.New()
+
+			req := httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(testCase.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			HandleTest(e, c, NewBookController(books).CreateBook)
+
+			var result struct {
+				Message string
+				Errors  []validation.FieldError
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+			assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+			assert.Equal(t, "validation failed", result.Message)
+
+			fields := map[string]string{}
+			for _, field := range result.Errors {
+				fields[field.Field] = field.Code
+			}
+			assert.Equal(t, testCase.expectedFields, fields)
+			assert.Empty(t, books.books)
+		})
+	}
+}
+
+func TestBookControllersIgnoreProtectedFields(t *testing.T) {
+	e, db := InitEchoTestAPIBook()
+	InsertDataBookForGetBooks(db)
+
+	book := models.Book{}
+	if err := db.First(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"id":99,"ID":99,"createdAt":"2000-01-01T00:00:00Z","CreatedAt":"2000-01-01T00:00:00Z",` +
+		`"DeletedAt":"2000-01-01T00:00:00Z","title":"physics","author":"newton","publishedAt":"1687-07-05"}`
+
+	req := httptest.NewRequest(http.MethodPut, "/jwt/books/:id", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(fmt.Sprint(book.ID))
+
+	HandleTest(e, c, InitBookControllerTest(db).UpdateBook)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/jwt/books", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	HandleTest(e, c, InitBookControllerTest(db).CreateBook)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	books := []models.Book{}
+	if assert.NoError(t, db.Unscoped().Order("id").Find(&books).Error) && assert.Len(t, books, 2) {
+		assert.Equal(t, book.ID, books[0].ID)
+		assert.NotEqual(t, uint(99), books[1].ID)
+		for _, stored := range books {
+			assert.Equal(t, "physics", stored.Title)
+			assert.True(t, stored.CreatedAt.Year() > 2000)
+			assert.False(t, stored.DeletedAt.Valid)
+		}
+	}
+}
+
+func TestGetAllBooksControllerPagination(t *testing.T) {
+	e, db := InitEchoTestAPIBook()
+	for _, title := range []string{"chemistry", "physics", "biology"} {
+		db.Save(&models.Book{Title: title, Author: "urnik", Published_at: models.MustParseDate("2021-01-01")})
+	}
+
+	testCases := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedLink string
+		expectedMeta response.PageMeta
+	}{
+		{"first page", "limit=1&sort=title&author=urnik", http.StatusOK,
+			`</books?author=urnik&limit=1&page=1&sort=title>; rel="first", </books?author=urnik&limit=1&page=2&sort=title>; rel="next", </books?author=urnik&limit=1&page=3&sort=title>; rel="last"`,
+			response.PageMeta{Limit: 1, Page: 1, HasMore: true}},
+		{"last page", "limit=2&page=2", http.StatusOK,
+			`</books?limit=2&page=1>; rel="first", </books?limit=2&page=1>; rel="prev", </books?limit=2&page=2>; rel="last"`,
+			response.PageMeta{Limit: 2, Page: 2}},
+		{"limit above maximum", "limit=101", http.StatusUnprocessableEntity, "", response.PageMeta{}},
+		{"unknown sort", "sort=password", http.StatusUnprocessableEntity, "", response.PageMeta{}},
+		{"page and cursor", "page=2&cursor=abc", http.StatusUnprocessableEntity, "", response.PageMeta{}},
+		{"invalid date", "published_after=2021", http.StatusUnprocessableEntity, "", response.PageMeta{}},
+		{"invalid cursor", "cursor=abc", http.StatusBadRequest, "", response.PageMeta{}},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/books?"+testCase.query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		HandleTest(e, c, InitBookControllerTest(db).GetAllBooks)
+
+		var result struct {
+			Message string
+			Data    []models.OutputBook
+			Meta    response.PageMeta
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result), testCase.name)
+		assert.Equal(t, testCase.expectedCode, rec.Code, testCase.name)
+		assert.Equal(t, testCase.expectedLink, rec.Header().Get("Link"), testCase.name)
+		if testCase.expectedCode == http.StatusOK {
+			assert.Equal(t, testCase.expectedMeta.Limit, result.Meta.Limit, testCase.name)
+			assert.Equal(t, testCase.expectedMeta.Page, result.Meta.Page, testCase.name)
+			assert.Equal(t, testCase.expectedMeta.HasMore, result.Meta.HasMore, testCase.name)
+			if assert.NotNil(t, result.Meta.Total, testCase.name) {
+				assert.Equal(t, int64(3), *result.Meta.Total, testCase.name)
+			}
+		}
+	}
+}
+
+func TestGetAllBooksControllerCursor(t *testing.T) {
+	e, db := InitEchoTestAPIBook()
+	for _, title := range []string{"chemistry", "physics", "biology"} {
+		db.Save(&models.Book{Title: title, Author: "urnik", Published_at: models.MustParseDate("2021-01-01")})
+	}
+
+	titles := []string{}
+	path := "/books?limit=2&sort=-title"
+	for path != "" {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		if !assert.NoError(t, HandleTest(e, c, InitBookControllerTest(db).GetAllBooks)) || !assert.Equal(t, http.StatusOK, rec.Code) {
+			return
+		}
+
+		var result struct {
+			Data []models.OutputBook
+			Meta response.PageMeta
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		for _, book := range result.Data {
+			titles = append(titles, book.Title)
+		}
+
+		path = ""
+		if result.Meta.HasMore {
+			path = "/books?" + url.Values{"limit": {"2"}, "sort": {"-title"}, "cursor": {result.Meta.NextCursor}}.Encode()
+			assert.Contains(t, rec.Header().Get("Link"), `rel="next"`)
+		}
+	}
+
+	assert.Equal(t, []string{"physics", "chemistry", "biology"}, titles)
+}
+
+func TestSearchBooksController(t *testing.T) {
+	e, db := InitEchoTestAPIBook()
+	h := InitBookControllerTest(db)
+	for _, book := range []models.Book{
+		{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")},
+		{Title: "physics", Author: "newton", Published_at: models.MustParseDate("1687-07-05")},
+	} {
+		book := book
+		if err := h.Books.Create(&book); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedCode   int
+		expectedTitles []string
+	}{
+		{"match", "q=physics", http.StatusOK, []string{"physics"}},
+		{"typo", "q=newtn", http.StatusOK, []string{"physics"}},
+		{"no match", "q=astronomy", http.StatusOK, []string{}},
+		{"missing query", "", http.StatusUnprocessableEntity, []string{}},
+		{"limit above maximum", "q=physics&limit=500", http.StatusUnprocessableEntity, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/books/search?"+testCase.query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		HandleTest(e, c, h.SearchBooks)
+
+		var result struct {
+			Data []response.BookSearchResult
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result), testCase.name)
+		assert.Equal(t, testCase.expectedCode, rec.Code, testCase.name)
+
+		titles := []string{}
+		for _, match := range result.Data {
+			titles = append(titles, match.Book.Title)
+			assert.NotZero(t, match.Book.ID, testCase.name)
+			assert.NotEmpty(t, match.Highlights, testCase.name)
+		}
+		assert.Equal(t, testCase.expectedTitles, titles, testCase.name)
+	}
+}
+
+func TestCreateBookControllerDetails(t *testing.T) {
+	e, db := InitEchoTestAPIBook()
+	h := InitBookControllerTest(db)
+
+	body := `{"title":"physics","author":"newton","publishedAt":"1687-07-05","isbn":"0-306-40615-2",` +
+		`"description":"laws of motion","language":"la","pages":510,"publisher":"royal society",` +
+		`"coverUrl":"https://example.com/principia.jpg","tags":["Science","classics"]}`
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/jwt/books", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		HandleTest(e, e.NewContext(req, rec), h.CreateBook)
+		return rec
+	}
+
+	rec := post()
+	var result struct {
+		Data models.OutputBook
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "9780306406157", *result.Data.ISBN)
+	assert.Equal(t, models.MustParseDate("1687-07-05"), result.Data.Published_at)
+	assert.Equal(t, 510, result.Data.Pages)
+	assert.Equal(t, []string{"classics", "science"}, result.Data.Tags)
+	assert.Contains(t, rec.Body.String(), `"Published_at":"1687-07-05"`)
+
+	rec = post()
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "a book with this ISBN already exists")
 }
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/models"
+	"cleancode/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type BookCopyController struct {
+	Copies databases.BookCopyRepository
+}
+
+func NewBookCopyController(copies databases.BookCopyRepository) *BookCopyController {
+	return &BookCopyController{Copies: copies}
+}
+
+func (h *BookCopyController) GetBookCopies(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
+	}
+
+	query := models.BookCopyListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	filter := databases.BookCopyFilter{Status: query.Status}
+	copies, page, err := h.Copies.List(bookId, filter, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputBookCopies(copies), page))
+}
+
+func (h *BookCopyController) GetSingleBookCopy(c echo.Context) error {
+	bookId, copyId, err := parseCopyParams(c)
+	if err != nil {
+		return err
+	}
+
+	bookCopy, err := h.Copies.GetByID(bookId, copyId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", bookCopy.Output()))
+}
+
+func (h *BookCopyController) CreateBookCopy(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
+	}
+
+	input := models.CreateBookCopyInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	bookCopy := input.BookCopy()
+	bookCopy.BookID = bookId
+
+	if err := h.Copies.Create(&bookCopy); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", bookCopy.Output()))
+}
+
+func (h *BookCopyController) UpdateBookCopy(c echo.Context) error {
+	bookId, copyId, err := parseCopyParams(c)
+	if err != nil {
+		return err
+	}
+
+	input := models.UpdateBookCopyInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	bookCopy, err := h.Copies.Update(bookId, copyId, input.BookCopy())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", bookCopy.Output()))
+}
+
+func (h *BookCopyController) DeleteBookCopy(c echo.Context) error {
+	bookId, copyId, err := parseCopyParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Copies.Delete(bookId, copyId); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", "deleted"))
+}
+
+// parseCopyParams reads the book and copy ids of /books/:id/copies/:copyId.
+func parseCopyParams(c echo.Context) (uint, uint, error) {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return 0, 0, err
+	}
+	copyId, err := parseParam(c, "copyId", "invalid copy id")
+	return bookId, copyId, err
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"cleancode/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookCopyControllerWithFakeRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		params       []string
+		handler      func(h *BookCopyController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"get copies", http.MethodGet, "/", "", []string{"id", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.GetBookCopies }, http.StatusOK, "success"},
+		{"get copies by status", http.MethodGet, "/?status=on-loan", "", []string{"id", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.GetBookCopies }, http.StatusOK, "success"},
+		{"get copies by unknown status", http.MethodGet, "/?status=stolen", "", []string{"id", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.GetBookCopies }, http.StatusUnprocessableEntity, "validation failed"},
+		{"get copies of unknown book", http.MethodGet, "/", "", []string{"id", "9"}, func(h *BookCopyController) echo.HandlerFunc { return h.GetBookCopies }, http.StatusNotFound, "book not found"},
+		{"get copy", http.MethodGet, "/", "", []string{"id", "1", "copyId", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.GetSingleBookCopy }, http.StatusOK, "success"},
+		{"get copy of another book", http.MethodGet, "/", "", []string{"id", "2", "copyId", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.GetSingleBookCopy }, http.StatusNotFound, "copy not found"},
+		{"get copy invalid id", http.MethodGet, "/", "", []string{"id", "1", "copyId", "abc"}, func(h *BookCopyController) echo.HandlerFunc { return h.GetSingleBookCopy }, http.StatusBadRequest, "invalid copy id"},
+		{"create copy", http.MethodPost, "/", `{"barcode":"ab-2","shelfLocation":"S1"}`, []string{"id", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.CreateBookCopy }, http.StatusCreated, "success"},
+		{"create copy with taken barcode", http.MethodPost, "/", `{"barcode":"ab-1"}`, []string{"id", "2"}, func(h *BookCopyController) echo.HandlerFunc { return h.CreateBookCopy }, http.StatusConflict, "a copy with this barcode already exists"},
+		{"create copy on loan", http.MethodPost, "/", `{"barcode":"ab-2","status":"on-loan"}`, []string{"id", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.CreateBookCopy }, http.StatusUnprocessableEntity, "validation failed"},
+		{"update copy", http.MethodPut, "/", `{"condition":"poor"}`, []string{"id", "1", "copyId", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.UpdateBookCopy }, http.StatusOK, "success"},
+		{"update copy on loan", http.MethodPut, "/", `{"status":"lost"}`, []string{"id", "1", "copyId", "2"}, func(h *BookCopyController) echo.HandlerFunc { return h.UpdateBookCopy }, http.StatusConflict, "copy is on loan"},
+		{"delete copy", http.MethodDelete, "/", "", []string{"id", "1", "copyId", "1"}, func(h *BookCopyController) echo.HandlerFunc { return h.DeleteBookCopy }, http.StatusOK, "success"},
+		{"delete copy on loan", http.MethodDelete, "/", "", []string{"id", "1", "copyId", "2"}, func(h *BookCopyController) echo.HandlerFunc { return h.DeleteBookCopy }, http.StatusConflict, "copy is on loan"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			copies := newFakeBookCopyRepository(1, 2)
+			for _, bookCopy := range []models.BookCopy{
+				{BookID: 1, Barcode: "AB-1", Status: models.CopyAvailable},
+				{BookID: 1, Barcode: "AB-9", Status: models.CopyOnLoan},
+			} {
+				if err := copies.Create(&bookCopy); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h := NewBookCopyController(copies)
+
+			rec, message := serveWithFake(t, testCase.handler(h), testCase.method, testCase.target, testCase.body, 0, "", testCase.params...)
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, message)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/lib/validation"
+	"cleancode/response"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPErrorHandler is the echo.HTTPErrorHandler for the API. Handlers return
+// errors instead of writing failures themselves, and this maps them onto a
+// status code and a {"message": ...} body.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	code, message := errorStatus(err)
+	if code >= http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+
+	body := response.ErrorResponse(message)
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		body = response.ValidationErrorResponse(message, invalid.Fields)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(code)
+	} else {
+		err = c.JSON(code, body)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+func errorStatus(err error) (int, string) {
+	var httpError *echo.HTTPError
+	var invalid *validation.Error
+
+	switch {
+	case errors.As(err, &httpError):
+		if httpError.Code >= http.StatusInternalServerError {
+			return httpError.Code, http.StatusText(httpError.Code)
+		}
+		return httpError.Code, fmt.Sprint(httpError.Message)
+	case errors.As(err, &invalid):
+		return http.StatusUnprocessableEntity, "validation failed"
+	case errors.Is(err, databases.ErrInvalidCursor), errors.Is(err, databases.ErrInvalidSort):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, databases.ErrNotFound):
+		return http.StatusNotFound, err.Error()
+	case isConflict(err):
+		return http.StatusConflict, conflictMessage(err)
+	case isRefreshTokenError(err):
+		return http.StatusUnauthorized, databases.ErrRefreshTokenInvalid.Error()
+	}
+
+	return http.StatusInternalServerError, "internal server error"
+}
+
+// conflicts are the errors for requests that clash with the stored state,
+// other than a bare databases.ErrConflict. Their text is written for clients.
+var conflicts = []error{
+	databases.ErrEmailTaken,
+	databases.ErrISBNTaken,
+	databases.ErrBarcodeTaken,
+	databases.ErrAlreadyReviewed,
+	databases.ErrListKindTaken,
+	databases.ErrAuthorHasBooks,
+	databases.ErrNoCopyAvailable,
+	databases.ErrLoanLimitReached,
+	databases.ErrAlreadyBorrowed,
+	databases.ErrLoanReturned,
+	databases.ErrCopyOnLoan,
+	databases.ErrCopyHeld,
+	databases.ErrFinesOwed,
+	databases.ErrAmountExceedsBalance,
+	databases.ErrCopyAvailable,
+	databases.ErrAlreadyReserved,
+	databases.ErrAlreadyListed,
+	databases.ErrListFull,
+	databases.ErrListOrderMismatch,
+}
+
+// isConflict reports errors for requests that clash with the stored state.
+func isConflict(err error) bool {
+	if errors.Is(err, databases.ErrConflict) {
+		return true
+	}
+	for _, conflict := range conflicts {
+		if errors.Is(err, conflict) {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictMessage is the text of the conflict err wraps. Any other unique
+// violation gets the text of databases.ErrConflict alone, since the driver's
+// message it wraps names tables and values.
+func conflictMessage(err error) string {
+	for _, conflict := range conflicts {
+		if errors.Is(err, conflict) {
+			return conflict.Error()
+		}
+	}
+	return databases.ErrConflict.Error()
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/lib/validation"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	testCases := []struct {
+		name            string
+		err             error
+		expectedCode    int
+		expectedMessage string
+	}{
+		{"not found", databases.ErrBookNotFound, http.StatusNotFound, "book not found"},
+		{"wrapped not found", fmt.Errorf("loading: %w", databases.ErrUserNotFound), http.StatusNotFound, "loading: user not found"},
+		{"conflict", fmt.Errorf("%w: UNIQUE constraint failed: users.email", databases.ErrConflict), http.StatusConflict, "already exists"},
+		{"named conflict", databases.ErrEmailTaken, http.StatusConflict, "a user with this email already exists"},
+		{"other conflict", databases.ErrListFull, http.StatusConflict, "reading list is full"},
+		{"invalid cursor", databases.ErrInvalidCursor, http.StatusBadRequest, "invalid cursor"},
+		{"credentials", errInvalidCredentials, http.StatusUnauthorized, "invalid email or password"},
+		{"refresh token reuse", databases.ErrRefreshTokenReused, http.StatusUnauthorized, "invalid refresh token"},
+		{"forbidden", echo.NewHTTPError(http.StatusForbidden, "forbidden"), http.StatusForbidden, "forbidden"},
+		{"validation", echo.NewHTTPError(http.StatusUnprocessableEntity, "invalid role"), http.StatusUnprocessableEntity, "invalid role"},
+		{"unexpected", errors.New("dial tcp: connection refused"), http.StatusInternalServerError, "internal server error"},
+	}
+
+	for _, testCase := range testCases {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		HTTPErrorHandler(testCase.err, c)
+
+		var body struct {
+			Message string
+		}
+		err := json.Unmarshal(rec.Body.Bytes(), &body)
+		if err != nil {
+			assert.Error(t, err, "error")
+		}
+
+		assert.Equal(t, testCase.expectedCode, rec.Code, testCase.name)
+		assert.Equal(t, testCase.expectedMessage, body.Message, testCase.name)
+	}
+}
+
+func TestHTTPErrorHandlerListsInvalidFields(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	HTTPErrorHandler(&validation.Error{Fields: []validation.FieldError{
+		{Field: "title", Code: "required", Message: "is required"},
+	}}, c)
+
+	var body struct {
+		Message string
+		Errors  []validation.FieldError
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "validation failed", body.Message)
+	assert.Equal(t, []validation.FieldError{{Field: "title", Code: "required", Message: "is required"}}, body.Errors)
+}
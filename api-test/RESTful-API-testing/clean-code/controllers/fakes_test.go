@@ -0,0 +1,1020 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/lib/validation"
+	"cleancode/models"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveWithFake runs handler, usually over fake repositories, for a request
+// with a JSON body and returns the response with its message. A non-zero
+// userId sends a token for that user and role through the JWT middleware;
+// without one the request is anonymous. params are path parameter names,
+// each followed by its value.
+func serveWithFake(t *testing.T, handler echo.HandlerFunc, method, target, body string, userId int, role string, params ...string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = validation.New()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if userId != 0 {
+		token, err := InitTokenManagerTest().CreateToken(userId, role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %v", token))
+		handler = InitTokenManagerTest().JWT()(handler)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+
+	HandleTest(e, c, handler)
+
+	var result struct {
+		Message string
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	return rec, result.Message
+}
+
+// fakeBookRepository keeps books in memory so handlers can be tested without
+// a database. Setting err makes every call fail with it.
+type fakeBookRepository struct {
+	books  map[uint]models.Book
+	nextId uint
+	err    error
+}
+
+func newFakeBookRepository(books ...models.Book) *fakeBookRepository {
+	r := &fakeBookRepository{books: map[uint]models.Book{}}
+	for i := range books {
+		r.Create(&books[i])
+	}
+	return r
+}
+
+// List ignores the filter and sort and returns every book as one page.
+func (r *fakeBookRepository) List(filter databases.BookFilter, opts databases.ListOptions) ([]models.Book, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+
+	books := []models.Book{}
+	for id := uint(1); id <= r.nextId; id++ {
+		if book, ok := r.books[id]; ok {
+			books = append(books, book)
+		}
+	}
+	return books, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(books))}, nil
+}
+
+// Search returns the books whose title or author contains text, ignoring
+// case, as one page.
+func (r *fakeBookRepository) Search(text string, opts databases.ListOptions) ([]databases.BookMatch, databases.Page, error) {
+	books, _, err := r.List(databases.BookFilter{}, opts)
+	if err != nil {
+		return nil, databases.Page{}, err
+	}
+
+	matches := []databases.BookMatch{}
+	text = strings.ToLower(text)
+	for _, book := range books {
+		if strings.Contains(strings.ToLower(book.Title+" "+book.Author), text) {
+			matches = append(matches, databases.BookMatch{Book: book, Score: 1})
+		}
+	}
+	return matches, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(matches))}, nil
+}
+
+func (r *fakeBookRepository) GetByID(id uint) (models.Book, error) {
+	if r.err != nil {
+		return models.Book{}, r.err
+	}
+
+	book, ok := r.books[id]
+	if !ok {
+		return models.Book{}, databases.ErrBookNotFound
+	}
+	return book, nil
+}
+
+func (r *fakeBookRepository) Create(book *models.Book) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	r.nextId++
+	book.ID = r.nextId
+	book.CreatedAt = time.Now()
+	book.UpdatedAt = book.CreatedAt
+	book.Copies, book.AvailableCopies = 0, 0
+	r.books[book.ID] = *book
+	return nil
+}
+
+func (r *fakeBookRepository) Update(id uint, changes models.Book) (models.Book, error) {
+	book, err := r.GetByID(id)
+	if err != nil {
+		return book, err
+	}
+
+	if changes.Title != "" {
+		book.Title = changes.Title
+	}
+	if changes.Author != "" {
+		book.Author = changes.Author
+	}
+	if !changes.Published_at.IsZero() {
+		book.Published_at = changes.Published_at
+	}
+	if changes.ISBN != nil {
+		book.ISBN = changes.ISBN
+	}
+	if changes.Description != "" {
+		book.Description = changes.Description
+	}
+	if changes.Language != "" {
+		book.Language = changes.Language
+	}
+	if changes.Pages != 0 {
+		book.Pages = changes.Pages
+	}
+	if changes.Publisher != "" {
+		book.Publisher = changes.Publisher
+	}
+	if changes.CoverURL != "" {
+		book.CoverURL = changes.CoverURL
+	}
+	if changes.Tags != nil {
+		book.Tags = changes.Tags
+	}
+	if changes.Authors != nil {
+		book.Authors = changes.Authors
+	}
+	r.books[id] = book
+	return book, nil
+}
+
+func (r *fakeBookRepository) Delete(id uint) error {
+	if _, err := r.GetByID(id); err != nil {
+		return err
+	}
+
+	delete(r.books, id)
+	return nil
+}
+
+// fakeAuthorRepository keeps authors and the books credited to them in
+// memory. Setting err makes every call fail with it.
+type fakeAuthorRepository struct {
+	authors map[uint]models.Author
+	books   map[uint][]models.Book
+	nextId  uint
+	err     error
+}
+
+func newFakeAuthorRepository(authors ...models.Author) *fakeAuthorRepository {
+	r := &fakeAuthorRepository{authors: map[uint]models.Author{}, books: map[uint][]models.Book{}}
+	for i := range authors {
+		r.Create(&authors[i])
+	}
+	return r
+}
+
+// List ignores the filter and sort and returns every author as one page.
+func (r *fakeAuthorRepository) List(filter databases.AuthorFilter, opts databases.ListOptions) ([]models.Author, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+
+	authors := []models.Author{}
+	for id := uint(1); id <= r.nextId; id++ {
+		if author, ok := r.authors[id]; ok {
+			authors = append(authors, author)
+		}
+	}
+	return authors, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(authors))}, nil
+}
+
+func (r *fakeAuthorRepository) GetByID(id uint) (models.Author, error) {
+	if r.err != nil {
+		return models.Author{}, r.err
+	}
+
+	author, ok := r.authors[id]
+	if !ok {
+		return models.Author{}, databases.ErrAuthorNotFound
+	}
+	return author, nil
+}
+
+// Books ignores the options and returns every book of the author as one page.
+func (r *fakeAuthorRepository) Books(id uint, opts databases.ListOptions) ([]models.Book, databases.Page, error) {
+	if _, err := r.GetByID(id); err != nil {
+		return nil, databases.Page{}, err
+	}
+
+	books := append([]models.Book{}, r.books[id]...)
+	return books, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(books))}, nil
+}
+
+func (r *fakeAuthorRepository) Create(author *models.Author) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	r.nextId++
+	author.ID = r.nextId
+	author.CreatedAt = time.Now()
+	author.UpdatedAt = author.CreatedAt
+	r.authors[author.ID] = *author
+	return nil
+}
+
+func (r *fakeAuthorRepository) Update(id uint, changes models.Author) (models.Author, error) {
+	author, err := r.GetByID(id)
+	if err != nil {
+		return author, err
+	}
+
+	if changes.Name != "" {
+		author.Name = changes.Name
+	}
+	if changes.Bio != "" {
+		author.Bio = changes.Bio
+	}
+	r.authors[id] = author
+	return author, nil
+}
+
+func (r *fakeAuthorRepository) Delete(id uint) error {
+	if _, err := r.GetByID(id); err != nil {
+		return err
+	}
+	if len(r.books[id]) > 0 {
+		return databases.ErrAuthorHasBooks
+	}
+
+	delete(r.authors, id)
+	return nil
+}
+
+// fakeUserRepository keeps users in memory. Setting err makes every call fail
+// with it.
+type fakeUserRepository struct {
+	users  map[uint]models.User
+	nextId uint
+	err    error
+}
+
+func newFakeUserRepository(users ...models.User) *fakeUserRepository {
+	r := &fakeUserRepository{users: map[uint]models.User{}}
+	for i := range users {
+		r.Create(&users[i])
+	}
+	return r
+}
+
+// List ignores the filter and sort and returns every user as one page.
+func (r *fakeUserRepository) List(filter databases.UserFilter, opts databases.ListOptions) ([]models.User, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+
+	users := []models.User{}
+	for id := uint(1); id <= r.nextId; id++ {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(users))}, nil
+}
+
+func (r *fakeUserRepository) GetByID(id uint) (models.User, error) {
+	if r.err != nil {
+		return models.User{}, r.err
+	}
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, databases.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetByEmail(email string) (models.User, error) {
+	if r.err != nil {
+		return models.User{}, r.err
+	}
+
+	for _, user := range r.users {
+		if user.Email == models.NormalizeEmail(email) {
+			return user, nil
+		}
+	}
+	return models.User{}, databases.ErrUserNotFound
+}
+
+func (r *fakeUserRepository) Create(user *models.User) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	user.Email = models.NormalizeEmail(user.Email)
+	if _, err := r.GetByEmail(user.Email); err == nil {
+		return databases.ErrEmailTaken
+	}
+	if user.Role == "" {
+		user.Role = models.RoleMember
+	}
+	r.nextId++
+	user.ID = r.nextId
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *fakeUserRepository) Update(id uint, changes models.User) (models.User, error) {
+	user, err := r.GetByID(id)
+	if err != nil {
+		return user, err
+	}
+
+	if changes.Name != "" {
+		user.Name = changes.Name
+	}
+	if changes.Email != "" {
+		if other, err := r.GetByEmail(changes.Email); err == nil && other.ID != id {
+			return user, databases.ErrEmailTaken
+		}
+		user.Email = models.NormalizeEmail(changes.Email)
+	}
+	if changes.Password != "" {
+		user.Password = changes.Password
+	}
+	if changes.Role != "" {
+		user.Role = changes.Role
+	}
+	r.users[id] = user
+	return user, nil
+}
+
+func (r *fakeUserRepository) UpdateRole(id uint, role string) (models.User, error) {
+	return r.Update(id, models.User{Role: role})
+}
+
+func (r *fakeUserRepository) UpdatePassword(id uint, hashed string) error {
+	_, err := r.Update(id, models.User{Password: hashed})
+	return err
+}
+
+func (r *fakeUserRepository) Delete(id uint) error {
+	if _, err := r.GetByID(id); err != nil {
+		return err
+	}
+
+	delete(r.users, id)
+	return nil
+}
+
+// fakeRefreshTokenRepository hands out numbered tokens without tracking
+// families; rotation itself is covered by the GORM repository tests.
+type fakeRefreshTokenRepository struct {
+	owners map[string]uint
+	issued int
+}
+
+func newFakeRefreshTokenRepository() *fakeRefreshTokenRepository {
+	return &fakeRefreshTokenRepository{owners: map[string]uint{}}
+}
+
+func (r *fakeRefreshTokenRepository) Create(userId uint, familyId string, ttl time.Duration) (string, error) {
+	r.issued++
+	raw := fmt.Sprintf("refresh-%d", r.issued)
+	r.owners[raw] = userId
+	return raw, nil
+}
+
+func (r *fakeRefreshTokenRepository) Rotate(raw string, ttl time.Duration) (string, uint, error) {
+	userId, ok := r.owners[raw]
+	if !ok {
+		return "", 0, databases.ErrRefreshTokenInvalid
+	}
+
+	delete(r.owners, raw)
+	next, err := r.Create(userId, "", ttl)
+	return next, userId, err
+}
+
+func (r *fakeRefreshTokenRepository) Revoke(raw string) error {
+	if !strings.HasPrefix(raw, "refresh-") {
+		return databases.ErrRefreshTokenInvalid
+	}
+
+	delete(r.owners, raw)
+	return nil
+}
+
+var (
+	_ databases.BookRepository         = (*fakeBookRepository)(nil)
+	_ databases.UserRepository         = (*fakeUserRepository)(nil)
+	_ databases.RefreshTokenRepository = (*fakeRefreshTokenRepository)(nil)
+)
+
+// fakeLoanRepository lends from a fixed number of copies per book, without
+// a loan limit. Setting err makes every call fail with it.
+type fakeLoanRepository struct {
+	loans     map[uint]models.Loan
+	available map[uint]int
+	nextId    uint
+	err       error
+}
+
+func newFakeLoanRepository(available map[uint]int) *fakeLoanRepository {
+	return &fakeLoanRepository{loans: map[uint]models.Loan{}, available: available}
+}
+
+func (r *fakeLoanRepository) GetByID(id uint) (models.Loan, error) {
+	if r.err != nil {
+		return models.Loan{}, r.err
+	}
+
+	loan, ok := r.loans[id]
+	if !ok {
+		return models.Loan{}, databases.ErrLoanNotFound
+	}
+	return loan, nil
+}
+
+// ListActive ignores the options and returns every active loan of the user
+// as one page.
+func (r *fakeLoanRepository) ListActive(userId uint, opts databases.ListOptions) ([]models.Loan, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+
+	loans := []models.Loan{}
+	for id := uint(1); id <= r.nextId; id++ {
+		if loan, ok := r.loans[id]; ok && loan.UserID == userId && loan.ReturnedAt == nil {
+			loans = append(loans, loan)
+		}
+	}
+	return loans, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(loans))}, nil
+}
+
+func (r *fakeLoanRepository) Checkout(userId uint, bookId uint) (models.Loan, error) {
+	if r.err != nil {
+		return models.Loan{}, r.err
+	}
+
+	available, ok := r.available[bookId]
+	if !ok {
+		return models.Loan{}, databases.ErrBookNotFound
+	}
+	if available == 0 {
+		return models.Loan{}, databases.ErrNoCopyAvailable
+	}
+	r.available[bookId]--
+
+	r.nextId++
+	now := time.Now()
+	loan := models.Loan{ID: r.nextId, UserID: userId, BookID: bookId, BorrowedAt: now, DueAt: now.Add(14 * 24 * time.Hour)}
+	r.loans[loan.ID] = loan
+	return loan, nil
+}
+
+func (r *fakeLoanRepository) Return(id uint) (models.Loan, error) {
+	loan, err := r.GetByID(id)
+	if err != nil {
+		return loan, err
+	}
+	if loan.ReturnedAt != nil {
+		return loan, databases.ErrLoanReturned
+	}
+
+	now := time.Now()
+	loan.ReturnedAt = &now
+	r.loans[id] = loan
+	r.available[loan.BookID]++
+	return loan, nil
+}
+
+// fakeReservationRepository queues users in memory for the books in
+// waitable, which have no copy on the shelf. Setting err makes every call
+// fail with it.
+type fakeReservationRepository struct {
+	reservations []models.Reservation
+	waitable     map[uint]bool
+	err          error
+}
+
+func newFakeReservationRepository(waitable ...uint) *fakeReservationRepository {
+	r := &fakeReservationRepository{waitable: map[uint]bool{}}
+	for _, bookId := range waitable {
+		r.waitable[bookId] = true
+	}
+	return r
+}
+
+func (r *fakeReservationRepository) Reserve(userId uint, bookId uint) (models.Reservation, error) {
+	if r.err != nil {
+		return models.Reservation{}, r.err
+	}
+	if !r.waitable[bookId] {
+		return models.Reservation{}, databases.ErrCopyAvailable
+	}
+
+	reservation := models.Reservation{ID: uint(len(r.reservations) + 1), UserID: userId, BookID: bookId, Status: models.ReservationWaiting, Position: 1}
+	for _, queued := range r.reservations {
+		if queued.BookID == bookId {
+			if queued.UserID == userId {
+				return models.Reservation{}, databases.ErrAlreadyReserved
+			}
+			reservation.Position++
+		}
+	}
+	r.reservations = append(r.reservations, reservation)
+	return reservation, nil
+}
+
+// ListByUser ignores the options and returns every matching reservation of
+// the user as one page.
+func (r *fakeReservationRepository) ListByUser(userId uint, filter databases.ReservationFilter, opts databases.ListOptions) ([]models.Reservation, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+
+	reservations := []models.Reservation{}
+	for _, reservation := range r.reservations {
+		if reservation.UserID == userId && (filter.Status == "" || reservation.Status == filter.Status) {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(reservations))}, nil
+}
+
+func (r *fakeReservationRepository) ExpireHolds() error {
+	return r.err
+}
+
+// fakeBookCopyRepository stores the copies of the books in books in memory,
+// without keeping counts. Setting err makes every call fail with it.
+type fakeBookCopyRepository struct {
+	books  map[uint]bool
+	copies map[uint]models.BookCopy
+	nextId uint
+	err    error
+}
+
+func newFakeBookCopyRepository(books ...uint) *fakeBookCopyRepository {
+	r := &fakeBookCopyRepository{books: map[uint]bool{}, copies: map[uint]models.BookCopy{}}
+	for _, bookId := range books {
+		r.books[bookId] = true
+	}
+	return r
+}
+
+// List ignores the options and returns every matching copy of the book as
+// one page.
+func (r *fakeBookCopyRepository) List(bookId uint, filter databases.BookCopyFilter, opts databases.ListOptions) ([]models.BookCopy, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+	if !r.books[bookId] {
+		return nil, databases.Page{}, databases.ErrBookNotFound
+	}
+
+	copies := []models.BookCopy{}
+	for id := uint(1); id <= r.nextId; id++ {
+		bookCopy, ok := r.copies[id]
+		if ok && bookCopy.BookID == bookId && (filter.Status == "" || bookCopy.Status == filter.Status) {
+			copies = append(copies, bookCopy)
+		}
+	}
+	return copies, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(copies))}, nil
+}
+
+func (r *fakeBookCopyRepository) GetByID(bookId uint, id uint) (models.BookCopy, error) {
+	if r.err != nil {
+		return models.BookCopy{}, r.err
+	}
+
+	bookCopy, ok := r.copies[id]
+	if !ok || bookCopy.BookID != bookId {
+		return models.BookCopy{}, databases.ErrCopyNotFound
+	}
+	return bookCopy, nil
+}
+
+func (r *fakeBookCopyRepository) Create(bookCopy *models.BookCopy) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.books[bookCopy.BookID] {
+		return databases.ErrBookNotFound
+	}
+	for _, stored := range r.copies {
+		if stored.Barcode == bookCopy.Barcode {
+			return databases.ErrBarcodeTaken
+		}
+	}
+
+	r.nextId++
+	bookCopy.ID = r.nextId
+	r.copies[bookCopy.ID] = *bookCopy
+	return nil
+}
+
+func (r *fakeBookCopyRepository) Update(bookId uint, id uint, changes models.BookCopy) (models.BookCopy, error) {
+	bookCopy, err := r.GetByID(bookId, id)
+	if err != nil {
+		return bookCopy, err
+	}
+	if changes.Status != "" && bookCopy.Status == models.CopyOnLoan {
+		return bookCopy, databases.ErrCopyOnLoan
+	}
+
+	if changes.Barcode != "" {
+		bookCopy.Barcode = changes.Barcode
+	}
+	if changes.ShelfLocation != "" {
+		bookCopy.ShelfLocation = changes.ShelfLocation
+	}
+	if changes.Condition != "" {
+		bookCopy.Condition = changes.Condition
+	}
+	if changes.Status != "" {
+		bookCopy.Status = changes.Status
+	}
+	r.copies[id] = bookCopy
+	return bookCopy, nil
+}
+
+func (r *fakeBookCopyRepository) Delete(bookId uint, id uint) error {
+	bookCopy, err := r.GetByID(bookId, id)
+	if err != nil {
+		return err
+	}
+	if bookCopy.Status == models.CopyOnLoan {
+		return databases.ErrCopyOnLoan
+	}
+
+	delete(r.copies, id)
+	return nil
+}
+
+// fakeFineRepository keeps fine ledgers in memory for the users in users,
+// without fees accruing. Setting err makes every call fail with it.
+type fakeFineRepository struct {
+	users   map[uint]bool
+	entries []models.FineEntry
+	err     error
+}
+
+func newFakeFineRepository(users ...uint) *fakeFineRepository {
+	r := &fakeFineRepository{users: map[uint]bool{}}
+	for _, userId := range users {
+		r.users[userId] = true
+	}
+	return r
+}
+
+// List ignores the options and returns every matching entry of the user,
+// newest first, as one page.
+func (r *fakeFineRepository) List(userId uint, filter databases.FineFilter, opts databases.ListOptions) ([]models.FineEntry, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+	if !r.users[userId] {
+		return nil, databases.Page{}, databases.ErrUserNotFound
+	}
+
+	entries := []models.FineEntry{}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		entry := r.entries[i]
+		if entry.UserID == userId && (filter.Kind == "" || entry.Kind == filter.Kind) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(entries))}, nil
+}
+
+func (r *fakeFineRepository) Balance(userId uint) (models.FineBalance, error) {
+	if r.err != nil {
+		return models.FineBalance{}, r.err
+	}
+	if !r.users[userId] {
+		return models.FineBalance{}, databases.ErrUserNotFound
+	}
+
+	balance := models.FineBalance{UserID: userId, Balance: decimal.Zero, Accruing: decimal.Zero, Limit: decimal.New(5, 0)}
+	for _, entry := range r.entries {
+		if entry.UserID == userId {
+			balance.Balance = balance.Balance.Add(entry.Amount)
+		}
+	}
+	return balance, nil
+}
+
+func (r *fakeFineRepository) Record(entry *models.FineEntry) error {
+	balance, err := r.Balance(entry.UserID)
+	if err != nil {
+		return err
+	}
+	if balance.Balance.Add(entry.Amount).IsNegative() {
+		return databases.ErrAmountExceedsBalance
+	}
+
+	entry.ID = uint(len(r.entries) + 1)
+	entry.CreatedAt = time.Now()
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// fakeReviewRepository keeps reviews in memory for the books in books, one
+// per user and book. Setting err makes every call fail with it.
+type fakeReviewRepository struct {
+	reviews map[uint]models.Review
+	books   map[uint]bool
+	nextId  uint
+	err     error
+}
+
+func newFakeReviewRepository(books ...uint) *fakeReviewRepository {
+	r := &fakeReviewRepository{reviews: map[uint]models.Review{}, books: map[uint]bool{}}
+	for _, bookId := range books {
+		r.books[bookId] = true
+	}
+	return r
+}
+
+// ListByBook ignores the options and returns every review of the book, by
+// id, as one page.
+func (r *fakeReviewRepository) ListByBook(bookId uint, opts databases.ListOptions) ([]models.Review, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+	if !r.books[bookId] {
+		return nil, databases.Page{}, databases.ErrBookNotFound
+	}
+
+	reviews := []models.Review{}
+	for id := uint(1); id <= r.nextId; id++ {
+		if review, ok := r.reviews[id]; ok && review.BookID == bookId {
+			reviews = append(reviews, review)
+		}
+	}
+	return reviews, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(reviews))}, nil
+}
+
+func (r *fakeReviewRepository) GetByID(id uint) (models.Review, error) {
+	if r.err != nil {
+		return models.Review{}, r.err
+	}
+
+	review, ok := r.reviews[id]
+	if !ok {
+		return models.Review{}, databases.ErrReviewNotFound
+	}
+	return review, nil
+}
+
+func (r *fakeReviewRepository) Create(review *models.Review) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.books[review.BookID] {
+		return databases.ErrBookNotFound
+	}
+	for _, existing := range r.reviews {
+		if existing.BookID == review.BookID && existing.UserID == review.UserID {
+			return databases.ErrAlreadyReviewed
+		}
+	}
+
+	r.nextId++
+	review.ID = r.nextId
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+	r.reviews[review.ID] = *review
+	return nil
+}
+
+func (r *fakeReviewRepository) Update(id uint, changes models.Review) (models.Review, error) {
+	review, err := r.GetByID(id)
+	if err != nil {
+		return review, err
+	}
+
+	if changes.Rating != 0 {
+		review.Rating = changes.Rating
+	}
+	if changes.Text != "" {
+		review.Text = changes.Text
+	}
+	review.UpdatedAt = time.Now()
+	r.reviews[id] = review
+	return review, nil
+}
+
+func (r *fakeReviewRepository) Delete(id uint) error {
+	if _, err := r.GetByID(id); err != nil {
+		return err
+	}
+
+	delete(r.reviews, id)
+	return nil
+}
+
+// fakeReadingListRepository keeps reading lists in memory, with the books
+// in books available to add. Slugs are the list ids. Setting err makes every
+// call fail with it.
+type fakeReadingListRepository struct {
+	lists  map[uint]models.ReadingList
+	books  map[uint]bool
+	nextId uint
+	err    error
+}
+
+func newFakeReadingListRepository(books ...uint) *fakeReadingListRepository {
+	r := &fakeReadingListRepository{lists: map[uint]models.ReadingList{}, books: map[uint]bool{}}
+	for _, bookId := range books {
+		r.books[bookId] = true
+	}
+	return r
+}
+
+// ListByUser ignores the options and returns every matching list of the
+// user, by id, as one page.
+func (r *fakeReadingListRepository) ListByUser(userId uint, filter databases.ReadingListFilter, opts databases.ListOptions) ([]models.ReadingList, databases.Page, error) {
+	if r.err != nil {
+		return nil, databases.Page{}, r.err
+	}
+
+	lists := []models.ReadingList{}
+	for id := uint(1); id <= r.nextId; id++ {
+		if list, ok := r.lists[id]; ok && list.UserID == userId && (filter.Kind == "" || list.Kind == filter.Kind) {
+			list.Items = nil
+			lists = append(lists, list)
+		}
+	}
+	return lists, databases.Page{Limit: databases.MaxPageSize, Page: 1, Total: int64(len(lists))}, nil
+}
+
+func (r *fakeReadingListRepository) GetByID(id uint) (models.ReadingList, error) {
+	if r.err != nil {
+		return models.ReadingList{}, r.err
+	}
+
+	list, ok := r.lists[id]
+	if !ok {
+		return models.ReadingList{}, databases.ErrReadingListNotFound
+	}
+	return list, nil
+}
+
+func (r *fakeReadingListRepository) GetPublicBySlug(slug string) (models.ReadingList, error) {
+	if r.err != nil {
+		return models.ReadingList{}, r.err
+	}
+
+	for _, list := range r.lists {
+		if list.Slug == slug && list.Public {
+			return list, nil
+		}
+	}
+	return models.ReadingList{}, databases.ErrReadingListNotFound
+}
+
+func (r *fakeReadingListRepository) Create(list *models.ReadingList) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, existing := range r.lists {
+		if list.Kind != models.ListCustom && existing.UserID == list.UserID && existing.Kind == list.Kind {
+			return databases.ErrListKindTaken
+		}
+	}
+
+	r.nextId++
+	list.ID = r.nextId
+	list.Slug = fmt.Sprint(list.ID)
+	list.Items = []models.ReadingListItem{}
+	list.BookCount = 0
+	r.lists[list.ID] = *list
+	return nil
+}
+
+func (r *fakeReadingListRepository) Update(id uint, changes models.ReadingListChanges) (models.ReadingList, error) {
+	list, err := r.GetByID(id)
+	if err != nil {
+		return list, err
+	}
+
+	if changes.Name != "" {
+		list.Name = changes.Name
+	}
+	if changes.Public != nil {
+		list.Public = *changes.Public
+	}
+	r.lists[id] = list
+	return list, nil
+}
+
+func (r *fakeReadingListRepository) Delete(id uint) error {
+	if _, err := r.GetByID(id); err != nil {
+		return err
+	}
+
+	delete(r.lists, id)
+	return nil
+}
+
+func (r *fakeReadingListRepository) AddBook(id uint, bookId uint) (models.ReadingList, error) {
+	list, err := r.GetByID(id)
+	if err != nil {
+		return list, err
+	}
+	if !r.books[bookId] {
+		return list, databases.ErrBookNotFound
+	}
+	for _, item := range list.Items {
+		if item.BookID == bookId {
+			return list, databases.ErrAlreadyListed
+		}
+	}
+
+	item := models.ReadingListItem{ReadingListID: id, BookID: bookId, Position: len(list.Items) + 1, CreatedAt: time.Now()}
+	list.Items = append(list.Items, item)
+	list.BookCount = len(list.Items)
+	r.lists[id] = list
+	return list, nil
+}
+
+func (r *fakeReadingListRepository) RemoveBook(id uint, bookId uint) (models.ReadingList, error) {
+	list, err := r.GetByID(id)
+	if err != nil {
+		return list, err
+	}
+
+	items := []models.ReadingListItem{}
+	for _, item := range list.Items {
+		if item.BookID != bookId {
+			item.Position = len(items) + 1
+			items = append(items, item)
+		}
+	}
+	if len(items) == len(list.Items) {
+		return list, databases.ErrListBookNotFound
+	}
+	list.Items = items
+	list.BookCount = len(items)
+	r.lists[id] = list
+	return list, nil
+}
+
+func (r *fakeReadingListRepository) Reorder(id uint, bookIds []uint) (models.ReadingList, error) {
+	list, err := r.GetByID(id)
+	if err != nil {
+		return list, err
+	}
+
+	items := map[uint]models.ReadingListItem{}
+	for _, item := range list.Items {
+		items[item.BookID] = item
+	}
+	reordered := []models.ReadingListItem{}
+	for _, bookId := range bookIds {
+		item, ok := items[bookId]
+		if !ok {
+			return list, databases.ErrListOrderMismatch
+		}
+		delete(items, bookId)
+		item.Position = len(reordered) + 1
+		reordered = append(reordered, item)
+	}
+	if len(items) != 0 {
+		return list, databases.ErrListOrderMismatch
+	}
+	list.Items = reordered
+	r.lists[id] = list
+	return list, nil
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/middlewares"
+	"cleancode/models"
+	"cleancode/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FineController struct {
+	Fines databases.FineRepository
+}
+
+func NewFineController(fines databases.FineRepository) *FineController {
+	return &FineController{Fines: fines}
+}
+
+// GetUserFines lists a user's fine ledger. Members see their own; staff
+// may see anyone's.
+func (h *FineController) GetUserFines(c echo.Context) error {
+	userId, err := parseOwnOrStaffID(c)
+	if err != nil {
+		return err
+	}
+
+	query := models.FineListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	filter := databases.FineFilter{Kind: query.Kind}
+	entries, page, err := h.Fines.List(userId, filter, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputFineEntries(entries), page))
+}
+
+// GetUserFineBalance reports what a user owes. Members see their own;
+// staff may see anyone's.
+func (h *FineController) GetUserFineBalance(c echo.Context) error {
+	userId, err := parseOwnOrStaffID(c)
+	if err != nil {
+		return err
+	}
+
+	balance, err := h.Fines.Balance(userId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", balance.Output()))
+}
+
+// RecordFine adds a charge, payment or waiver to a user's ledger, recorded
+// as entered by the logged in librarian.
+func (h *FineController) RecordFine(c echo.Context) error {
+	userId, err := parseID(c, "invalid user id")
+	if err != nil {
+		return err
+	}
+
+	input := models.CreateFineEntryInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	entry := input.FineEntry()
+	entry.UserID = userId
+	librarianId := uint(middlewares.ExtractToken(c))
+	entry.RecordedBy = &librarianId
+
+	if err := h.Fines.Record(&entry); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", entry.Output()))
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"cleancode/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFineControllerWithFakeRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		id           string
+		userId       int
+		role         string
+		handler      func(h *FineController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"get own fines", http.MethodGet, "/", "", "1", 1, models.RoleMember, func(h *FineController) echo.HandlerFunc { return h.GetUserFines }, http.StatusOK, "success"},
+		{"get fines by kind", http.MethodGet, "/?kind=charge", "", "1", 1, models.RoleMember, func(h *FineController) echo.HandlerFunc { return h.GetUserFines }, http.StatusOK, "success"},
+		{"get fines by unknown kind", http.MethodGet, "/?kind=refund", "", "1", 1, models.RoleMember, func(h *FineController) echo.HandlerFunc { return h.GetUserFines }, http.StatusUnprocessableEntity, "validation failed"},
+		{"get another member's fines", http.MethodGet, "/", "", "1", 2, models.RoleMember, func(h *FineController) echo.HandlerFunc { return h.GetUserFines }, http.StatusForbidden, "forbidden"},
+		{"librarian gets fines", http.MethodGet, "/", "", "1", 3, models.RoleLibrarian, func(h *FineController) echo.HandlerFunc { return h.GetUserFines }, http.StatusOK, "success"},
+		{"get own balance", http.MethodGet, "/", "", "1", 1, models.RoleMember, func(h *FineController) echo.HandlerFunc { return h.GetUserFineBalance }, http.StatusOK, "success"},
+		{"get another member's balance", http.MethodGet, "/", "", "1", 2, models.RoleMember, func(h *FineController) echo.HandlerFunc { return h.GetUserFineBalance }, http.StatusForbidden, "forbidden"},
+		{"get balance of unknown user", http.MethodGet, "/", "", "9", 3, models.RoleLibrarian, func(h *FineController) echo.HandlerFunc { return h.GetUserFineBalance }, http.StatusNotFound, "user not found"},
+		{"record payment", http.MethodPost, "/", `{"kind":"payment","amount":"1.50"}`, "1", 3, models.RoleLibrarian, func(h *FineController) echo.HandlerFunc { return h.RecordFine }, http.StatusCreated, "success"},
+		{"record overpayment", http.MethodPost, "/", `{"kind":"payment","amount":"2.01"}`, "1", 3, models.RoleLibrarian, func(h *FineController) echo.HandlerFunc { return h.RecordFine }, http.StatusConflict, "amount exceeds the outstanding balance"},
+		{"record fractional cents", http.MethodPost, "/", `{"kind":"charge","amount":"0.005"}`, "1", 3, models.RoleLibrarian, func(h *FineController) echo.HandlerFunc { return h.RecordFine }, http.StatusUnprocessableEntity, "validation failed"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fines := newFakeFineRepository(1, 2, 3)
+			if err := fines.Record(&models.FineEntry{UserID: 1, Kind: models.FineCharge, Amount: decimal.RequireFromString("2")}); err != nil {
+				t.Fatal(err)
+			}
+			h := NewFineController(fines)
+
+			rec, message := serveWithFake(t, testCase.handler(h), testCase.method, testCase.target, testCase.body, testCase.userId, testCase.role, "id", testCase.id)
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, message)
+		})
+	}
+}
+
+func TestRecordFineRecordsLibrarian(t *testing.T) {
+	fines := newFakeFineRepository(1)
+
+	body := `{"kind":"charge","amount":"12.5","note":"water damage"}`
+	rec, _ := serveWithFake(t, NewFineController(fines).RecordFine, http.MethodPost, "/", body, 3, models.RoleLibrarian, "id", "1")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Kind":"charge","Amount":"12.50","Note":"water damage","LoanID":null,"RecordedBy":3`)
+}
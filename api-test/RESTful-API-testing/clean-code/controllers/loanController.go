@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/middlewares"
+	"cleancode/models"
+	"cleancode/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type LoanController struct {
+	Loans databases.LoanRepository
+}
+
+func NewLoanController(loans databases.LoanRepository) *LoanController {
+	return &LoanController{Loans: loans}
+}
+
+// GetMyLoans lists the loans the logged in user has not returned yet.
+func (h *LoanController) GetMyLoans(c echo.Context) error {
+	query := models.LoanListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	userId := uint(middlewares.ExtractToken(c))
+	loans, page, err := h.Loans.ListActive(userId, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputLoans(loans), page))
+}
+
+// CheckoutBook lends the book to the logged in user.
+func (h *LoanController) CheckoutBook(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
+	}
+
+	userId := uint(middlewares.ExtractToken(c))
+	loan, err := h.Loans.Checkout(userId, bookId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", loan.Output()))
+}
+
+// ReturnLoan takes a book back. Members return their own loans; staff may
+// return anyone's.
+func (h *LoanController) ReturnLoan(c echo.Context) error {
+	loanId, err := parseID(c, "invalid loan id")
+	if err != nil {
+		return err
+	}
+
+	loan, err := h.Loans.GetByID(loanId)
+	if err != nil {
+		return err
+	}
+	if loan.UserID != uint(middlewares.ExtractToken(c)) && !isStaff(c) {
+		return errForbidden
+	}
+
+	loan, err = h.Loans.Return(loanId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", loan.Output()))
+}
+
+// isStaff reports whether the logged in user runs the library.
+func isStaff(c echo.Context) bool {
+	role := middlewares.ExtractRole(c)
+	return role == models.RoleAdmin || role == models.RoleLibrarian
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"cleancode/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoanControllerWithFakeRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		id           string
+		userId       int
+		role         string
+		handler      func(h *LoanController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"get my loans", http.MethodGet, "", 1, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.GetMyLoans }, http.StatusOK, "success"},
+		{"checkout book", http.MethodPost, "1", 2, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.CheckoutBook }, http.StatusCreated, "success"},
+		{"checkout unavailable book", http.MethodPost, "2", 2, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.CheckoutBook }, http.StatusConflict, "no copy of this book is available"},
+		{"checkout missing book", http.MethodPost, "9", 2, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.CheckoutBook }, http.StatusNotFound, "book not found"},
+		{"checkout invalid id", http.MethodPost, "abc", 2, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.CheckoutBook }, http.StatusBadRequest, "invalid book id"},
+		{"return own loan", http.MethodPost, "1", 1, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.ReturnLoan }, http.StatusOK, "success"},
+		{"return another member's loan", http.MethodPost, "1", 2, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.ReturnLoan }, http.StatusForbidden, "forbidden"},
+		{"librarian returns a loan", http.MethodPost, "1", 3, models.RoleLibrarian, func(h *LoanController) echo.HandlerFunc { return h.ReturnLoan }, http.StatusOK, "success"},
+		{"return missing loan", http.MethodPost, "9", 1, models.RoleMember, func(h *LoanController) echo.HandlerFunc { return h.ReturnLoan }, http.StatusNotFound, "loan not found"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			loans := newFakeLoanRepository(map[uint]int{1: 2, 2: 1})
+			if _, err := loans.Checkout(1, 2); err != nil {
+				t.Fatal(err)
+			}
+			h := NewLoanController(loans)
+
+			rec, message := serveWithFake(t, testCase.handler(h), testCase.method, "/jwt/loans", "", testCase.userId, testCase.role, "id", testCase.id)
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, message)
+		})
+	}
+}
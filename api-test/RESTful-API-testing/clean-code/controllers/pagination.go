@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/models"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+func listOptions(query models.ListQuery, sort string) databases.ListOptions {
+	return databases.ListOptions{
+		Limit:  query.Limit,
+		Page:   query.Page,
+		Cursor: query.Cursor,
+		Sort:   sort,
+	}
+}
+
+// setLinkHeader adds an RFC 8288 Link header pointing at the neighbouring
+// pages. Numbered pages link first, prev, next and last; cursor pages only
+// link next. The links keep the rest of the request's query string.
+func setLinkHeader(c echo.Context, page databases.Page) {
+	links := []string{}
+	link := func(rel string, set func(query url.Values)) {
+		target := *c.Request().URL
+		query := target.Query()
+		query.Del("page")
+		query.Del("cursor")
+		set(query)
+		target.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, target.RequestURI(), rel))
+	}
+	numbered := func(n int) func(query url.Values) {
+		return func(query url.Values) { query.Set("page", strconv.Itoa(n)) }
+	}
+
+	if page.Page > 0 {
+		last := int((page.Total + int64(page.Limit) - 1) / int64(page.Limit))
+		if last < 1 {
+			last = 1
+		}
+
+		link("first", numbered(1))
+		if page.Page > 1 {
+			link("prev", numbered(page.Page-1))
+		}
+		if page.HasMore {
+			link("next", numbered(page.Page+1))
+		}
+		link("last", numbered(last))
+	} else if page.HasMore {
+		link("next", func(query url.Values) { query.Set("cursor", page.NextCursor) })
+	}
+
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+}
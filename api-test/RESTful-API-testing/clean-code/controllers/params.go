@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"cleancode/middlewares"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// parseID reads the ":id" path parameter, answering 400 with message when it
+// is not a positive integer.
+func parseID(c echo.Context, message string) (uint, error) {
+	return parseParam(c, "id", message)
+}
+
+// parseParam reads the path parameter name like parseID.
+func parseParam(c echo.Context, name string, message string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, message)
+	}
+	return uint(id), nil
+}
+
+// parseOwnOrStaffID reads the user id of /users/:id routes that members may
+// only use for themselves.
+func parseOwnOrStaffID(c echo.Context) (uint, error) {
+	userId, err := parseID(c, "invalid user id")
+	if err != nil {
+		return 0, err
+	}
+	if userId != uint(middlewares.ExtractToken(c)) && !isStaff(c) {
+		return 0, errForbidden
+	}
+	return userId, nil
+}
+
+// bindAndValidate binds the request body into input and checks its validate
+// tags, so an invalid request is answered with 422 and the failing fields.
+func bindAndValidate(c echo.Context, input interface{}) error {
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+	return c.Validate(input)
+}
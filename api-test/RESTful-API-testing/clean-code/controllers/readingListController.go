@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/middlewares"
+	"cleancode/models"
+	"cleancode/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReadingListController struct {
+	Lists databases.ReadingListRepository
+}
+
+func NewReadingListController(lists databases.ReadingListRepository) *ReadingListController {
+	return &ReadingListController{Lists: lists}
+}
+
+// GetMyLists lists the reading lists of the logged in user.
+func (h *ReadingListController) GetMyLists(c echo.Context) error {
+	query := models.ReadingListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	userId := uint(middlewares.ExtractToken(c))
+	filter := databases.ReadingListFilter{Kind: query.Kind}
+	lists, page, err := h.Lists.ListByUser(userId, filter, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputReadingLists(lists), page))
+}
+
+// GetList shows a reading list with its books. Private lists are only
+// shown to their owner.
+func (h *ReadingListController) GetList(c echo.Context) error {
+	listId, err := parseID(c, "invalid list id")
+	if err != nil {
+		return err
+	}
+
+	list, err := h.Lists.GetByID(listId)
+	if err != nil {
+		return err
+	}
+	if !list.Public && list.UserID != uint(middlewares.ExtractToken(c)) {
+		return errForbidden
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", list.Output()))
+}
+
+// GetPublicList shows a public reading list by the slug it is shared with.
+func (h *ReadingListController) GetPublicList(c echo.Context) error {
+	list, err := h.Lists.GetPublicBySlug(c.Param("slug"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", list.Output()))
+}
+
+// CreateList creates a reading list for the logged in user.
+func (h *ReadingListController) CreateList(c echo.Context) error {
+	input := models.CreateReadingListInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	list := input.ReadingList()
+	list.UserID = uint(middlewares.ExtractToken(c))
+
+	if err := h.Lists.Create(&list); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", list.Output()))
+}
+
+func (h *ReadingListController) UpdateList(c echo.Context) error {
+	listId, err := h.parseOwnListID(c)
+	if err != nil {
+		return err
+	}
+
+	input := models.UpdateReadingListInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	list, err := h.Lists.Update(listId, input.ReadingListChanges())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", list.Output()))
+}
+
+func (h *ReadingListController) DeleteList(c echo.Context) error {
+	listId, err := h.parseOwnListID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Lists.Delete(listId); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", "deleted"))
+}
+
+func (h *ReadingListController) AddListBook(c echo.Context) error {
+	listId, err := h.parseOwnListID(c)
+	if err != nil {
+		return err
+	}
+
+	input := models.AddListBookInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	list, err := h.Lists.AddBook(listId, input.BookID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", list.Output()))
+}
+
+func (h *ReadingListController) RemoveListBook(c echo.Context) error {
+	listId, err := h.parseOwnListID(c)
+	if err != nil {
+		return err
+	}
+	bookId, err := parseParam(c, "bookId", "invalid book id")
+	if err != nil {
+		return err
+	}
+
+	list, err := h.Lists.RemoveBook(listId, bookId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", list.Output()))
+}
+
+func (h *ReadingListController) ReorderList(c echo.Context) error {
+	listId, err := h.parseOwnListID(c)
+	if err != nil {
+		return err
+	}
+
+	input := models.ReorderListInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	list, err := h.Lists.Reorder(listId, input.BookIDs)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", list.Output()))
+}
+
+// parseOwnListID parses the list id of the path and returns errForbidden
+// unless the logged in user owns that list.
+func (h *ReadingListController) parseOwnListID(c echo.Context) (uint, error) {
+	listId, err := parseID(c, "invalid list id")
+	if err != nil {
+		return 0, err
+	}
+
+	list, err := h.Lists.GetByID(listId)
+	if err != nil {
+		return 0, err
+	}
+	if list.UserID != uint(middlewares.ExtractToken(c)) {
+		return 0, errForbidden
+	}
+
+	return listId, nil
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"cleancode/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadingListControllerWithFakeRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		params       []string
+		userId       int
+		handler      func(h *ReadingListController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"get my lists", http.MethodGet, "/", "", nil, 1, func(h *ReadingListController) echo.HandlerFunc { return h.GetMyLists }, http.StatusOK, "success"},
+		{"get my lists by unknown kind", http.MethodGet, "/?kind=wishlist", "", nil, 1, func(h *ReadingListController) echo.HandlerFunc { return h.GetMyLists }, http.StatusUnprocessableEntity, "validation failed"},
+		{"get own private list", http.MethodGet, "/", "", []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.GetList }, http.StatusOK, "success"},
+		{"get another member's private list", http.MethodGet, "/", "", []string{"id", "1"}, 2, func(h *ReadingListController) echo.HandlerFunc { return h.GetList }, http.StatusForbidden, "forbidden"},
+		{"get another member's public list", http.MethodGet, "/", "", []string{"id", "2"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.GetList }, http.StatusOK, "success"},
+		{"get missing list", http.MethodGet, "/", "", []string{"id", "9"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.GetList }, http.StatusNotFound, "reading list not found"},
+		{"create list", http.MethodPost, "/", `{"kind":"want-to-read"}`, nil, 1, func(h *ReadingListController) echo.HandlerFunc { return h.CreateList }, http.StatusCreated, "success"},
+		{"create second list of a kind", http.MethodPost, "/", `{"kind":"reading"}`, nil, 2, func(h *ReadingListController) echo.HandlerFunc { return h.CreateList }, http.StatusConflict, "a list of this kind already exists"},
+		{"create custom list without name", http.MethodPost, "/", `{"kind":"custom"}`, nil, 1, func(h *ReadingListController) echo.HandlerFunc { return h.CreateList }, http.StatusUnprocessableEntity, "validation failed"},
+		{"create list of unknown kind", http.MethodPost, "/", `{"name":"wishes","kind":"wishlist"}`, nil, 1, func(h *ReadingListController) echo.HandlerFunc { return h.CreateList }, http.StatusUnprocessableEntity, "validation failed"},
+		{"publish own list", http.MethodPut, "/", `{"public":true}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.UpdateList }, http.StatusOK, "success"},
+		{"rename another member's list", http.MethodPut, "/", `{"name":"mine"}`, []string{"id", "2"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.UpdateList }, http.StatusForbidden, "forbidden"},
+		{"delete own list", http.MethodDelete, "/", "", []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.DeleteList }, http.StatusOK, "success"},
+		{"delete another member's list", http.MethodDelete, "/", "", []string{"id", "1"}, 2, func(h *ReadingListController) echo.HandlerFunc { return h.DeleteList }, http.StatusForbidden, "forbidden"},
+		{"add book", http.MethodPost, "/", `{"bookId":2}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.AddListBook }, http.StatusOK, "success"},
+		{"add listed book", http.MethodPost, "/", `{"bookId":1}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.AddListBook }, http.StatusConflict, "book is already on this list"},
+		{"add missing book", http.MethodPost, "/", `{"bookId":9}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.AddListBook }, http.StatusNotFound, "book not found"},
+		{"add book without id", http.MethodPost, "/", `{}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.AddListBook }, http.StatusUnprocessableEntity, "validation failed"},
+		{"add book to another member's list", http.MethodPost, "/", `{"bookId":2}`, []string{"id", "2"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.AddListBook }, http.StatusForbidden, "forbidden"},
+		{"remove book", http.MethodDelete, "/", "", []string{"id", "1", "bookId", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.RemoveListBook }, http.StatusOK, "success"},
+		{"remove unlisted book", http.MethodDelete, "/", "", []string{"id", "1", "bookId", "2"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.RemoveListBook }, http.StatusNotFound, "book on this list not found"},
+		{"remove book invalid id", http.MethodDelete, "/", "", []string{"id", "1", "bookId", "abc"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.RemoveListBook }, http.StatusBadRequest, "invalid book id"},
+		{"reorder list", http.MethodPut, "/", `{"bookIds":[1]}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.ReorderList }, http.StatusOK, "success"},
+		{"reorder list with other books", http.MethodPut, "/", `{"bookIds":[2]}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.ReorderList }, http.StatusConflict, "book ids must name every book on the list once"},
+		{"reorder list without books", http.MethodPut, "/", `{}`, []string{"id", "1"}, 1, func(h *ReadingListController) echo.HandlerFunc { return h.ReorderList }, http.StatusUnprocessableEntity, "validation failed"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			h := newTestReadingListController(t)
+
+			rec, message := serveWithFake(t, testCase.handler(h), testCase.method, testCase.target, testCase.body, testCase.userId, models.RoleMember, testCase.params...)
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, message)
+		})
+	}
+}
+
+func TestGetPublicList(t *testing.T) {
+	for _, testCase := range []struct {
+		slug         string
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"2", http.StatusOK, "success"},
+		{"1", http.StatusNotFound, "reading list not found"},
+		{"unknown", http.StatusNotFound, "reading list not found"},
+	} {
+		h := newTestReadingListController(t)
+
+		rec, message := serveWithFake(t, h.GetPublicList, http.MethodGet, "/", "", 0, "", "slug", testCase.slug)
+		assert.Equal(t, testCase.expectedCode, rec.Code, testCase.slug)
+		assert.Equal(t, testCase.expectedMsg, message, testCase.slug)
+	}
+}
+
+// newTestReadingListController serves a private custom list of user 1
+// holding book 1 and a public reading list of user 2, with books 1 and 2 in
+// the catalogue.
+func newTestReadingListController(t *testing.T) *ReadingListController {
+	t.Helper()
+
+	lists := newFakeReadingListRepository(1, 2)
+	for _, list := range []models.ReadingList{
+		{UserID: 1, Name: "Summer", Kind: models.ListCustom},
+		{UserID: 2, Name: "Reading", Kind: models.ListReading, Public: true},
+	} {
+		if err := lists.Create(&list); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := lists.AddBook(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	return NewReadingListController(lists)
+}
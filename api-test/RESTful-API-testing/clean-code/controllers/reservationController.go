@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/middlewares"
+	"cleancode/models"
+	"cleancode/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReservationController struct {
+	Reservations databases.ReservationRepository
+}
+
+func NewReservationController(reservations databases.ReservationRepository) *ReservationController {
+	return &ReservationController{Reservations: reservations}
+}
+
+// ReserveBook queues the logged in user for the book.
+func (h *ReservationController) ReserveBook(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
+	}
+
+	userId := uint(middlewares.ExtractToken(c))
+	reservation, err := h.Reservations.Reserve(userId, bookId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", reservation.Output()))
+}
+
+// GetUserReservations lists a user's reservations. Members see their own;
+// staff may see anyone's.
+func (h *ReservationController) GetUserReservations(c echo.Context) error {
+	userId, err := parseOwnOrStaffID(c)
+	if err != nil {
+		return err
+	}
+
+	query := models.ReservationListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	filter := databases.ReservationFilter{Status: query.Status}
+	reservations, page, err := h.Reservations.ListByUser(userId, filter, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputReservations(reservations), page))
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"cleancode/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservationControllerWithFakeRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		target       string
+		id           string
+		userId       int
+		role         string
+		handler      func(h *ReservationController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"reserve book", http.MethodPost, "/", "1", 2, models.RoleMember, func(h *ReservationController) echo.HandlerFunc { return h.ReserveBook }, http.StatusCreated, "success"},
+		{"reserve book twice", http.MethodPost, "/", "1", 1, models.RoleMember, func(h *ReservationController) echo.HandlerFunc { return h.ReserveBook }, http.StatusConflict, "book is already reserved by this user"},
+		{"reserve available book", http.MethodPost, "/", "2", 1, models.RoleMember, func(h *ReservationController) echo.HandlerFunc { return h.ReserveBook }, http.StatusConflict, "a copy of this book is available to borrow"},
+		{"reserve invalid id", http.MethodPost, "/", "abc", 1, models.RoleMember, func(h *ReservationController) echo.HandlerFunc { return h.ReserveBook }, http.StatusBadRequest, "invalid book id"},
+		{"get own reservations", http.MethodGet, "/", "1", 1, models.RoleMember, func(h *ReservationController) echo.HandlerFunc { return h.GetUserReservations }, http.StatusOK, "success"},
+		{"get another member's reservations", http.MethodGet, "/", "1", 2, models.RoleMember, func(h *ReservationController) echo.HandlerFunc { return h.GetUserReservations }, http.StatusForbidden, "forbidden"},
+		{"librarian gets reservations", http.MethodGet, "/", "1", 3, models.RoleLibrarian, func(h *ReservationController) echo.HandlerFunc { return h.GetUserReservations }, http.StatusOK, "success"},
+		{"get reservations by unknown status", http.MethodGet, "/?status=lost", "1", 1, models.RoleMember, func(h *ReservationController) echo.HandlerFunc { return h.GetUserReservations }, http.StatusUnprocessableEntity, "validation failed"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reservations := newFakeReservationRepository(1)
+			if _, err := reservations.Reserve(1, 1); err != nil {
+				t.Fatal(err)
+			}
+			h := NewReservationController(reservations)
+
+			rec, message := serveWithFake(t, testCase.handler(h), testCase.method, testCase.target, "", testCase.userId, testCase.role, "id", testCase.id)
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, message)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"cleancode/lib/databases"
+	"cleancode/middlewares"
+	"cleancode/models"
+	"cleancode/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReviewController struct {
+	Reviews databases.ReviewRepository
+}
+
+func NewReviewController(reviews databases.ReviewRepository) *ReviewController {
+	return &ReviewController{Reviews: reviews}
+}
+
+func (h *ReviewController) GetBookReviews(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
+	}
+
+	query := models.ReviewListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	reviews, page, err := h.Reviews.ListByBook(bookId, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
+	}
+
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputReviews(reviews), page))
+}
+
+// CreateReview reviews the book as the logged in user.
+func (h *ReviewController) CreateReview(c echo.Context) error {
+	bookId, err := parseID(c, "invalid book id")
+	if err != nil {
+		return err
+	}
+
+	input := models.CreateReviewInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	review := input.Review()
+	review.BookID = bookId
+	review.UserID = uint(middlewares.ExtractToken(c))
+
+	if err := h.Reviews.Create(&review); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", review.Output()))
+}
+
+// UpdateReview edits a review. Only its author may.
+func (h *ReviewController) UpdateReview(c echo.Context) error {
+	reviewId, err := h.parseOwnReviewID(c)
+	if err != nil {
+		return err
+	}
+
+	input := models.UpdateReviewInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
+
+	review, err := h.Reviews.Update(reviewId, input.Review())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", review.Output()))
+}
+
+// DeleteReview removes a review. Only its author may.
+func (h *ReviewController) DeleteReview(c echo.Context) error {
+	reviewId, err := h.parseOwnReviewID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Reviews.Delete(reviewId); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", "deleted"))
+}
+
+// parseOwnReviewID parses the review id of the path and returns
+// errForbidden unless the logged in user wrote that review.
+func (h *ReviewController) parseOwnReviewID(c echo.Context) (uint, error) {
+	reviewId, err := parseID(c, "invalid review id")
+	if err != nil {
+		return 0, err
+	}
+
+	review, err := h.Reviews.GetByID(reviewId)
+	if err != nil {
+		return 0, err
+	}
+	if review.UserID != uint(middlewares.ExtractToken(c)) {
+		return 0, errForbidden
+	}
+
+	return reviewId, nil
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"cleancode/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewControllerWithFakeRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		id           string
+		userId       int
+		role         string
+		handler      func(h *ReviewController) echo.HandlerFunc
+		expectedCode int
+		expectedMsg  string
+	}{
+		{"get reviews", http.MethodGet, "/", "", "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.GetBookReviews }, http.StatusOK, "success"},
+		{"get reviews by rating", http.MethodGet, "/?sort=-rating", "", "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.GetBookReviews }, http.StatusOK, "success"},
+		{"get reviews by unknown sort", http.MethodGet, "/?sort=text", "", "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.GetBookReviews }, http.StatusUnprocessableEntity, "validation failed"},
+		{"get reviews of missing book", http.MethodGet, "/", "", "9", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.GetBookReviews }, http.StatusNotFound, "book not found"},
+		{"create review", http.MethodPost, "/", `{"rating":4,"text":"good read"}`, "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.CreateReview }, http.StatusCreated, "success"},
+		{"create second review", http.MethodPost, "/", `{"rating":4}`, "1", 1, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.CreateReview }, http.StatusConflict, "a review of this book by this user already exists"},
+		{"create review without rating", http.MethodPost, "/", `{"text":"good read"}`, "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.CreateReview }, http.StatusUnprocessableEntity, "validation failed"},
+		{"create review with six stars", http.MethodPost, "/", `{"rating":6}`, "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.CreateReview }, http.StatusUnprocessableEntity, "validation failed"},
+		{"create review of missing book", http.MethodPost, "/", `{"rating":4}`, "9", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.CreateReview }, http.StatusNotFound, "book not found"},
+		{"update own review", http.MethodPut, "/", `{"rating":3}`, "1", 1, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.UpdateReview }, http.StatusOK, "success"},
+		{"update another member's review", http.MethodPut, "/", `{"rating":1}`, "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.UpdateReview }, http.StatusForbidden, "forbidden"},
+		{"admin updates a review", http.MethodPut, "/", `{"rating":1}`, "1", 3, models.RoleAdmin, func(h *ReviewController) echo.HandlerFunc { return h.UpdateReview }, http.StatusForbidden, "forbidden"},
+		{"update review with negative stars", http.MethodPut, "/", `{"rating":-1}`, "1", 1, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.UpdateReview }, http.StatusUnprocessableEntity, "validation failed"},
+		{"update missing review", http.MethodPut, "/", `{"rating":3}`, "9", 1, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.UpdateReview }, http.StatusNotFound, "review not found"},
+		{"delete own review", http.MethodDelete, "/", "", "1", 1, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.DeleteReview }, http.StatusOK, "success"},
+		{"delete another member's review", http.MethodDelete, "/", "", "1", 2, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.DeleteReview }, http.StatusForbidden, "forbidden"},
+		{"delete review invalid id", http.MethodDelete, "/", "", "abc", 1, models.RoleMember, func(h *ReviewController) echo.HandlerFunc { return h.DeleteReview }, http.StatusBadRequest, "invalid review id"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reviews := newFakeReviewRepository(1)
+			if err := reviews.Create(&models.Review{UserID: 1, BookID: 1, Rating: 5}); err != nil {
+				t.Fatal(err)
+			}
+			h := NewReviewController(reviews)
+
+			rec, message := serveWithFake(t, testCase.handler(h), testCase.method, testCase.target, testCase.body, testCase.userId, testCase.role, "id", testCase.id)
+			assert.Equal(t, testCase.expectedCode, rec.Code)
+			assert.Equal(t, testCase.expectedMsg, message)
+		})
+	}
+}
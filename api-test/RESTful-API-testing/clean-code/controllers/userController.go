@@ -2,132 +2,200 @@ package controllers
 
 import (
 	"cleancode/lib/databases"
+	"cleancode/lib/passwords"
 	"cleancode/middlewares"
 	"cleancode/models"
 	"cleancode/response"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
 
-func GetAllUsersController(c echo.Context) error {
-	users, rowAffected, err := databases.GetAllUsers()
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("failed"))
-	}
+var (
+	// errForbidden is returned when a user acts on another user's account.
+	errForbidden = echo.NewHTTPError(http.StatusForbidden, "forbidden")
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponse("failed"))
-	}
+	// errInvalidCredentials does not say whether the email or the password was
+	// wrong.
+	errInvalidCredentials = echo.NewHTTPError(http.StatusUnauthorized, "invalid email or password")
+)
+
+type UserController struct {
+	Users         databases.UserRepository
+	RefreshTokens databases.RefreshTokenRepository
+	Tokens        *middlewares.TokenManager
+}
 
-	return c.JSON(http.StatusOK, response.SuccessResponse("success", users))
+func NewUserController(users databases.UserRepository, refreshTokens databases.RefreshTokenRepository, tokens *middlewares.TokenManager) *UserController {
+	return &UserController{Users: users, RefreshTokens: refreshTokens, Tokens: tokens}
 }
 
-func GetSingleUserController(c echo.Context) error {
-	userId, errorId := strconv.Atoi(c.Param("id"))
-	if errorId != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("invalid user id"))
+func (h *UserController) GetAllUsers(c echo.Context) error {
+	query := models.UserListQuery{}
+	if err := bindAndValidate(c, &query); err != nil {
+		return err
 	}
 
-	loggedUserId := middlewares.ExtractToken(c)
-	if loggedUserId != userId {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("unauthorized"))
+	filter := databases.UserFilter{
+		Name:  query.Name,
+		Email: query.Email,
+		Role:  query.Role,
+	}
+	users, page, err := h.Users.List(filter, listOptions(query.ListQuery, query.Sort))
+	if err != nil {
+		return err
 	}
 
-	user, rowAffected, err := databases.GetSingleUser(userId)
+	setLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response.PageResponse("success", models.OutputUsers(users), page))
+}
+
+func (h *UserController) GetSingleUser(c echo.Context) error {
+	userId, err := h.ownUserID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("failed"))
+		return err
 	}
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponse("failed"))
+	user, err := h.Users.GetByID(userId)
+	if err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponse("success", user))
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", user.Output()))
 }
 
-func CreateUserControllers(c echo.Context) error {
-	var user models.User
-	c.Bind(&user)
+func (h *UserController) CreateUser(c echo.Context) error {
+	input := models.CreateUserInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
 
-	newUser, err := databases.CreateNewUser(&user)
+	hashed, err := passwords.Hash(input.Password)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("failed"))
+		return err
+	}
+
+	user := input.User()
+	user.Password = hashed
+
+	if err := h.Users.Create(&user); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponse("success", newUser))
+	return c.JSON(http.StatusCreated, response.SuccessResponse("success", user.Output()))
 }
 
-func DeleteUserController(c echo.Context) error {
-	userId, errorId := strconv.Atoi(c.Param("id"))
-	if errorId != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("invalid user id"))
+func (h *UserController) DeleteUser(c echo.Context) error {
+	userId, err := h.ownUserID(c)
+	if err != nil {
+		return err
 	}
 
-	loggedUserId := middlewares.ExtractToken(c)
-	if loggedUserId != userId {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("unauthorized"))
+	if err := h.Users.Delete(userId); err != nil {
+		return err
 	}
 
-	message, rowAffected, err := databases.DeleteUser(userId)
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", "deleted"))
+}
+
+func (h *UserController) UpdateUser(c echo.Context) error {
+	userId, err := h.ownUserID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("failed"))
+		return err
 	}
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponse("failed"))
+	input := models.UpdateUserInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponse("success", message))
-}
+	newUser := input.User()
 
-func UpdateUserController(c echo.Context) error {
-	userId, errorId := strconv.Atoi(c.Param("id"))
-	if errorId != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("invalid user id"))
+	if input.Password != "" {
+		newUser.Password, err = passwords.Hash(input.Password)
+		if err != nil {
+			return err
+		}
 	}
 
-	loggedUserId := middlewares.ExtractToken(c)
-	if loggedUserId != userId {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("unauthorized"))
+	updatedUser, err := h.Users.Update(userId, newUser)
+	if err != nil {
+		return err
 	}
 
-	newUser := models.User{}
-	c.Bind(&newUser)
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", updatedUser.Output()))
+}
 
-	updatedUser, rowAffected, err := databases.UpdateUser(userId, newUser)
+func (h *UserController) UpdateUserRole(c echo.Context) error {
+	userId, err := parseID(c, "invalid user id")
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("failed"))
+		return err
 	}
 
-	if rowAffected == 0 {
-		return c.JSON(http.StatusOK, response.ErrorResponse("failed"))
+	input := models.RoleInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponse("success", updatedUser))
+	updatedUser, err := h.Users.UpdateRole(userId, input.Role)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", updatedUser.Output()))
 }
 
-func LoginUserController(c echo.Context) error {
-	user := models.User{}
-	c.Bind(&user)
+func (h *UserController) Login(c echo.Context) error {
+	input := models.LoginInput{}
+	if err := bindAndValidate(c, &input); err != nil {
+		return err
+	}
 
-	loggedUser, err := databases.LoginUsers(&user)
+	user, err := h.Users.GetByEmail(input.Email)
+	if errors.Is(err, databases.ErrNotFound) {
+		passwords.CompareNothing(input.Password)
+		return errInvalidCredentials
+	}
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, response.ErrorResponse("failed"))
+		return err
 	}
 
-	return c.JSON(http.StatusOK, response.SuccessResponse("success", loggedUser))
-}
+	match, needsRehash := passwords.Compare(user.Password, input.Password)
+	if !match {
+		return errInvalidCredentials
+	}
 
-func GetUserDetailControllersTesting() echo.HandlerFunc {
-	return GetSingleUserController
-}
+	if needsRehash {
+		hashed, err := passwords.Hash(input.Password)
+		if err != nil {
+			return err
+		}
 
-func UpdatedDetailUserTesting() echo.HandlerFunc {
-	return UpdateUserController
+		if err := h.Users.UpdatePassword(user.ID, hashed); err != nil {
+			return err
+		}
+	}
+
+	pair, err := issueTokens(h.Tokens, h.RefreshTokens, user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse("success", response.NewLoginResponse(pair, user)))
 }
 
-func DeleteDetailUserTesting() echo.HandlerFunc {
-	return DeleteUserController
+// ownUserID returns the ":id" path parameter, refusing ids other than the
+// logged in user's own.
+func (h *UserController) ownUserID(c echo.Context) (uint, error) {
+	userId, err := parseID(c, "invalid user id")
+	if err != nil {
+		return 0, err
+	}
+
+	if uint(middlewares.ExtractToken(c)) != userId {
+		return 0, errForbidden
+	}
+
+	return userId, nil
 }
@@ -3,35 +3,95 @@ package controllers
 import (
 	"bytes"
 	"cleancode/config"
-	"cleancode/constants"
+	"cleancode/lib/databases"
+	"cleancode/lib/passwords"
+	"cleancode/lib/search"
+	"cleancode/lib/validation"
 	"cleancode/middlewares"
 	"cleancode/models"
+	"cleancode/response"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
-func InitEchoTestAPI() *echo.Echo {
-	config.InitDbTest()
+var jwtConfigTest = config.JWTConfig{
+	Secret:          "0123456789abcdef0123456789abcdef",
+	Issuer:          "cleancode-test",
+	Audience:        "cleancode-test",
+	AccessTokenTTL:  time.Hour * 1,
+	RefreshTokenTTL: time.Hour * 24,
+}
+
+func InitTokenManagerTest() *middlewares.TokenManager {
+	tokens, err := middlewares.NewTokenManager(jwtConfigTest)
+	if err != nil {
+		panic(err)
+	}
+	return tokens
+}
+
+// InitUserControllerTest, InitBookControllerTest and InitAuthControllerTest
+// build handlers on the GORM repositories over db. Books get a new, empty
+// search index.
+func InitUserControllerTest(db *gorm.DB) *UserController {
+	return NewUserController(databases.NewGormUserRepository(db), databases.NewGormRefreshTokenRepository(db), InitTokenManagerTest())
+}
+
+func InitBookControllerTest(db *gorm.DB) *BookController {
+	index, err := search.NewMemoryIndex()
+	if err != nil {
+		panic(err)
+	}
+	return NewBookController(databases.NewGormBookRepository(db, index))
+}
+
+func InitAuthControllerTest(db *gorm.DB) *AuthController {
+	return NewAuthController(databases.NewGormUserRepository(db), databases.NewGormRefreshTokenRepository(db), InitTokenManagerTest())
+}
+
+func InitEchoTestAPI() (*echo.Echo, *gorm.DB) {
+	db := config.InitDbTest()
 	e := echo.New()
-	return e
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = validation.New()
+	return e, db
+}
+
+// HandleTest runs handler the way the router does, passing a returned error to
+// the echo error handler so the response can be inspected on the recorder.
+func HandleTest(e *echo.Echo, c echo.Context, handler echo.HandlerFunc) error {
+	if err := handler(c); err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+	return nil
+}
+
+// UserRequest is the JSON body a client sends; models.User cannot be used
+// because it never encodes its password.
+type UserRequest struct {
+	Name     string `json:"name,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
 }
 
-func InsertDataUserForGetUsers() error {
+func InsertDataUserForGetUsers(db *gorm.DB) error {
 	user := models.User{
 		Name:     "Alta",
 		Password: "123",
 		Email:    "alta@gmail.com",
 	}
 
-	err := config.Db.Save(&user).Error
+	err := db.Save(&user).Error
 	if err != nil {
 		return err
 	}
@@ -49,8 +109,8 @@ func TestGetUserController(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
 	req := httptest.NewRequest(http.MethodGet, "/users", nil)
 	rec := httptest.NewRecorder()
@@ -61,7 +121,7 @@ func TestGetUserController(t *testing.T) {
 		Data    models.User
 	}
 
-	if assert.NoError(t, GetAllUsersController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).GetAllUsers)) {
 		body := rec.Body.String()
 
 		var user UserResponse
@@ -76,6 +136,46 @@ func TestGetUserController(t *testing.T) {
 
 }
 
+func TestGetUserControllerFilter(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
+	db.Save(&models.User{Name: "Budi", Password: "123", Email: "budi@gmail.com", Role: models.RoleLibrarian})
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedCode   int
+		expectedEmails []string
+	}{
+		{"by role", "role=librarian", http.StatusOK, []string{"budi@gmail.com"}},
+		{"by name", "name=ALT", http.StatusOK, []string{"alta@gmail.com"}},
+		{"sorted", "sort=-email", http.StatusOK, []string{"budi@gmail.com", "alta@gmail.com"}},
+		{"unknown role", "role=owner", http.StatusUnprocessableEntity, nil},
+		{"sort by password", "sort=password", http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/jwt/users?"+testCase.query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		HandleTest(e, c, InitUserControllerTest(db).GetAllUsers)
+
+		var result struct {
+			Data []models.OutputUser
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result), testCase.name)
+		assert.Equal(t, testCase.expectedCode, rec.Code, testCase.name)
+
+		var emails []string
+		for _, user := range result.Data {
+			emails = append(emails, user.Email)
+			assert.NotZero(t, user.ID, testCase.name)
+		}
+		assert.Equal(t, testCase.expectedEmails, emails, testCase.name)
+	}
+}
+
 func TestGetUserControllerNilFailed(t *testing.T) {
 	type Expected struct {
 		name         string
@@ -87,7 +187,7 @@ func TestGetUserControllerNilFailed(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPI()
+	e, db := InitEchoTestAPI()
 
 	req := httptest.NewRequest(http.MethodGet, "/users", nil)
 	rec := httptest.NewRecorder()
@@ -98,7 +198,7 @@ func TestGetUserControllerNilFailed(t *testing.T) {
 		Data    models.User `json:"data"`
 	}
 
-	if assert.NoError(t, GetAllUsersController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).GetAllUsers)) {
 		body := rec.Body.String()
 		var user UserResponse
 		err := json.Unmarshal([]byte(body), &user)
@@ -107,7 +207,7 @@ func TestGetUserControllerNilFailed(t *testing.T) {
 		}
 
 		assert.Equal(t, testCases.expectedCode, rec.Code)
-		assert.Equal(t, "failed", user.Message)
+		assert.Equal(t, "success", user.Message)
 	}
 
 }
@@ -120,11 +220,11 @@ func TestGetUserControllerFailed(t *testing.T) {
 
 	testCases := Expected{
 		name:         "failed get all users",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPI()
-	config.Db.Migrator().DropTable(&models.User{})
+	e, db := InitEchoTestAPI()
+	db.Migrator().DropTable(&models.User{})
 
 	req := httptest.NewRequest(http.MethodGet, "/users", nil)
 	rec := httptest.NewRecorder()
@@ -135,7 +235,7 @@ func TestGetUserControllerFailed(t *testing.T) {
 		Data    models.User
 	}
 
-	GetAllUsersController(c)
+	HandleTest(e, c, InitUserControllerTest(db).GetAllUsers)
 
 	body := rec.Body.String()
 	var user UserResponse
@@ -145,7 +245,7 @@ func TestGetUserControllerFailed(t *testing.T) {
 	}
 
 	assert.Equal(t, testCases.expectedCode, rec.Code)
-	assert.Equal(t, "failed", user.Message)
+	assert.Equal(t, "internal server error", user.Message)
 
 }
 
@@ -157,12 +257,12 @@ func TestCreateUserController(t *testing.T) {
 
 	testCases := Expected{
 		name:         "succes create new user",
-		expectedCode: http.StatusOK,
+		expectedCode: http.StatusCreated,
 	}
 
-	e := InitEchoTestAPI()
+	e, db := InitEchoTestAPI()
 
-	user := models.User{
+	user := UserRequest{
 		Name:     "urnik",
 		Email:    "urnik@gmail.com",
 		Password: "urnik123",
@@ -184,7 +284,7 @@ func TestCreateUserController(t *testing.T) {
 		Data    models.User
 	}
 
-	if assert.NoError(t, CreateUserControllers(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).CreateUser)) {
 		body := rec.Body.String()
 		var user UserResponse
 
@@ -197,6 +297,13 @@ func TestCreateUserController(t *testing.T) {
 		assert.Equal(t, "urnik", user.Data.Name)
 		assert.Equal(t, "urnik@gmail.com", user.Data.Email)
 		assert.Equal(t, "success", user.Message)
+
+		stored := models.User{}
+		if assert.NoError(t, db.Where("email = ?", "urnik@gmail.com").First(&stored).Error) {
+			assert.NotEqual(t, "urnik123", stored.Password)
+			match, _ := passwords.Compare(stored.Password, "urnik123")
+			assert.True(t, match)
+		}
 	}
 
 }
@@ -209,13 +316,15 @@ func TestCreateUserControllerFailed(t *testing.T) {
 
 	testCases := Expected{
 		name:         "failed create new user",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPI()
-	config.Db.Migrator().DropTable(&models.User{})
+	e, db := InitEchoTestAPI()
+	db.Migrator().DropTable(&models.User{})
 
-	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	body := `{"name":"urnik","email":"urnik@gmail.com","password":"urnik123"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 
 	c := e.NewContext(req, rec)
@@ -225,7 +334,7 @@ func TestCreateUserControllerFailed(t *testing.T) {
 		Data    models.User
 	}
 
-	if assert.NoError(t, CreateUserControllers(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).CreateUser)) {
 		body := rec.Body.String()
 		var user UserResponse
 
@@ -235,7 +344,7 @@ func TestCreateUserControllerFailed(t *testing.T) {
 		}
 
 		assert.Equal(t, testCases.expectedCode, rec.Code)
-		assert.Equal(t, "failed", user.Message)
+		assert.Equal(t, "internal server error", user.Message)
 	}
 
 }
@@ -260,15 +369,15 @@ func TestGetSingleUserController(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -282,7 +391,7 @@ func TestGetSingleUserController(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCaseSuccess.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(GetUserDetailControllersTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).GetSingleUser))
 
 	type UserResponse struct {
 		Message string
@@ -322,15 +431,15 @@ func TestGetSingleUserControllerInvalidId(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -344,7 +453,7 @@ func TestGetSingleUserControllerInvalidId(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(GetUserDetailControllersTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).GetSingleUser))
 
 	type UserResponse struct {
 		Message string
@@ -372,7 +481,7 @@ func TestGetSingleUserControllerUnauthorized(t *testing.T) {
 	testCase := Expected{
 		name:         "failed get single user",
 		id:           "22",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusForbidden,
 	}
 
 	dummyData := models.User{
@@ -382,15 +491,15 @@ func TestGetSingleUserControllerUnauthorized(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -404,7 +513,7 @@ func TestGetSingleUserControllerUnauthorized(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(GetUserDetailControllersTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).GetSingleUser))
 
 	type UserResponse struct {
 		Message string
@@ -419,7 +528,7 @@ func TestGetSingleUserControllerUnauthorized(t *testing.T) {
 		assert.Error(t, err1, "error")
 	}
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "unauthorized", users.Message)
+	assert.Equal(t, "forbidden", users.Message)
 
 }
 
@@ -443,15 +552,15 @@ func TestGetSingleUserControllerUnauthorized(t *testing.T) {
 
 // 	user := models.User{}
 
-// 	e := InitEchoTestAPI()
-// 	InsertDataUserForGetUsers()
+// 	e, db := InitEchoTestAPI()
+// 	InsertDataUserForGetUsers(db)
 
-// 	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+// 	result := db.Where("email = ?", dummyData.Email).First(&user)
 // 	if result.Error != nil {
 // 		t.Error(result.Error)
 // 	}
 
-// 	token, err := middlewares.CreateToken(int(user.ID))
+// 	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 // 	if err != nil {
 // 		t.Error(err)
 // 	}
@@ -465,7 +574,7 @@ func TestGetSingleUserControllerUnauthorized(t *testing.T) {
 // 	c.SetParamNames("id")
 // 	c.SetParamValues(testCase.id)
 
-// 	middleware.JWT([]byte(constants.SECRET_JWT))(GetUserDetailControllersTesting())(c)
+// 	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).GetSingleUser))
 
 // 	type UserResponse struct {
 // 		Message string
@@ -493,7 +602,7 @@ func TestGetSingleUserControllerFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "failed get single user",
 		id:           "1",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
 	dummyData := models.User{
@@ -503,20 +612,20 @@ func TestGetSingleUserControllerFailed(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		assert.Error(t, result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
 
-	config.Db.Migrator().DropTable(&models.User{})
+	db.Migrator().DropTable(&models.User{})
 
 	req := httptest.NewRequest(http.MethodGet, "/jwt/users/:id", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -532,7 +641,7 @@ func TestGetSingleUserControllerFailed(t *testing.T) {
 		Data    models.User
 	}
 
-	if assert.NoError(t, middleware.JWT([]byte(constants.SECRET_JWT))(GetUserDetailControllersTesting())(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).GetSingleUser))) {
 		body := rec.Body.String()
 		var users UserResponse
 
@@ -541,7 +650,7 @@ func TestGetSingleUserControllerFailed(t *testing.T) {
 			assert.Error(t, err1, "error")
 		}
 		assert.Equal(t, testCase.expectedCode, rec.Code)
-		assert.Equal(t, "failed", users.Message)
+		assert.Equal(t, "internal server error", users.Message)
 	}
 }
 
@@ -553,13 +662,15 @@ func TestLoginUserControllerFailed(t *testing.T) {
 
 	testCases := Expected{
 		name:         "failed to login",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
-	e := InitEchoTestAPI()
-	config.Db.Migrator().DropTable(&models.User{})
+	e, db := InitEchoTestAPI()
+	db.Migrator().DropTable(&models.User{})
 
-	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	body := `{"email":"alta@gmail.com","password":"123"}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 
 	c := e.NewContext(req, rec)
@@ -569,7 +680,7 @@ func TestLoginUserControllerFailed(t *testing.T) {
 		Data    models.User
 	}
 
-	if assert.NoError(t, LoginUserController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).Login)) {
 		body := rec.Body.String()
 		var user UserResponse
 
@@ -579,7 +690,7 @@ func TestLoginUserControllerFailed(t *testing.T) {
 		}
 
 		assert.Equal(t, testCases.expectedCode, rec.Code)
-		assert.Equal(t, "failed", user.Message)
+		assert.Equal(t, "internal server error", user.Message)
 	}
 
 }
@@ -595,10 +706,10 @@ func TestLoginUserController(t *testing.T) {
 		expectedCode: http.StatusOK,
 	}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	user := models.User{
+	user := UserRequest{
 		Email:    "alta@gmail.com",
 		Password: "123",
 	}
@@ -616,10 +727,10 @@ func TestLoginUserController(t *testing.T) {
 
 	type UserResponse struct {
 		Message string
-		Data    models.User
+		Data    response.LoginResponse
 	}
 
-	if assert.NoError(t, LoginUserController(c)) {
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).Login)) {
 		body := rec.Body.String()
 		var users UserResponse
 
@@ -629,11 +740,99 @@ func TestLoginUserController(t *testing.T) {
 		}
 
 		assert.Equal(t, testCases.expectedCode, rec.Code)
-		assert.Equal(t, user.Email, users.Data.Email)
-		assert.Equal(t, user.Password, users.Data.Password)
 		assert.Equal(t, "success", users.Message)
+		assert.NotEmpty(t, users.Data.AccessToken)
+		assert.NotEmpty(t, users.Data.RefreshToken)
+		assert.Equal(t, "Bearer", users.Data.TokenType)
+		assert.Equal(t, int64(3600), users.Data.ExpiresIn)
+		assert.Equal(t, user.Email, users.Data.User.Email)
+		assert.Equal(t, "Alta", users.Data.User.Name)
+		assert.NotContains(t, body, "password")
+		assert.NotContains(t, body, "Password")
+	}
+
+}
+
+func TestLoginUserControllerWrongPassword(t *testing.T) {
+	type Expected struct {
+		name         string
+		expectedCode int
+	}
+
+	testCases := Expected{
+		name:         "failed to login with wrong password",
+		expectedCode: http.StatusUnauthorized,
+	}
+
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
+
+	user := UserRequest{
+		Email:    "alta@gmail.com",
+		Password: "1234",
+	}
+
+	body, err := json.Marshal(user)
+	if err != nil {
+		t.Error(t, err, "error")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	type UserResponse struct {
+		Message string
 	}
 
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).Login)) {
+		body := rec.Body.String()
+		var users UserResponse
+
+		err := json.Unmarshal([]byte(body), &users)
+		if err != nil {
+			assert.Error(t, err, "error")
+		}
+
+		assert.Equal(t, testCases.expectedCode, rec.Code)
+		assert.Equal(t, "invalid email or password", users.Message)
+	}
+}
+
+func TestLoginUserControllerRehashesPlaintextPassword(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
+
+	user := UserRequest{
+		Email:    "alta@gmail.com",
+		Password: "123",
+	}
+
+	body, err := json.Marshal(user)
+	if err != nil {
+		t.Error(t, err, "error")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).Login)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		stored := models.User{}
+		if assert.NoError(t, db.Where("email = ?", user.Email).First(&stored).Error) {
+			assert.True(t, passwords.IsHashed(stored.Password))
+
+			match, needsRehash := passwords.Compare(stored.Password, user.Password)
+			assert.True(t, match)
+			assert.False(t, needsRehash)
+		}
+	}
 }
 
 func TestUpdatedUserControllerInvalidId(t *testing.T) {
@@ -656,15 +855,15 @@ func TestUpdatedUserControllerInvalidId(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -678,7 +877,7 @@ func TestUpdatedUserControllerInvalidId(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(UpdatedDetailUserTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).UpdateUser))
 
 	type UserResponse struct {
 		Message string
@@ -706,7 +905,7 @@ func TestUpdateUserControllerUnauthorized(t *testing.T) {
 	testCase := Expected{
 		name:         "invalid user id",
 		id:           "2",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusForbidden,
 	}
 
 	dummyData := models.User{
@@ -716,15 +915,15 @@ func TestUpdateUserControllerUnauthorized(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -738,7 +937,7 @@ func TestUpdateUserControllerUnauthorized(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(UpdatedDetailUserTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).UpdateUser))
 
 	type UserResponse struct {
 		Message string
@@ -752,7 +951,7 @@ func TestUpdateUserControllerUnauthorized(t *testing.T) {
 		assert.Error(t, err1, "error")
 	}
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "unauthorized", users.Message)
+	assert.Equal(t, "forbidden", users.Message)
 
 }
 
@@ -776,20 +975,20 @@ func TestUpdateUserControllerUnauthorized(t *testing.T) {
 
 // 	user := models.User{}
 
-// 	e := InitEchoTestAPI()
-// 	InsertDataUserForGetUsers()
+// 	e, db := InitEchoTestAPI()
+// 	InsertDataUserForGetUsers(db)
 
-// 	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+// 	result := db.Where("email = ?", dummyData.Email).First(&user)
 // 	if result.Error != nil {
 // 		t.Error(result.Error)
 // 	}
 
-// 	token, err := middlewares.CreateToken(int(user.ID))
+// 	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 // 	if err != nil {
 // 		t.Error(err)
 // 	}
 
-// 	config.Db.Migrator().DropTable(&models.User{})
+// 	db.Migrator().DropTable(&models.User{})
 // 	req := httptest.NewRequest(http.MethodPut, "/jwt/users/:id", nil)
 // 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 // 	req.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %v", token))
@@ -799,7 +998,7 @@ func TestUpdateUserControllerUnauthorized(t *testing.T) {
 // 	c.SetParamNames("id")
 // 	c.SetParamValues(testCase.id)
 
-// 	middleware.JWT([]byte(constants.SECRET_JWT))(UpdateBookTesting())(c)
+// 	HandleTest(e, c, InitTokenManagerTest().JWT()(InitBookControllerTest(db).UpdateBook))
 
 // 	type UserResponse struct {
 // 		Message string
@@ -836,23 +1035,23 @@ func TestUpdatedUserControllerSuccess(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
 
-	NewUser := models.User{
+	NewUser := UserRequest{
 		Name:     "urnik rokhiyah",
-		Email:    "urnik456",
-		Password: "669",
+		Email:    "urnik456@gmail.com",
+		Password: "669secret",
 	}
 
 	body, err1 := json.Marshal(NewUser)
@@ -869,7 +1068,7 @@ func TestUpdatedUserControllerSuccess(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(UpdateBookTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).UpdateUser))
 
 	type UserResponse struct {
 		Message string
@@ -888,6 +1087,13 @@ func TestUpdatedUserControllerSuccess(t *testing.T) {
 	assert.Equal(t, testCase.expectedCode, rec.Code)
 	assert.Equal(t, NewUser.Name, users.Data.Name)
 	assert.Equal(t, "success", users.Message)
+
+	stored := models.User{}
+	if assert.NoError(t, db.First(&stored, user.ID).Error) {
+		match, _ := passwords.Compare(stored.Password, NewUser.Password)
+		assert.True(t, match)
+		assert.NotEqual(t, NewUser.Password, stored.Password)
+	}
 }
 
 func TestDeleteUserControllerInvalidId(t *testing.T) {
@@ -910,15 +1116,15 @@ func TestDeleteUserControllerInvalidId(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -932,7 +1138,7 @@ func TestDeleteUserControllerInvalidId(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteDetailUserTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).DeleteUser))
 
 	type UserResponse struct {
 		Message string
@@ -960,7 +1166,7 @@ func TestDeleteUserControllerUnauthorized(t *testing.T) {
 	testCase := Expected{
 		name:         "invalid user id",
 		id:           "2",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusForbidden,
 	}
 
 	dummyData := models.User{
@@ -970,15 +1176,15 @@ func TestDeleteUserControllerUnauthorized(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -992,7 +1198,7 @@ func TestDeleteUserControllerUnauthorized(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteDetailUserTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).DeleteUser))
 
 	type UserResponse struct {
 		Message string
@@ -1006,7 +1212,7 @@ func TestDeleteUserControllerUnauthorized(t *testing.T) {
 		assert.Error(t, err1, "error")
 	}
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "unauthorized", users.Message)
+	assert.Equal(t, "forbidden", users.Message)
 
 }
 
@@ -1030,15 +1236,15 @@ func TestDeleteUserControllerSuccess(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
@@ -1052,7 +1258,7 @@ func TestDeleteUserControllerSuccess(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteDetailUserTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).DeleteUser))
 
 	type UserResponse struct {
 		Message string
@@ -1079,7 +1285,7 @@ func TestDeleteUserControllerFailed(t *testing.T) {
 	testCase := Expected{
 		name:         "failed to delete user",
 		id:           "1",
-		expectedCode: http.StatusBadRequest,
+		expectedCode: http.StatusInternalServerError,
 	}
 
 	dummyData := models.User{
@@ -1089,20 +1295,20 @@ func TestDeleteUserControllerFailed(t *testing.T) {
 
 	user := models.User{}
 
-	e := InitEchoTestAPI()
-	InsertDataUserForGetUsers()
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
 
-	result := config.Db.Where("email = ? AND password = ?", dummyData.Email, dummyData.Password).First(&user)
+	result := db.Where("email = ?", dummyData.Email).First(&user)
 	if result.Error != nil {
 		t.Error(result.Error)
 	}
 
-	token, err := middlewares.CreateToken(int(user.ID))
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
 	if err != nil {
 		t.Error(err)
 	}
 
-	config.Db.Migrator().DropTable(&models.User{})
+	db.Migrator().DropTable(&models.User{})
 	req := httptest.NewRequest(http.MethodDelete, "/jwt/users/:id", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	req.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %v", token))
@@ -1112,7 +1318,7 @@ func TestDeleteUserControllerFailed(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(testCase.id)
 
-	middleware.JWT([]byte(constants.SECRET_JWT))(DeleteDetailUserTesting())(c)
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).DeleteUser))
 
 	type UserResponse struct {
 		Message string
@@ -1126,5 +1332,308 @@ func TestDeleteUserControllerFailed(t *testing.T) {
 		assert.Error(t, err1, "error")
 	}
 	assert.Equal(t, testCase.expectedCode, rec.Code)
-	assert.Equal(t, "failed", users.Message)
+	assert.Equal(t, "internal server error", users.Message)
+}
+
+func TestUserControllersNeverEmitPassword(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
+
+	user := models.User{}
+	if err := db.Where("email = ?", "alta@gmail.com").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newUser, err := json.Marshal(UserRequest{Name: "urnik", Email: "urnik@gmail.com", Password: "urnik123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := json.Marshal(UserRequest{Email: "alta@gmail.com", Password: "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update, err := json.Marshal(UserRequest{Name: "alta", Password: "456secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		method       string
+		body         []byte
+		id           string
+		handler      echo.HandlerFunc
+		expectedCode int
+	}{
+		{"create user", http.MethodPost, newUser, "", InitUserControllerTest(db).CreateUser, http.StatusCreated},
+		{"login", http.MethodPost, login, "", InitUserControllerTest(db).Login, http.StatusOK},
+		{"get all users", http.MethodGet, nil, "", InitUserControllerTest(db).GetAllUsers, http.StatusOK},
+		{"get single user", http.MethodGet, nil, fmt.Sprint(user.ID), InitTokenManagerTest().JWT()(InitUserControllerTest(db).GetSingleUser), http.StatusOK},
+		{"update user", http.MethodPut, update, fmt.Sprint(user.ID), InitTokenManagerTest().JWT()(InitUserControllerTest(db).UpdateUser), http.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(testCase.method, "/", bytes.NewBuffer(testCase.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %v", token))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		if testCase.id != "" {
+			c.SetParamNames("id")
+			c.SetParamValues(testCase.id)
+		}
+
+		if assert.NoError(t, HandleTest(e, c, testCase.handler), testCase.name) {
+			assert.Equal(t, testCase.expectedCode, rec.Code, testCase.name)
+			assert.NotContains(t, strings.ToLower(rec.Body.String()), "password", testCase.name)
+			assert.NotContains(t, rec.Body.String(), "$2a$", testCase.name)
+		}
+	}
+}
+
+func TestCreateUserControllerIgnoresRole(t *testing.T) {
+	e, db := InitEchoTestAPI()
+
+	body := []byte(`{"name":"urnik","email":"urnik@gmail.com","password":"urnik123","role":"admin","Role":"admin"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, HandleTest(e, c, InitUserControllerTest(db).CreateUser)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		stored := models.User{}
+		if assert.NoError(t, db.Where("email = ?", "urnik@gmail.com").First(&stored).Error) {
+			assert.Equal(t, models.RoleMember, stored.Role)
+		}
+	}
+}
+
+func TestUserControllerWithFakeRepository(t *testing.T) {
+	hashed, err := passwords.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newController := func() (*UserController, *fakeUserRepository) {
+		users := newFakeUserRepository(
+			models.User{Name: "alta", Email: "alta@gmail.com", Password: hashed},
+			models.User{Name: "budi", Email: "budi@gmail.com", Password: "plain"},
+		)
+		return NewUserController(users, newFakeRefreshTokenRepository(), InitTokenManagerTest()), users
+	}
+
+	serve := func(h echo.HandlerFunc, method, id, body string, loggedIn uint) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Validator = validation.New()
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+
+		if loggedIn != 0 {
+			token, err := InitTokenManagerTest().CreateToken(int(loggedIn), models.RoleMember)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			h = InitTokenManagerTest().JWT()(h)
+		}
+
+		HandleTest(e, c, h)
+		return rec
+	}
+
+	t.Run("create user is hashed and a member", func(t *testing.T) {
+		h, users := newController()
+		rec := serve(h.CreateUser, http.MethodPost, "", `{"name":"cici","email":"cici@gmail.com","password":"password1"}`, 0)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		created, err := users.GetByEmail("cici@gmail.com")
+		assert.NoError(t, err)
+		assert.True(t, passwords.IsHashed(created.Password))
+		assert.Equal(t, models.RoleMember, created.Role)
+	})
+
+	t.Run("get own user", func(t *testing.T) {
+		h, _ := newController()
+		rec := serve(h.GetSingleUser, http.MethodGet, "1", "", 1)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "alta@gmail.com")
+	})
+
+	t.Run("get another user is forbidden", func(t *testing.T) {
+		h, _ := newController()
+		rec := serve(h.GetSingleUser, http.MethodGet, "2", "", 1)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("update cannot change role", func(t *testing.T) {
+		h, users := newController()
+		rec := serve(h.UpdateUser, http.MethodPut, "1", `{"name":"alta baru","role":"admin"}`, 1)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		updated, _ := users.GetByID(1)
+		assert.Equal(t, "alta baru", updated.Name)
+		assert.Equal(t, models.RoleMember, updated.Role)
+	})
+
+	t.Run("delete own user", func(t *testing.T) {
+		h, users := newController()
+		rec := serve(h.DeleteUser, http.MethodDelete, "1", "", 1)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		_, err := users.GetByID(1)
+		assert.ErrorIs(t, err, databases.ErrNotFound)
+	})
+
+	t.Run("update role rejects unknown role", func(t *testing.T) {
+		h, _ := newController()
+		rec := serve(h.UpdateUserRole, http.MethodPut, "2", `{"role":"owner"}`, 0)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+
+	t.Run("login", func(t *testing.T) {
+		h, _ := newController()
+		rec := serve(h.Login, http.MethodPost, "", `{"email":"alta@gmail.com","password":"secret"}`, 0)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "refresh-1")
+		assert.NotContains(t, rec.Body.String(), hashed)
+	})
+
+	t.Run("login rehashes plaintext password", func(t *testing.T) {
+		h, users := newController()
+		rec := serve(h.Login, http.MethodPost, "", `{"email":"budi@gmail.com","password":"plain"}`, 0)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		upgraded, _ := users.GetByID(2)
+		assert.True(t, passwords.IsHashed(upgraded.Password))
+	})
+
+	t.Run("create user with invalid email and short password", func(t *testing.T) {
+		h, users := newController()
+		rec := serve(h.CreateUser, http.MethodPost, "", `{"name":"cici","email":"cici","password":"pw"}`, 0)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `{"field":"email","code":"email","message":"must be a valid email address"}`)
+		assert.Contains(t, rec.Body.String(), `{"field":"password","code":"min","message":"must be at least 8 characters"}`)
+		assert.Len(t, users.users, 2)
+	})
+
+	t.Run("login without password", func(t *testing.T) {
+		h, _ := newController()
+		rec := serve(h.Login, http.MethodPost, "", `{"email":"alta@gmail.com"}`, 0)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+
+	t.Run("login with unknown email", func(t *testing.T) {
+		h, _ := newController()
+		rec := serve(h.Login, http.MethodPost, "", `{"email":"nobody@gmail.com","password":"secret"}`, 0)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid email or password")
+	})
+}
+
+// protectedFieldsBody tries to set every field a client must not control,
+// under both the JSON and the Go field names.
+const protectedFieldsBody = `{
+	"id": 99, "ID": 99,
+	"createdAt": "2000-01-01T00:00:00Z", "CreatedAt": "2000-01-01T00:00:00Z",
+	"deletedAt": "2000-01-01T00:00:00Z", "DeletedAt": "2000-01-01T00:00:00Z",
+	"role": "admin", "Role": "admin",
+	"token": "forged", "Token": "forged",
+	"name": "mallory", "email": "mallory@gmail.com", "password": "mallory123"
+}`
+
+func TestUserControllersIgnoreProtectedFields(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
+
+	user := models.User{}
+	if err := db.Where("email = ?", "alta@gmail.com").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := InitTokenManagerTest().CreateToken(int(user.ID), user.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/jwt/users/:id", strings.NewReader(protectedFieldsBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(fmt.Sprint(user.ID))
+
+	HandleTest(e, c, InitTokenManagerTest().JWT()(InitUserControllerTest(db).UpdateUser))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	updated := models.User{}
+	if assert.NoError(t, db.Unscoped().First(&updated, user.ID).Error) {
+		assert.Equal(t, "mallory", updated.Name)
+		assert.Equal(t, models.RoleMember, updated.Role)
+		assert.Equal(t, user.CreatedAt.Unix(), updated.CreatedAt.Unix())
+		assert.False(t, updated.DeletedAt.Valid)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(strings.Replace(protectedFieldsBody, "mallory@", "eve@", 1)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	HandleTest(e, c, InitUserControllerTest(db).CreateUser)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	created := models.User{}
+	if assert.NoError(t, db.Unscoped().Where("email = ?", "eve@gmail.com").First(&created).Error) {
+		assert.NotEqual(t, uint(99), created.ID)
+		assert.Equal(t, models.RoleMember, created.Role)
+		assert.True(t, created.CreatedAt.Year() > 2000)
+		assert.False(t, created.DeletedAt.Valid)
+	}
+}
+
+func TestCreateUserControllerDuplicateEmail(t *testing.T) {
+	e, db := InitEchoTestAPI()
+	InsertDataUserForGetUsers(db)
+
+	body := `{"name":"alta","email":"ALTA@gmail.com","password":"alta12345"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	HandleTest(e, c, InitUserControllerTest(db).CreateUser)
+
+	var result struct {
+		Message string
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "a user with this email already exists", result.Message)
+
+	var count int64
+	db.Model(&models.User{}).Where("email = ?", "alta@gmail.com").Count(&count)
+	assert.Equal(t, int64(1), count)
 }
@@ -0,0 +1,275 @@
+package databases
+
+import (
+	"cleancode/lib/search"
+	"cleancode/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// AuthorFilter narrows an author list. Name matches any part of the name
+// ignoring case.
+type AuthorFilter struct {
+	Name string
+}
+
+var authorList = listQuery{
+	columns: map[string]listColumn{
+		"id":         {expr: "id", field: "ID", kind: idColumn},
+		"name":       {expr: "COALESCE(name, '')", field: "Name", kind: textColumn},
+		"created_at": {expr: "created_at", field: "CreatedAt", kind: timeColumn},
+	},
+	defaultSort: "name",
+}
+
+// GormAuthorRepository stores authors with GORM. Renaming an author
+// rewrites the byline of their books, so it shares the book search index.
+type GormAuthorRepository struct {
+	db    *gorm.DB
+	index search.BookIndex
+}
+
+func NewGormAuthorRepository(db *gorm.DB, index search.BookIndex) *GormAuthorRepository {
+	return &GormAuthorRepository{db: db, index: index}
+}
+
+func (r *GormAuthorRepository) List(filter AuthorFilter, opts ListOptions) ([]models.Author, Page, error) {
+	query := r.db.Model(&models.Author{})
+	if filter.Name != "" {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '!'", containsPattern(filter.Name))
+	}
+
+	authors := []models.Author{}
+	page, err := authorList.find(query, opts, &authors)
+	if err != nil {
+		return nil, page, translateError(err, ErrAuthorNotFound)
+	}
+
+	return authors, page, nil
+}
+
+func (r *GormAuthorRepository) GetByID(id uint) (models.Author, error) {
+	author := models.Author{}
+	result := r.db.First(&author, id)
+	if result.Error != nil {
+		return author, translateError(result.Error, ErrAuthorNotFound)
+	}
+
+	return author, nil
+}
+
+func (r *GormAuthorRepository) Create(author *models.Author) error {
+	result := r.db.Create(author)
+	return translateError(result.Error, ErrAuthorNotFound)
+}
+
+// Update writes the name and bio set in changes. A new name is written into
+// the byline of every book of the author.
+func (r *GormAuthorRepository) Update(id uint, changes models.Author) (models.Author, error) {
+	author, err := r.GetByID(id)
+	if err != nil {
+		return author, err
+	}
+
+	updates := map[string]interface{}{}
+	if changes.Name != "" {
+		updates["name"] = changes.Name
+	}
+	if changes.Bio != "" {
+		updates["bio"] = changes.Bio
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&author).Updates(updates).Error; err != nil {
+			return err
+		}
+		if changes.Name == "" {
+			return nil
+		}
+		return r.refreshBylines(tx, id)
+	})
+	if err != nil {
+		return author, translateError(err, ErrAuthorNotFound)
+	}
+
+	return author, nil
+}
+
+// refreshBylines rewrites the byline of the books of an author and indexes
+// them again.
+func (r *GormAuthorRepository) refreshBylines(tx *gorm.DB, authorId uint) error {
+	books := []models.Book{}
+	err := tx.Where("id IN (?)", tx.Model(&models.BookAuthor{}).Select("book_id").Where("author_id = ?", authorId)).Find(&books).Error
+	if err != nil {
+		return err
+	}
+	if err := loadAuthors(tx, books); err != nil {
+		return err
+	}
+
+	for _, book := range books {
+		book.Author = models.Byline(book.Authors)
+		if err := tx.Model(&book).UpdateColumn("author", book.Author).Error; err != nil {
+			return err
+		}
+		if err := r.index.Index(book); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes an author that is not credited on any book; otherwise it
+// fails with ErrAuthorHasBooks.
+func (r *GormAuthorRepository) Delete(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var books int64
+		err := tx.Model(&models.Book{}).
+			Where("id IN (?)", tx.Model(&models.BookAuthor{}).Select("book_id").Where("author_id = ?", id)).
+			Count(&books).Error
+		if err != nil {
+			return err
+		}
+		if books > 0 {
+			return ErrAuthorHasBooks
+		}
+
+		result := tx.Delete(&models.Author{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAuthorNotFound
+		}
+		return nil
+	})
+	return translateError(err, ErrAuthorNotFound)
+}
+
+// Books lists the books an author is credited on.
+func (r *GormAuthorRepository) Books(id uint, opts ListOptions) ([]models.Book, Page, error) {
+	if _, err := r.GetByID(id); err != nil {
+		return nil, Page{}, err
+	}
+
+	query := r.db.Model(&models.Book{}).
+		Where("id IN (?)", r.db.Model(&models.BookAuthor{}).Select("book_id").Where("author_id = ?", id))
+
+	books := []models.Book{}
+	page, err := bookList.find(query.Scopes(withTags), opts, &books)
+	if err == nil {
+		err = loadAuthors(r.db, books)
+	}
+	if err != nil {
+		return nil, page, translateError(err, ErrBookNotFound)
+	}
+
+	return books, page, nil
+}
+
+// resolveAuthors returns the stored authors for authors, in order and
+// without repeats. An author given by ID must exist; one given by name is
+// matched ignoring case to the oldest author of that name, or created.
+func resolveAuthors(tx *gorm.DB, authors []models.Author) ([]models.Author, error) {
+	resolved := []models.Author{}
+	seen := map[uint]bool{}
+	for _, author := range authors {
+		stored := models.Author{}
+		var err error
+		if author.ID != 0 {
+			err = tx.First(&stored, author.ID).Error
+		} else {
+			err = tx.Where("LOWER(name) = ?", strings.ToLower(author.Name)).Order("id").First(&stored).Error
+			if err == gorm.ErrRecordNotFound {
+				stored = models.Author{Name: author.Name}
+				err = tx.Create(&stored).Error
+			}
+		}
+		if err != nil {
+			return nil, translateError(err, ErrAuthorNotFound)
+		}
+
+		if !seen[stored.ID] {
+			seen[stored.ID] = true
+			resolved = append(resolved, stored)
+		}
+	}
+	return resolved, nil
+}
+
+// authorsFromByline is how a book given only a byline gets its authors.
+func authorsFromByline(byline string) []models.Author {
+	authors := []models.Author{}
+	for _, name := range models.SplitAuthorNames(byline) {
+		authors = append(authors, models.Author{Name: name})
+	}
+	return authors
+}
+
+// setBookAuthors credits book to authors, which must be stored, in order,
+// and writes the byline they make.
+func setBookAuthors(tx *gorm.DB, book *models.Book, authors []models.Author) error {
+	if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookAuthor{}).Error; err != nil {
+		return err
+	}
+
+	for position, author := range authors {
+		link := models.BookAuthor{BookID: book.ID, AuthorID: author.ID, Position: position}
+		if err := tx.Create(&link).Error; err != nil {
+			return err
+		}
+	}
+
+	book.Authors = authors
+	book.Author = models.Byline(authors)
+	return tx.Model(book).UpdateColumn("author", book.Author).Error
+}
+
+// loadAuthorsOf fills in the authors of a single book.
+func loadAuthorsOf(db *gorm.DB, book *models.Book) error {
+	books := []models.Book{*book}
+	if err := loadAuthors(db, books); err != nil {
+		return err
+	}
+	book.Authors = books[0].Authors
+	return nil
+}
+
+// loadAuthors fills in the authors of books, in credit order.
+func loadAuthors(db *gorm.DB, books []models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(books))
+	for _, book := range books {
+		ids = append(ids, book.ID)
+	}
+
+	var rows []struct {
+		models.Author
+		BookID uint
+	}
+	err := db.Model(&models.Author{}).
+		Select("authors.*, book_authors.book_id").
+		Joins("JOIN book_authors ON book_authors.author_id = authors.id").
+		Where("book_authors.book_id IN ?", ids).
+		Order("book_authors.position").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byBook := map[uint][]models.Author{}
+	for _, row := range rows {
+		byBook[row.BookID] = append(byBook[row.BookID], row.Author)
+	}
+	for i := range books {
+		books[i].Authors = byBook[books[i].ID]
+		if books[i].Authors == nil {
+			books[i].Authors = []models.Author{}
+		}
+	}
+	return nil
+}
@@ -1,87 +1,344 @@
 package databases
 
 import (
-	"cleancode/config"
+	"cleancode/lib/search"
 	"cleancode/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func GetAllBooks() (interface{}, int, error) {
-	books := []models.Book{}
-	outputBook := []models.OutputBook{}
-	result := config.Db.Model(&books).Find(&outputBook)
-	if result.Error != nil {
-		return nil, 0, result.Error
+// reindexBatchSize is how many books Reindex loads at a time.
+const reindexBatchSize = 500
+
+// GormBookRepository stores books with GORM and keeps index in step with
+// every write. The index is changed inside the write's transaction, so a
+// write the index rejects is rolled back.
+type GormBookRepository struct {
+	db    *gorm.DB
+	index search.BookIndex
+}
+
+func NewGormBookRepository(db *gorm.DB, index search.BookIndex) *GormBookRepository {
+	return &GormBookRepository{db: db, index: index}
+}
+
+// BookMatch is a book found by Search, with the relevance and highlighted
+// fragments the index reported for it.
+type BookMatch struct {
+	Book       models.Book
+	Score      float64
+	Highlights map[string][]string
+}
+
+// BookFilter narrows a book list. Title and Author match any part of the
+// column ignoring case; the publication dates are exclusive bounds.
+type BookFilter struct {
+	Title           string
+	Author          string
+	PublishedAfter  string
+	PublishedBefore string
+}
+
+var bookList = listQuery{
+	columns: map[string]listColumn{
+		"id":           {expr: "id", field: "ID", kind: idColumn},
+		"title":        {expr: "COALESCE(title, '')", field: "Title", kind: textColumn},
+		"author":       {expr: "COALESCE(author, '')", field: "Author", kind: textColumn},
+		"published_at": {expr: "COALESCE(published_at, '" + noDate + "')", field: "Published_at", kind: dateColumn},
+		"created_at":   {expr: "created_at", field: "CreatedAt", kind: timeColumn},
+	},
+	defaultSort: "id",
+}
+
+func (r *GormBookRepository) List(filter BookFilter, opts ListOptions) ([]models.Book, Page, error) {
+	query := r.db.Model(&models.Book{})
+	if filter.Title != "" {
+		query = query.Where("LOWER(title) LIKE ? ESCAPE '!'", containsPattern(filter.Title))
+	}
+	if filter.Author != "" {
+		query = query.Where("LOWER(author) LIKE ? ESCAPE '!'", containsPattern(filter.Author))
+	}
+	if filter.PublishedAfter != "" {
+		query = query.Where("published_at > ?", filter.PublishedAfter)
+	}
+	if filter.PublishedBefore != "" {
+		query = query.Where("published_at < ?", filter.PublishedBefore)
 	}
 
-	if result.RowsAffected > 0 {
-		return outputBook, 1, nil
+	books := []models.Book{}
+	page, err := bookList.find(query.Scopes(withTags), opts, &books)
+	if err == nil {
+		err = loadAuthors(r.db, books)
+	}
+	if err != nil {
+		return nil, page, translateError(err, ErrBookNotFound)
 	}
 
-	return "Book not found", 0, nil
+	return books, page, nil
 }
 
-func GetSingleBook(bookId int) (interface{}, int, error) {
+func (r *GormBookRepository) GetByID(id uint) (models.Book, error) {
 	book := models.Book{}
-	bookOutput := models.OutputBook{}
-	result := config.Db.Model(&book).Find(&bookOutput, bookId)
-	if result.Error != nil {
-		return nil, 0, result.Error
+	err := r.db.Scopes(withTags).First(&book, id).Error
+	if err == nil {
+		err = loadAuthorsOf(r.db, &book)
+	}
+	if err != nil {
+		return book, translateError(err, ErrBookNotFound)
 	}
 
-	if result.RowsAffected > 0 {
-		return bookOutput, 1, nil
+	return book, nil
+}
+
+// Search finds books by title and author, best match first. The page is
+// addressed by number only, as relevance gives no stable cursor.
+func (r *GormBookRepository) Search(text string, opts ListOptions) ([]BookMatch, Page, error) {
+	page := Page{Limit: opts.Limit, Page: opts.Page}
+	if page.Limit <= 0 {
+		page.Limit = DefaultPageSize
+	}
+	if page.Limit > MaxPageSize {
+		page.Limit = MaxPageSize
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+
+	offset := (page.Page - 1) * page.Limit
+	result, err := r.index.Search(text, page.Limit, offset)
+	if err != nil {
+		return nil, page, err
 	}
+	page.Total = int64(result.Total)
+	page.HasMore = uint64(offset+len(result.Hits)) < result.Total
+
+	ids := make([]uint, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+
+	books := []models.Book{}
+	if len(ids) > 0 {
+		err := r.db.Scopes(withTags).Where("id IN ?", ids).Find(&books).Error
+		if err == nil {
+			err = loadAuthors(r.db, books)
+		}
+		if err != nil {
+			return nil, page, translateError(err, ErrBookNotFound)
+		}
+	}
+	byId := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		byId[book.ID] = book
+	}
+
+	// keep the index's order, skipping hits the database no longer has
+	matches := make([]BookMatch, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		if book, ok := byId[hit.ID]; ok {
+			matches = append(matches, BookMatch{Book: book, Score: hit.Score, Highlights: hit.Highlights})
+		}
+	}
+	return matches, page, nil
+}
+
+// Reindex indexes every stored book, for an index that is new or was lost.
+func (r *GormBookRepository) Reindex() error {
+	books := []models.Book{}
+	result := r.db.FindInBatches(&books, reindexBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, book := range books {
+			if err := r.index.Index(book); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return translateError(result.Error, ErrBookNotFound)
+}
+
+// Create stores book with its tags and authors, creating the tags that do
+// not exist yet. Without Authors, the authors are taken from the Author
+// byline, matching stored authors by name. A new book has no copies or
+// reviews; they are added with their own repositories.
+func (r *GormBookRepository) Create(book *models.Book) error {
+	book.Copies, book.AvailableCopies = 0, 0
+	book.ReviewCount, book.RatingSum = 0, 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		tags, err := resolveTags(tx, book.Tags)
+		if err != nil {
+			return err
+		}
+		if book.Authors == nil {
+			book.Authors = authorsFromByline(book.Author)
+		}
+		authors, err := resolveAuthors(tx, book.Authors)
+		if err != nil {
+			return err
+		}
 
-	return "Book not found", 0, nil
+		if err := tx.Omit("Tags").Create(book).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(book).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		book.Tags = tags
+		if err := setBookAuthors(tx, book, authors); err != nil {
+			return err
+		}
+		return r.index.Index(*book)
+	})
+	return translateBookError(err)
 }
 
-func CreateNewBook(book *models.Book) (interface{}, error) {
-	result := config.Db.Create(&book)
-	if result.Error != nil {
-		return nil, result.Error
+// Update writes the fields set in changes. Tags are replaced when
+// changes.Tags is not nil, so an empty list removes them all. Authors are
+// replaced when changes.Authors is not nil or, failing that, when a new
+// Author byline is given.
+func (r *GormBookRepository) Update(id uint, changes models.Book) (models.Book, error) {
+	book, err := r.GetByID(id)
+	if err != nil {
+		return book, err
 	}
 
-	bookOutput := models.OutputBook{}
-	bookOutput.Author = book.Author
-	bookOutput.Title = book.Title
-	bookOutput.Published_at = book.Published_at
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&book).Omit("Tags").Updates(bookUpdates(changes)).Error; err != nil {
+			return err
+		}
+		if changes.Tags != nil {
+			tags, err := resolveTags(tx, changes.Tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&book).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+		authors := changes.Authors
+		if authors == nil && changes.Author != "" {
+			authors = authorsFromByline(changes.Author)
+		}
+		if authors != nil {
+			resolved, err := resolveAuthors(tx, authors)
+			if err != nil {
+				return err
+			}
+			if err := setBookAuthors(tx, &book, resolved); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Scopes(withTags).First(&book, id).Error; err != nil {
+			return err
+		}
+		if err := loadAuthorsOf(tx, &book); err != nil {
+			return err
+		}
+		return r.index.Index(book)
+	})
+	if err != nil {
+		return book, translateBookError(err)
+	}
 
-	return bookOutput, nil
+	return book, nil
 }
 
-func DeleteBook(bookId int) (interface{}, int, error) {
-	book := models.Book{}
-	result := config.Db.Delete(&book, bookId)
-	if result.Error != nil {
-		return nil, 0, result.Error
+// bookUpdates picks the columns Update may write from the non-empty fields of
+// changes. The ID, timestamps and soft delete marker are never copied, and
+// the author byline is written with the authors.
+func bookUpdates(changes models.Book) map[string]interface{} {
+	updates := map[string]interface{}{}
+	if changes.Title != "" {
+		updates["title"] = changes.Title
+	}
+	if !changes.Published_at.IsZero() {
+		updates["published_at"] = changes.Published_at
+	}
+	if changes.ISBN != nil {
+		updates["isbn"] = *changes.ISBN
+	}
+	if changes.Description != "" {
+		updates["description"] = changes.Description
+	}
+	if changes.Language != "" {
+		updates["language"] = changes.Language
+	}
+	if changes.Pages != 0 {
+		updates["pages"] = changes.Pages
+	}
+	if changes.Publisher != "" {
+		updates["publisher"] = changes.Publisher
+	}
+	if changes.CoverURL != "" {
+		updates["cover_url"] = changes.CoverURL
+	}
+	return updates
+}
+
+// resolveTags returns the stored tags named in tags, creating missing ones.
+// A tag created concurrently by another writer is picked up, not duplicated.
+func resolveTags(tx *gorm.DB, tags []models.Tag) ([]models.Tag, error) {
+	resolved := []models.Tag{}
+	if len(tags) == 0 {
+		return resolved, nil
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, models.NormalizeTag(tag.Name))
 	}
 
-	if result.RowsAffected > 0 {
-		return "deleted", 1, nil
+	missing := []models.Tag{}
+	for _, name := range names {
+		missing = append(missing, models.Tag{Name: name})
 	}
-	return "Book not found", 0, nil
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&missing).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Where("name IN ?", names).Order("name").Find(&resolved).Error
+	return resolved, err
 }
 
-func UpdateBook(bookId int, newBook models.Book) (interface{}, int, error) {
-	book := models.Book{}
-	findResult := config.Db.Find(&book, bookId)
-	if findResult.Error != nil {
-		return nil, 0, findResult.Error
+// withTags loads the tags of books, ordered by name.
+func withTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name")
+	})
+}
+
+// translateBookError reports a unique violation as ErrISBNTaken, the only
+// unique column a caller can write.
+func translateBookError(err error) error {
+	err = translateError(err, ErrBookNotFound)
+	if errors.Is(err, ErrConflict) {
+		return ErrISBNTaken
 	}
+	return err
+}
 
-	if findResult.RowsAffected > 0 {
-		updatedResult := config.Db.Model(&book).Updates(newBook)
-		if updatedResult.Error != nil {
-			return nil, 0, updatedResult.Error
+// Delete soft deletes a book and clears its ISBN, so the ISBN can be
+// catalogued again.
+func (r *GormBookRepository) Delete(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Book{}).Where("id = ? AND isbn IS NOT NULL", id).UpdateColumn("isbn", nil).Error
+		if err != nil {
+			return err
 		}
 
-		bookOutput := models.OutputBook{}
-		bookOutput.Author = book.Author
-		bookOutput.Title = book.Title
-		bookOutput.Published_at = book.Published_at
+		result := tx.Delete(&models.Book{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
 
-		return bookOutput, 1, nil
-	}
+		if result.RowsAffected == 0 {
+			return ErrBookNotFound
+		}
 
-	return "Book not found", 0, nil
+		return r.index.Delete(id)
+	})
+	return translateError(err, ErrBookNotFound)
 }
@@ -0,0 +1,237 @@
+package databases
+
+import (
+	"cleancode/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookCopyFilter narrows a copy list to one status.
+type BookCopyFilter struct {
+	Status string
+}
+
+var bookCopyList = listQuery{
+	columns: map[string]listColumn{
+		"id":             {expr: "id", field: "ID", kind: idColumn},
+		"barcode":        {expr: "barcode", field: "Barcode", kind: textColumn},
+		"shelf_location": {expr: "shelf_location", field: "ShelfLocation", kind: textColumn},
+	},
+	defaultSort: "id",
+}
+
+// GormBookCopyRepository stores the copies of books with GORM and keeps the
+// counts of their books with them: books.copies counts the copies that are
+// not deleted, and books.available_copies those on the shelf that are not
+// held for a reservation. Every change locks the book's row first, like
+// checkouts and returns do, so the counts never drift from the copies.
+type GormBookCopyRepository struct {
+	db         *gorm.DB
+	holdPeriod time.Duration
+}
+
+// NewGormBookCopyRepository holds copies put back on the shelf for
+// holdPeriod when someone is queued for their book.
+func NewGormBookCopyRepository(db *gorm.DB, holdPeriod time.Duration) *GormBookCopyRepository {
+	return &GormBookCopyRepository{db: db, holdPeriod: holdPeriod}
+}
+
+// List lists the copies of a book, by id unless opts sorts them otherwise.
+func (r *GormBookCopyRepository) List(bookId uint, filter BookCopyFilter, opts ListOptions) ([]models.BookCopy, Page, error) {
+	if err := r.db.Select("id").First(&models.Book{}, bookId).Error; err != nil {
+		return nil, Page{}, translateError(err, ErrBookNotFound)
+	}
+
+	query := r.db.Model(&models.BookCopy{}).Where("book_id = ?", bookId)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	copies := []models.BookCopy{}
+	page, err := bookCopyList.find(query, opts, &copies)
+	if err != nil {
+		return nil, page, translateError(err, ErrCopyNotFound)
+	}
+
+	return copies, page, nil
+}
+
+func (r *GormBookCopyRepository) GetByID(bookId uint, id uint) (models.BookCopy, error) {
+	bookCopy := models.BookCopy{}
+	result := r.db.Where("book_id = ?", bookId).First(&bookCopy, id)
+	if result.Error != nil {
+		return bookCopy, translateError(result.Error, ErrCopyNotFound)
+	}
+
+	return bookCopy, nil
+}
+
+// Create adds a copy to the book named by bookCopy.BookID. A copy added on
+// the shelf goes to the oldest reservation of the book first.
+func (r *GormBookCopyRepository) Create(bookCopy *models.BookCopy) error {
+	bookCopy.Barcode = models.NormalizeBarcode(bookCopy.Barcode)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		book, err := lockBook(tx, bookCopy.BookID)
+		if err != nil {
+			return err
+		}
+		if book.DeletedAt.Valid {
+			return ErrBookNotFound
+		}
+
+		if err := tx.Create(bookCopy).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&models.Book{}).
+			Where("id = ?", book.ID).
+			UpdateColumn("copies", gorm.Expr("copies + 1")).Error
+		if err != nil {
+			return err
+		}
+		if bookCopy.Status == models.CopyAvailable {
+			return releaseCopy(tx, book.ID, time.Now(), r.holdPeriod)
+		}
+		return nil
+	})
+	return translateCopyError(err)
+}
+
+// Update writes the fields set in changes. A copy put back on the shelf is
+// handled like a returned one; a copy taken off it must not be the last
+// one held for a reservation.
+func (r *GormBookCopyRepository) Update(bookId uint, id uint, changes models.BookCopy) (models.BookCopy, error) {
+	bookCopy := models.BookCopy{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockBook(tx, bookId); err != nil {
+			return err
+		}
+		if err := tx.Where("book_id = ?", bookId).First(&bookCopy, id).Error; err != nil {
+			return err
+		}
+
+		if changes.Status != "" && changes.Status != bookCopy.Status {
+			if err := r.moveCopy(tx, bookCopy, changes.Status); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&bookCopy).Updates(bookCopyUpdates(changes)).Error; err != nil {
+			return err
+		}
+		return tx.First(&bookCopy, id).Error
+	})
+	if err != nil {
+		return bookCopy, translateCopyError(err)
+	}
+
+	return bookCopy, nil
+}
+
+// Delete removes a copy that is not on loan from its book.
+func (r *GormBookCopyRepository) Delete(bookId uint, id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockBook(tx, bookId); err != nil {
+			return err
+		}
+		bookCopy := models.BookCopy{}
+		if err := tx.Where("book_id = ?", bookId).First(&bookCopy, id).Error; err != nil {
+			return err
+		}
+
+		switch bookCopy.Status {
+		case models.CopyOnLoan:
+			return ErrCopyOnLoan
+		case models.CopyAvailable:
+			if err := r.shelveOff(tx, bookId); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&bookCopy).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Book{}).
+			Where("id = ?", bookId).
+			UpdateColumn("copies", gorm.Expr("copies - 1")).Error
+	})
+	return translateCopyError(err)
+}
+
+// moveCopy adjusts the counts of a copy's book for the copy changing to
+// status. The caller must hold the book's lock.
+func (r *GormBookCopyRepository) moveCopy(tx *gorm.DB, bookCopy models.BookCopy, status string) error {
+	switch {
+	case bookCopy.Status == models.CopyOnLoan:
+		return ErrCopyOnLoan
+	case bookCopy.Status == models.CopyAvailable:
+		return r.shelveOff(tx, bookCopy.BookID)
+	case status == models.CopyAvailable:
+		return releaseCopy(tx, bookCopy.BookID, time.Now(), r.holdPeriod)
+	}
+	return nil
+}
+
+// shelveOff takes a copy of a book off the shelf, failing with ErrCopyHeld
+// when every copy on it is held for a reservation. Lapsed holds are expired
+// first so their copies count as free. The caller must hold the book's lock.
+func (r *GormBookCopyRepository) shelveOff(tx *gorm.DB, bookId uint) error {
+	if err := expireHolds(tx, r.holdPeriod, "book_id = ?", bookId); err != nil {
+		return err
+	}
+
+	shelf := tx.Unscoped().Model(&models.Book{}).
+		Where("id = ? AND available_copies > 0", bookId).
+		UpdateColumn("available_copies", gorm.Expr("available_copies - 1"))
+	if shelf.Error != nil {
+		return shelf.Error
+	}
+	if shelf.RowsAffected == 0 {
+		return ErrCopyHeld
+	}
+	return nil
+}
+
+// bookCopyUpdates picks the columns Update may write from the non-empty
+// fields of changes.
+func bookCopyUpdates(changes models.BookCopy) map[string]interface{} {
+	updates := map[string]interface{}{}
+	if barcode := models.NormalizeBarcode(changes.Barcode); barcode != "" {
+		updates["barcode"] = barcode
+	}
+	if changes.ShelfLocation != "" {
+		updates["shelf_location"] = changes.ShelfLocation
+	}
+	if changes.Condition != "" {
+		updates["condition"] = changes.Condition
+	}
+	if changes.Status != "" {
+		updates["status"] = changes.Status
+	}
+	return updates
+}
+
+// lendCopy marks a copy of a book on the shelf as on loan and returns it.
+// The caller must hold the book's lock and have taken the copy from the
+// book's counts.
+func lendCopy(tx *gorm.DB, bookId uint) (models.BookCopy, error) {
+	bookCopy := models.BookCopy{}
+	err := tx.Where("book_id = ? AND status = ?", bookId, models.CopyAvailable).Order("id").Take(&bookCopy).Error
+	if err != nil {
+		return bookCopy, translateError(err, ErrNoCopyAvailable)
+	}
+
+	err = tx.Model(&bookCopy).Update("status", models.CopyOnLoan).Error
+	return bookCopy, err
+}
+
+// translateCopyError maps unique key violations onto ErrBarcodeTaken, the
+// only unique key of copies.
+func translateCopyError(err error) error {
+	err = translateError(err, ErrCopyNotFound)
+	if errors.Is(err, ErrConflict) {
+		return ErrBarcodeTaken
+	}
+	return err
+}
@@ -0,0 +1,1385 @@
+package databases
+
+import (
+	"cleancode/config"
+	"cleancode/lib/search"
+	"cleancode/models"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// testBackends opens an empty database for every backend available to the
+// test run: SQLite always, Postgres when TEST_POSTGRES_DSN is set or TestMain
+// started an embedded one, and MySQL when TEST_MYSQL_DSN is set.
+func testBackends(t *testing.T) map[string]*gorm.DB {
+	t.Helper()
+
+	backends := map[string]string{config.DriverSQLite: config.TestDSN()}
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		backends[config.DriverPostgres] = dsn
+	}
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		backends[config.DriverMySQL] = dsn
+	}
+
+	dbs := map[string]*gorm.DB{}
+	for name, dsn := range backends {
+		dbs[name] = config.InitDbTestWith(dsn)
+	}
+	return dbs
+}
+
+// newTestBookRepository returns a book repository over db with its own
+// in-memory search index.
+func newTestBookRepository(t *testing.T, db *gorm.DB) *GormBookRepository {
+	t.Helper()
+
+	index, err := search.NewMemoryIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return NewGormBookRepository(db, index)
+}
+
+func TestMigrationSchema(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			migrator := db.Migrator()
+
+			for _, table := range []interface{}{&models.User{}, &models.Book{}, &models.RefreshToken{}, &models.Tag{}, "book_tags", &models.Author{}, "book_authors", &models.Loan{}, &models.Reservation{}, &models.BookCopy{}, &models.FineEntry{}, &models.Review{}, &models.ReadingList{}, &models.ReadingListItem{}} {
+				assert.True(t, migrator.HasTable(table))
+			}
+			for _, column := range []string{"Title", "Author", "Published_at", "DeletedAt", "ISBN", "Description", "Language", "Pages", "Publisher", "CoverURL", "Copies", "AvailableCopies", "ReviewCount", "RatingSum"} {
+				assert.True(t, migrator.HasColumn(&models.Book{}, column), column)
+			}
+			for _, column := range []string{"Name", "Email", "Password", "Role"} {
+				assert.True(t, migrator.HasColumn(&models.User{}, column), column)
+			}
+			assert.True(t, migrator.HasIndex(&models.RefreshToken{}, "TokenHash"))
+			assert.True(t, migrator.HasIndex(&models.Book{}, "ISBN"))
+			assert.True(t, migrator.HasIndex(&models.Tag{}, "Name"))
+			assert.True(t, migrator.HasIndex(&models.Author{}, "Name"))
+			assert.True(t, migrator.HasIndex(&models.BookCopy{}, "Barcode"))
+			assert.True(t, migrator.HasColumn(&models.Loan{}, "CopyID"))
+			assert.True(t, migrator.HasIndex(&models.Review{}, "idx_reviews_book_user"))
+			assert.True(t, migrator.HasIndex(&models.ReadingList{}, "Slug"))
+			assert.True(t, migrator.HasIndex(&models.ReadingListItem{}, "idx_reading_list_items_book"))
+		})
+	}
+}
+
+func TestDuplicateKeyIsConflict(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+
+			book := models.Book{Title: "chemistry"}
+			assert.NoError(t, books.Create(&book))
+
+			duplicate := models.Book{Model: gorm.Model{ID: book.ID}, Title: "physics"}
+			err := books.Create(&duplicate)
+			assert.True(t, errors.Is(err, ErrConflict), "got %v", err)
+
+			token := models.RefreshToken{UserID: 1, FamilyID: "family", TokenHash: "hash", ExpiresAt: time.Now()}
+			assert.NoError(t, db.Create(&token).Error)
+
+			token.ID = 0
+			err = translateError(db.Create(&token).Error, ErrNotFound)
+			assert.True(t, errors.Is(err, ErrConflict), "got %v", err)
+		})
+	}
+}
+
+func TestRepositoriesNotFound(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			users := NewGormUserRepository(db)
+
+			_, err := books.GetByID(42)
+			assert.Equal(t, ErrBookNotFound, err)
+			assert.Equal(t, ErrBookNotFound, books.Delete(42))
+
+			_, err = users.GetByEmail("nobody@example.com")
+			assert.Equal(t, ErrUserNotFound, err)
+			_, err = users.UpdateRole(42, models.RoleAdmin)
+			assert.Equal(t, ErrUserNotFound, err)
+		})
+	}
+}
+
+func TestUpdateIgnoresProtectedFields(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			users := NewGormUserRepository(db)
+
+			book := models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")}
+			assert.NoError(t, books.Create(&book))
+			user := models.User{Name: "alta", Email: "alta@gmail.com", Password: "hash", Role: models.RoleMember}
+			assert.NoError(t, users.Create(&user))
+
+			past := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			protected := gorm.Model{ID: 99, CreatedAt: past, DeletedAt: gorm.DeletedAt{Time: past, Valid: true}}
+
+			_, err := books.Update(book.ID, models.Book{Model: protected, Title: "physics"})
+			assert.NoError(t, err)
+			_, err = users.Update(user.ID, models.User{Model: protected, Name: "alta baru", Role: models.RoleAdmin})
+			assert.NoError(t, err)
+
+			storedBook, err := books.GetByID(book.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "physics", storedBook.Title)
+				assert.Equal(t, "urnik", storedBook.Author)
+				assert.True(t, storedBook.CreatedAt.After(past))
+				assert.False(t, storedBook.DeletedAt.Valid)
+			}
+			_, err = books.GetByID(99)
+			assert.True(t, errors.Is(err, ErrNotFound))
+
+			storedUser, err := users.GetByID(user.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "alta baru", storedUser.Name)
+				assert.Equal(t, "hash", storedUser.Password)
+				assert.Equal(t, models.RoleMember, storedUser.Role)
+				assert.True(t, storedUser.CreatedAt.After(past))
+				assert.False(t, storedUser.DeletedAt.Valid)
+			}
+		})
+	}
+}
+
+func TestUserEmailIsUniqueIgnoringCase(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			users := NewGormUserRepository(db)
+
+			alta := models.User{Name: "alta", Email: " Alta@Gmail.com ", Password: "hash"}
+			assert.NoError(t, users.Create(&alta))
+			assert.Equal(t, "alta@gmail.com", alta.Email)
+
+			found, err := users.GetByEmail("ALTA@gmail.com")
+			if assert.NoError(t, err) {
+				assert.Equal(t, alta.ID, found.ID)
+			}
+
+			err = users.Create(&models.User{Name: "copy", Email: "alta@GMAIL.com", Password: "hash"})
+			assert.Equal(t, ErrEmailTaken, err)
+			assert.True(t, errors.Is(err, ErrConflict))
+
+			budi := models.User{Name: "budi", Email: "budi@gmail.com", Password: "hash"}
+			assert.NoError(t, users.Create(&budi))
+			_, err = users.Update(budi.ID, models.User{Email: "ALTA@gmail.com"})
+			assert.Equal(t, ErrEmailTaken, err)
+
+			_, err = users.Update(budi.ID, models.User{Email: "Budi.Baru@gmail.com"})
+			assert.NoError(t, err)
+			found, err = users.GetByID(budi.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "budi.baru@gmail.com", found.Email)
+			}
+		})
+	}
+}
+
+func TestDeletedUserEmailCanRegisterAgain(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			users := NewGormUserRepository(db)
+
+			alta := models.User{Name: "alta", Email: "alta@gmail.com", Password: "hash"}
+			assert.NoError(t, users.Create(&alta))
+			assert.NoError(t, users.Delete(alta.ID))
+			assert.Equal(t, ErrUserNotFound, users.Delete(alta.ID))
+
+			again := models.User{Name: "alta", Email: "Alta@gmail.com", Password: "hash"}
+			assert.NoError(t, users.Create(&again))
+			assert.NotEqual(t, alta.ID, again.ID)
+
+			found, err := users.GetByEmail("alta@gmail.com")
+			if assert.NoError(t, err) {
+				assert.Equal(t, again.ID, found.ID)
+			}
+
+			deleted := models.User{}
+			assert.NoError(t, db.Unscoped().First(&deleted, alta.ID).Error)
+			assert.Equal(t, models.ReleasedEmail(alta.ID, "alta@gmail.com"), deleted.Email)
+		})
+	}
+}
+
+func insertBooksForList(t *testing.T, books *GormBookRepository) {
+	for _, book := range []models.Book{
+		{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2019-05-01")},
+		{Title: "physics", Author: "newton", Published_at: models.MustParseDate("1687-07-05")},
+		{Title: "biology", Author: "Urnik Baru", Published_at: models.MustParseDate("2021-01-01")},
+		{Title: "100% math", Author: "alta", Published_at: models.MustParseDate("2020-02-02")},
+		{Title: "algebra", Author: "alta", Published_at: models.MustParseDate("2020-02-02")},
+	} {
+		book := book
+		if err := books.Create(&book); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func bookTitles(books []models.Book) []string {
+	titles := []string{}
+	for _, book := range books {
+		titles = append(titles, book.Title)
+	}
+	return titles
+}
+
+func TestListBooksByPage(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			insertBooksForList(t, books)
+
+			found, page, err := books.List(BookFilter{}, ListOptions{Limit: 2, Page: 2, Sort: "title"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"biology", "chemistry"}, bookTitles(found))
+				assert.Equal(t, Page{Limit: 2, Page: 2, Total: 5, HasMore: true, NextCursor: page.NextCursor}, page)
+			}
+
+			found, page, err = books.List(BookFilter{}, ListOptions{Limit: 2, Page: 3, Sort: "title"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"physics"}, bookTitles(found))
+				assert.False(t, page.HasMore)
+				assert.Empty(t, page.NextCursor)
+			}
+
+			found, page, err = books.List(BookFilter{}, ListOptions{Limit: 1000})
+			if assert.NoError(t, err) {
+				assert.Len(t, found, 5)
+				assert.Equal(t, MaxPageSize, page.Limit)
+			}
+		})
+	}
+}
+
+func TestListBooksByCursor(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			insertBooksForList(t, books)
+
+			titles := []string{}
+			opts := ListOptions{Limit: 2, Sort: "-published_at,title"}
+			for i := 0; i < 5; i++ {
+				found, page, err := books.List(BookFilter{}, opts)
+				if !assert.NoError(t, err) {
+					return
+				}
+				titles = append(titles, bookTitles(found)...)
+				if !page.HasMore {
+					break
+				}
+
+				// rows inserted behind the cursor do not shift later pages
+				assert.NoError(t, books.Create(&models.Book{Title: "zoology", Published_at: models.MustParseDate("2022-01-01")}))
+				opts.Cursor = page.NextCursor
+			}
+
+			assert.Equal(t, []string{"biology", "100% math", "algebra", "chemistry", "physics"}, titles)
+		})
+	}
+}
+
+func TestListBooksInvalidCursor(t *testing.T) {
+	books := newTestBookRepository(t, config.InitDbTest())
+	insertBooksForList(t, books)
+
+	_, page, err := books.List(BookFilter{}, ListOptions{Limit: 1, Sort: "title"})
+	assert.NoError(t, err)
+
+	for _, opts := range []ListOptions{
+		{Cursor: "not a cursor", Sort: "title"},
+		{Cursor: "e30", Sort: "title"},
+		{Cursor: page.NextCursor, Sort: "-title"},
+	} {
+		_, _, err := books.List(BookFilter{}, opts)
+		assert.Equal(t, ErrInvalidCursor, err, opts.Cursor)
+	}
+
+	_, _, err = books.List(BookFilter{}, ListOptions{Sort: "password"})
+	assert.Equal(t, ErrInvalidSort, err)
+}
+
+func TestListBooksFilter(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			insertBooksForList(t, books)
+
+			testCases := []struct {
+				name     string
+				filter   BookFilter
+				expected []string
+			}{
+				{"author ignores case", BookFilter{Author: "URNIK"}, []string{"chemistry", "biology"}},
+				{"title wildcard is literal", BookFilter{Title: "100%"}, []string{"100% math"}},
+				{"title underscore is literal", BookFilter{Title: "_"}, []string{}},
+				{"published after", BookFilter{PublishedAfter: "2020-02-02"}, []string{"biology"}},
+				{"published between", BookFilter{PublishedAfter: "1700-01-01", PublishedBefore: "2020-02-02"}, []string{"chemistry"}},
+			}
+
+			for _, testCase := range testCases {
+				found, page, err := books.List(testCase.filter, ListOptions{})
+				if assert.NoError(t, err, testCase.name) {
+					assert.Equal(t, testCase.expected, bookTitles(found), testCase.name)
+					assert.Equal(t, int64(len(testCase.expected)), page.Total, testCase.name)
+				}
+			}
+		})
+	}
+}
+
+func TestListUsersFilter(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			users := NewGormUserRepository(db)
+			for _, user := range []models.User{
+				{Name: "alta", Email: "alta@gmail.com", Role: models.RoleAdmin},
+				{Name: "budi", Email: "budi@gmail.com", Role: models.RoleMember},
+				{Name: "Alta Baru", Email: "baru@gmail.com", Role: models.RoleMember},
+			} {
+				user := user
+				assert.NoError(t, users.Create(&user))
+			}
+
+			found, page, err := users.List(UserFilter{Name: "alta", Role: models.RoleMember}, ListOptions{})
+			if assert.NoError(t, err) && assert.Len(t, found, 1) {
+				assert.Equal(t, "baru@gmail.com", found[0].Email)
+				assert.Equal(t, int64(1), page.Total)
+			}
+
+			found, _, err = users.List(UserFilter{Email: "BUDI@gmail.com"}, ListOptions{Sort: "-name"})
+			if assert.NoError(t, err) && assert.Len(t, found, 1) {
+				assert.Equal(t, "budi", found[0].Name)
+			}
+		})
+	}
+}
+
+func TestSearchBooks(t *testing.T) {
+	books := newTestBookRepository(t, config.InitDbTest())
+	insertBooksForList(t, books)
+
+	testCases := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"title", "chemistry", []string{"chemistry"}},
+		{"author", "newton", []string{"physics"}},
+		{"typo", "chemestry", []string{"chemistry"}},
+		{"title ranks above author", "urnik biology", []string{"biology", "chemistry"}},
+		{"no match", "astronomy", []string{}},
+	}
+
+	for _, testCase := range testCases {
+		matches, page, err := books.Search(testCase.text, ListOptions{})
+		if assert.NoError(t, err, testCase.name) {
+			titles := []string{}
+			for _, match := range matches {
+				titles = append(titles, match.Book.Title)
+			}
+			assert.Equal(t, testCase.expected, titles, testCase.name)
+			assert.Equal(t, int64(len(testCase.expected)), page.Total, testCase.name)
+		}
+	}
+
+	matches, _, err := books.Search("newton", ListOptions{})
+	if assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, []string{"<mark>newton</mark>"}, matches[0].Highlights["author"])
+		if assert.Len(t, matches[0].Book.Authors, 1) {
+			assert.Equal(t, "newton", matches[0].Book.Authors[0].Name)
+		}
+	}
+}
+
+func TestSearchFollowsWrites(t *testing.T) {
+	books := newTestBookRepository(t, config.InitDbTest())
+
+	book := models.Book{Title: "chemistry", Author: "urnik"}
+	assert.NoError(t, books.Create(&book))
+
+	_, err := books.Update(book.ID, models.Book{Title: "organic physics"})
+	assert.NoError(t, err)
+
+	matches, _, err := books.Search("chemistry", ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	matches, _, err = books.Search("organic", ListOptions{})
+	if assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "organic physics", matches[0].Book.Title)
+	}
+
+	assert.NoError(t, books.Delete(book.ID))
+	matches, page, err := books.Search("organic", ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, int64(0), page.Total)
+}
+
+// failingIndex rejects every write.
+type failingIndex struct {
+	search.BookIndex
+}
+
+func (failingIndex) Index(book models.Book) error {
+	return errors.New("index unavailable")
+}
+
+func TestBookWriteRolledBackWhenIndexFails(t *testing.T) {
+	db := config.InitDbTest()
+	books := NewGormBookRepository(db, failingIndex{})
+
+	assert.Error(t, books.Create(&models.Book{Title: "chemistry"}))
+
+	var count int64
+	db.Model(&models.Book{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestBookDetailsAndTags(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+
+			isbn := "9780306406157"
+			book := models.Book{
+				Title:        "physics",
+				Author:       "newton",
+				Published_at: models.MustParseDate("1687-07-05"),
+				ISBN:         &isbn,
+				Description:  "laws of motion",
+				Language:     "la",
+				Pages:        510,
+				Publisher:    "royal society",
+				CoverURL:     "https://example.com/principia.jpg",
+				Tags:         []models.Tag{{Name: "science"}, {Name: "classics"}},
+			}
+			assert.NoError(t, books.Create(&book))
+			assert.Equal(t, []string{"classics", "science"}, models.TagNames(book.Tags))
+
+			stored, err := books.GetByID(book.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, book.Published_at, stored.Published_at)
+				assert.Equal(t, isbn, *stored.ISBN)
+				assert.Equal(t, 510, stored.Pages)
+				assert.Equal(t, "https://example.com/principia.jpg", stored.CoverURL)
+				assert.Equal(t, []string{"classics", "science"}, models.TagNames(stored.Tags))
+			}
+
+			// tags are shared between books, not duplicated
+			other := models.Book{Title: "chemistry", Tags: []models.Tag{{Name: "science"}}}
+			assert.NoError(t, books.Create(&other))
+			var tagCount int64
+			db.Model(&models.Tag{}).Count(&tagCount)
+			assert.Equal(t, int64(2), tagCount)
+
+			updated, err := books.Update(book.ID, models.Book{Pages: 512, Tags: []models.Tag{{Name: "history"}}})
+			if assert.NoError(t, err) {
+				assert.Equal(t, 512, updated.Pages)
+				assert.Equal(t, "laws of motion", updated.Description)
+				assert.Equal(t, []string{"history"}, models.TagNames(updated.Tags))
+			}
+
+			updated, err = books.Update(book.ID, models.Book{Title: "principia"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"history"}, models.TagNames(updated.Tags))
+			}
+
+			updated, err = books.Update(book.ID, models.Book{Tags: []models.Tag{}})
+			if assert.NoError(t, err) {
+				assert.Empty(t, updated.Tags)
+			}
+
+			found, _, err := books.List(BookFilter{}, ListOptions{Sort: "published_at"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"chemistry", "principia"}, bookTitles(found))
+				assert.Equal(t, []string{"science"}, models.TagNames(found[0].Tags))
+			}
+		})
+	}
+}
+
+func TestBookISBNIsUnique(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+
+			isbn := "9780306406157"
+			assert.NoError(t, books.Create(&models.Book{Title: "physics", ISBN: &isbn}))
+			assert.NoError(t, books.Create(&models.Book{Title: "no isbn"}))
+			assert.NoError(t, books.Create(&models.Book{Title: "no isbn either"}))
+
+			err := books.Create(&models.Book{Title: "copy", ISBN: &isbn})
+			assert.Equal(t, ErrISBNTaken, err)
+			assert.True(t, errors.Is(err, ErrConflict))
+
+			chemistry := models.Book{Title: "chemistry"}
+			assert.NoError(t, books.Create(&chemistry))
+			_, err = books.Update(chemistry.ID, models.Book{ISBN: &isbn})
+			assert.Equal(t, ErrISBNTaken, err)
+		})
+	}
+}
+
+func TestDeletedBookISBNCanBeCataloguedAgain(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+
+			isbn := "9780306406157"
+			physics := models.Book{Title: "physics", ISBN: &isbn}
+			assert.NoError(t, books.Create(&physics))
+			untitled := models.Book{Title: "no isbn"}
+			assert.NoError(t, books.Create(&untitled))
+
+			assert.NoError(t, books.Delete(physics.ID))
+			assert.NoError(t, books.Delete(untitled.ID))
+			assert.Equal(t, ErrBookNotFound, books.Delete(physics.ID))
+			_, err := books.GetByID(physics.ID)
+			assert.Equal(t, ErrBookNotFound, err)
+
+			again := models.Book{Title: "physics", ISBN: &isbn}
+			assert.NoError(t, books.Create(&again))
+			found, err := books.GetByID(again.ID)
+			if assert.NoError(t, err) && assert.NotNil(t, found.ISBN) {
+				assert.Equal(t, isbn, *found.ISBN)
+			}
+		})
+	}
+}
+
+func TestListBooksByPublicationCursor(t *testing.T) {
+	books := newTestBookRepository(t, config.InitDbTest())
+	insertBooksForList(t, books)
+	assert.NoError(t, books.Create(&models.Book{Title: "undated"}))
+
+	titles := []string{}
+	opts := ListOptions{Limit: 2, Sort: "published_at"}
+	for {
+		found, page, err := books.List(BookFilter{}, opts)
+		if !assert.NoError(t, err) {
+			return
+		}
+		titles = append(titles, bookTitles(found)...)
+		if !page.HasMore {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	assert.Equal(t, []string{"undated", "physics", "chemistry", "100% math", "algebra", "biology"}, titles)
+}
+
+func authorNames(authors []models.Author) []string {
+	names := []string{}
+	for _, author := range authors {
+		names = append(names, author.Name)
+	}
+	return names
+}
+
+func TestBookAuthors(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			authors := NewGormAuthorRepository(db, books.index)
+
+			sussman := models.Author{Name: "Gerald Jay Sussman"}
+			assert.NoError(t, authors.Create(&sussman))
+
+			sicp := models.Book{Title: "sicp", Author: "Harold Abelson and gerald jay sussman"}
+			assert.NoError(t, books.Create(&sicp))
+			assert.Equal(t, []string{"Harold Abelson", "Gerald Jay Sussman"}, authorNames(sicp.Authors))
+			assert.Equal(t, sussman.ID, sicp.Authors[1].ID)
+			assert.Equal(t, "Harold Abelson, Gerald Jay Sussman", sicp.Author)
+
+			abelson := sicp.Authors[0]
+			scheme := models.Book{Title: "scheme", Authors: []models.Author{{Model: gorm.Model{ID: sussman.ID}}, {Model: gorm.Model{ID: abelson.ID}}}}
+			assert.NoError(t, books.Create(&scheme))
+
+			found, err := books.GetByID(scheme.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"Gerald Jay Sussman", "Harold Abelson"}, authorNames(found.Authors))
+				assert.Equal(t, "Gerald Jay Sussman, Harold Abelson", found.Author)
+			}
+
+			missing := models.Book{Title: "ghost", Authors: []models.Author{{Model: gorm.Model{ID: 999}}}}
+			assert.True(t, errors.Is(books.Create(&missing), ErrAuthorNotFound))
+
+			updated, err := books.Update(scheme.ID, models.Book{Authors: []models.Author{{Model: gorm.Model{ID: abelson.ID}}}})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"Harold Abelson"}, authorNames(updated.Authors))
+				assert.Equal(t, "Harold Abelson", updated.Author)
+			}
+
+			list, _, err := books.List(BookFilter{Author: "sussman"}, ListOptions{})
+			if assert.NoError(t, err) && assert.Len(t, list, 1) {
+				assert.Equal(t, "sicp", list[0].Title)
+				assert.Len(t, list[0].Authors, 2)
+			}
+
+			written, _, err := authors.Books(abelson.ID, ListOptions{Sort: "title"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"scheme", "sicp"}, bookTitles(written))
+			}
+		})
+	}
+}
+
+func TestAuthorRenameRewritesBylines(t *testing.T) {
+	books := newTestBookRepository(t, config.InitDbTest())
+	authors := NewGormAuthorRepository(books.db, books.index)
+
+	book := models.Book{Title: "the c programming language", Author: "Kernighan & Ritchie"}
+	assert.NoError(t, books.Create(&book))
+
+	renamed, err := authors.Update(book.Authors[1].ID, models.Author{Name: "Dennis Ritchie", Bio: "wrote c"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Dennis Ritchie", renamed.Name)
+		assert.Equal(t, "wrote c", renamed.Bio)
+	}
+
+	found, err := books.GetByID(book.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Kernighan, Dennis Ritchie", found.Author)
+	}
+
+	matches, _, err := books.Search("dennis", ListOptions{})
+	if assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, book.ID, matches[0].Book.ID)
+	}
+}
+
+func TestAuthorRepository(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			authors := NewGormAuthorRepository(db, books.index)
+
+			for _, name := range []string{"urnik", "alta", "budi"} {
+				assert.NoError(t, authors.Create(&models.Author{Name: name}))
+			}
+
+			list, page, err := authors.List(AuthorFilter{}, ListOptions{Limit: 2})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"alta", "budi"}, authorNames(list))
+				assert.Equal(t, int64(3), page.Total)
+			}
+			list, _, err = authors.List(AuthorFilter{Name: "RN"}, ListOptions{})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"urnik"}, authorNames(list))
+			}
+
+			book := models.Book{Title: "chemistry", Author: "urnik"}
+			assert.NoError(t, books.Create(&book))
+			urnik := book.Authors[0]
+			assert.Equal(t, list[0].ID, urnik.ID)
+
+			assert.True(t, errors.Is(authors.Delete(urnik.ID), ErrAuthorHasBooks))
+			assert.NoError(t, books.Delete(book.ID))
+			assert.NoError(t, authors.Delete(urnik.ID))
+
+			_, err = authors.GetByID(urnik.ID)
+			assert.True(t, errors.Is(err, ErrAuthorNotFound))
+			assert.True(t, errors.Is(authors.Delete(urnik.ID), ErrAuthorNotFound))
+			_, _, err = authors.Books(urnik.ID, ListOptions{})
+			assert.True(t, errors.Is(err, ErrAuthorNotFound))
+		})
+	}
+}
+
+func TestCheckoutAndReturn(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			loans := NewGormLoanRepository(db, LoanPolicy{Period: 14 * 24 * time.Hour, Limit: 5, HoldPeriod: time.Hour})
+
+			users := []models.User{{Email: "alta@gmail.com"}, {Email: "budi@gmail.com"}, {Email: "cici@gmail.com"}}
+			assert.NoError(t, db.Create(&users).Error)
+			book := models.Book{Title: "chemistry"}
+			assert.NoError(t, books.Create(&book))
+			addCopies(t, db, book.ID, 2)
+
+			loan, err := loans.Checkout(users[0].ID, book.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "chemistry", loan.Book.Title)
+				assert.Nil(t, loan.ReturnedAt)
+				assert.WithinDuration(t, loan.BorrowedAt.Add(14*24*time.Hour), loan.DueAt, time.Second)
+				assert.Equal(t, models.LoanActive, loan.Status(time.Now()))
+			}
+
+			_, err = loans.Checkout(users[0].ID, book.ID)
+			assert.True(t, errors.Is(err, ErrAlreadyBorrowed))
+			_, err = loans.Checkout(users[1].ID, book.ID)
+			assert.NoError(t, err)
+			_, err = loans.Checkout(users[2].ID, book.ID)
+			assert.True(t, errors.Is(err, ErrNoCopyAvailable))
+
+			found, err := books.GetByID(book.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 0, found.AvailableCopies)
+			}
+
+			active, _, err := loans.ListActive(users[0].ID, ListOptions{})
+			if assert.NoError(t, err) && assert.Len(t, active, 1) {
+				assert.Equal(t, loan.ID, active[0].ID)
+			}
+
+			returned, err := loans.Return(loan.ID)
+			if assert.NoError(t, err) && assert.NotNil(t, returned.ReturnedAt) {
+				assert.Equal(t, models.LoanReturned, returned.Status(time.Now()))
+			}
+			_, err = loans.Return(loan.ID)
+			assert.True(t, errors.Is(err, ErrLoanReturned))
+
+			active, _, err = loans.ListActive(users[0].ID, ListOptions{})
+			if assert.NoError(t, err) {
+				assert.Empty(t, active)
+			}
+			_, err = loans.Checkout(users[2].ID, book.ID)
+			assert.NoError(t, err)
+
+			_, err = loans.Checkout(users[0].ID, 999)
+			assert.True(t, errors.Is(err, ErrBookNotFound))
+			_, err = loans.Checkout(999, book.ID)
+			assert.True(t, errors.Is(err, ErrUserNotFound))
+			_, err = loans.Return(999)
+			assert.True(t, errors.Is(err, ErrLoanNotFound))
+		})
+	}
+}
+
+func TestCheckoutLimit(t *testing.T) {
+	db := config.InitDbTest()
+	books := newTestBookRepository(t, db)
+	loans := NewGormLoanRepository(db, LoanPolicy{Period: time.Hour, Limit: 2, HoldPeriod: time.Hour})
+
+	user := models.User{Email: "alta@gmail.com"}
+	assert.NoError(t, db.Create(&user).Error)
+	for _, title := range []string{"biology", "chemistry", "physics"} {
+		book := models.Book{Title: title}
+		assert.NoError(t, books.Create(&book))
+		addCopies(t, db, book.ID, 1)
+	}
+
+	for id := uint(1); id <= 2; id++ {
+		_, err := loans.Checkout(user.ID, id)
+		assert.NoError(t, err)
+	}
+	_, err := loans.Checkout(user.ID, 3)
+	assert.True(t, errors.Is(err, ErrLoanLimitReached))
+
+	found, err := books.GetByID(3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, found.AvailableCopies)
+	}
+}
+
+func TestConcurrentCheckoutsNeverOverlend(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			loans := NewGormLoanRepository(db, LoanPolicy{Period: time.Hour, Limit: 5, HoldPeriod: time.Hour})
+
+			book := models.Book{Title: "chemistry"}
+			assert.NoError(t, books.Create(&book))
+			addCopies(t, db, book.ID, 3)
+			users := make([]models.User, 10)
+			for i := range users {
+				users[i].Email = fmt.Sprintf("user%d@gmail.com", i)
+			}
+			assert.NoError(t, db.Create(&users).Error)
+
+			var wg sync.WaitGroup
+			var lent int64
+			for _, user := range users {
+				wg.Add(1)
+				go func(userId uint) {
+					defer wg.Done()
+					if _, err := loans.Checkout(userId, book.ID); err == nil {
+						atomic.AddInt64(&lent, 1)
+					}
+				}(user.ID)
+			}
+			wg.Wait()
+
+			found, err := books.GetByID(book.ID)
+			if assert.NoError(t, err) {
+				assert.LessOrEqual(t, lent, int64(3))
+				assert.Equal(t, 3-int(lent), found.AvailableCopies)
+			}
+			var stored int64
+			assert.NoError(t, db.Model(&models.Loan{}).Count(&stored).Error)
+			assert.Equal(t, lent, stored)
+		})
+	}
+}
+
+// addCopies adds count copies on the shelf to a book.
+func addCopies(t *testing.T, db *gorm.DB, bookId uint, count int) {
+	t.Helper()
+
+	copies := NewGormBookCopyRepository(db, time.Hour)
+	for i := 1; i <= count; i++ {
+		bookCopy := models.BookCopy{BookID: bookId, Barcode: fmt.Sprintf("B%d-%d", bookId, i), Status: models.CopyAvailable}
+		if err := copies.Create(&bookCopy); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBookCopies(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			copies := NewGormBookCopyRepository(db, time.Hour)
+			loans := NewGormLoanRepository(db, LoanPolicy{Period: time.Hour, Limit: 5, HoldPeriod: time.Hour})
+
+			counts := func(bookId uint) (int, int) {
+				t.Helper()
+				found, err := books.GetByID(bookId)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return found.Copies, found.AvailableCopies
+			}
+
+			user := models.User{Email: "alta@gmail.com"}
+			assert.NoError(t, db.Create(&user).Error)
+			book := models.Book{Title: "chemistry", Copies: 7}
+			assert.NoError(t, books.Create(&book))
+			total, available := counts(book.ID)
+			assert.Equal(t, []int{0, 0}, []int{total, available})
+
+			shelved := models.BookCopy{BookID: book.ID, Barcode: " ab-1 ", ShelfLocation: "S1", Condition: models.ConditionGood, Status: models.CopyAvailable}
+			assert.NoError(t, copies.Create(&shelved))
+			assert.Equal(t, "AB-1", shelved.Barcode)
+			repaired := models.BookCopy{BookID: book.ID, Barcode: "AB-2", Condition: models.ConditionPoor, Status: models.CopyRepair}
+			assert.NoError(t, copies.Create(&repaired))
+			total, available = counts(book.ID)
+			assert.Equal(t, []int{2, 1}, []int{total, available})
+
+			err := copies.Create(&models.BookCopy{BookID: book.ID, Barcode: "ab-1", Status: models.CopyAvailable})
+			assert.True(t, errors.Is(err, ErrBarcodeTaken), "got %v", err)
+			assert.True(t, errors.Is(copies.Create(&models.BookCopy{BookID: 999, Barcode: "AB-3"}), ErrBookNotFound))
+
+			// the loan takes the only copy on the shelf
+			loan, err := loans.Checkout(user.ID, book.ID)
+			if assert.NoError(t, err) && assert.NotNil(t, loan.CopyID) {
+				assert.Equal(t, shelved.ID, *loan.CopyID)
+			}
+			lent, err := copies.GetByID(book.ID, shelved.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, models.CopyOnLoan, lent.Status)
+			}
+			_, err = copies.Update(book.ID, shelved.ID, models.BookCopy{Status: models.CopyLost})
+			assert.True(t, errors.Is(err, ErrCopyOnLoan))
+			assert.True(t, errors.Is(copies.Delete(book.ID, shelved.ID), ErrCopyOnLoan))
+
+			// the repaired copy goes back on the shelf
+			updated, err := copies.Update(book.ID, repaired.ID, models.BookCopy{Condition: models.ConditionGood, Status: models.CopyAvailable})
+			if assert.NoError(t, err) {
+				assert.Equal(t, models.ConditionGood, updated.Condition)
+				assert.Equal(t, models.CopyAvailable, updated.Status)
+			}
+			total, available = counts(book.ID)
+			assert.Equal(t, []int{2, 1}, []int{total, available})
+
+			_, err = loans.Return(loan.ID)
+			assert.NoError(t, err)
+			returned, err := copies.GetByID(book.ID, shelved.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, models.CopyAvailable, returned.Status)
+			}
+			total, available = counts(book.ID)
+			assert.Equal(t, []int{2, 2}, []int{total, available})
+
+			onShelf, _, err := copies.List(book.ID, BookCopyFilter{Status: models.CopyAvailable}, ListOptions{Sort: "-barcode"})
+			if assert.NoError(t, err) && assert.Len(t, onShelf, 2) {
+				assert.Equal(t, "AB-2", onShelf[0].Barcode)
+			}
+
+			_, err = copies.Update(book.ID, shelved.ID, models.BookCopy{Status: models.CopyLost})
+			assert.NoError(t, err)
+			assert.NoError(t, copies.Delete(book.ID, shelved.ID))
+			total, available = counts(book.ID)
+			assert.Equal(t, []int{1, 1}, []int{total, available})
+
+			_, err = copies.GetByID(book.ID, shelved.ID)
+			assert.True(t, errors.Is(err, ErrCopyNotFound))
+			_, err = copies.GetByID(999, repaired.ID)
+			assert.True(t, errors.Is(err, ErrCopyNotFound))
+			_, _, err = copies.List(999, BookCopyFilter{}, ListOptions{})
+			assert.True(t, errors.Is(err, ErrBookNotFound))
+		})
+	}
+}
+
+func TestHeldCopyStaysOnShelf(t *testing.T) {
+	db := config.InitDbTest()
+	books := newTestBookRepository(t, db)
+	copies := NewGormBookCopyRepository(db, time.Hour)
+	loans := NewGormLoanRepository(db, LoanPolicy{Period: time.Hour, Limit: 5, HoldPeriod: time.Hour})
+	reservations := NewGormReservationRepository(db, time.Hour)
+
+	users := []models.User{{Email: "alta@gmail.com"}, {Email: "budi@gmail.com"}}
+	assert.NoError(t, db.Create(&users).Error)
+	book := models.Book{Title: "chemistry"}
+	assert.NoError(t, books.Create(&book))
+
+	// a book without copies can only be reserved, and its first copy is
+	// held for the reservation
+	_, err := loans.Checkout(users[0].ID, book.ID)
+	assert.True(t, errors.Is(err, ErrNoCopyAvailable))
+	_, err = reservations.Reserve(users[0].ID, book.ID)
+	assert.NoError(t, err)
+	addCopies(t, db, book.ID, 1)
+	assert.Equal(t, []string{models.ReservationReady}, reservationStatuses(t, db))
+
+	held, _, err := copies.List(book.ID, BookCopyFilter{}, ListOptions{})
+	if assert.NoError(t, err) && assert.Len(t, held, 1) {
+		_, err = copies.Update(book.ID, held[0].ID, models.BookCopy{Status: models.CopyRepair})
+		assert.True(t, errors.Is(err, ErrCopyHeld))
+		assert.True(t, errors.Is(copies.Delete(book.ID, held[0].ID), ErrCopyHeld))
+	}
+	_, err = loans.Checkout(users[1].ID, book.ID)
+	assert.True(t, errors.Is(err, ErrNoCopyAvailable))
+	_, err = loans.Checkout(users[0].ID, book.ID)
+	assert.NoError(t, err)
+}
+
+func reservationStatuses(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var statuses []string
+	if err := db.Model(&models.Reservation{}).Order("id").Pluck("status", &statuses).Error; err != nil {
+		t.Fatal(err)
+	}
+	return statuses
+}
+
+func TestReservationQueue(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			loans := NewGormLoanRepository(db, LoanPolicy{Period: time.Hour, Limit: 5, HoldPeriod: time.Hour})
+			reservations := NewGormReservationRepository(db, time.Hour)
+
+			users := []models.User{{Email: "alta@gmail.com"}, {Email: "budi@gmail.com"}, {Email: "cici@gmail.com"}}
+			assert.NoError(t, db.Create(&users).Error)
+			alta, budi, cici := users[0].ID, users[1].ID, users[2].ID
+			book := models.Book{Title: "chemistry"}
+			assert.NoError(t, books.Create(&book))
+			addCopies(t, db, book.ID, 1)
+
+			_, err := reservations.Reserve(budi, book.ID)
+			assert.True(t, errors.Is(err, ErrCopyAvailable))
+
+			loan, err := loans.Checkout(alta, book.ID)
+			assert.NoError(t, err)
+
+			first, err := reservations.Reserve(budi, book.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, models.ReservationWaiting, first.Status)
+				assert.Equal(t, 1, first.Position)
+				assert.Equal(t, "chemistry", first.Book.Title)
+			}
+			second, err := reservations.Reserve(cici, book.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 2, second.Position)
+			}
+			_, err = reservations.Reserve(budi, book.ID)
+			assert.True(t, errors.Is(err, ErrAlreadyReserved))
+			_, err = reservations.Reserve(alta, book.ID)
+			assert.True(t, errors.Is(err, ErrAlreadyBorrowed))
+			_, err = reservations.Reserve(alta, 999)
+			assert.True(t, errors.Is(err, ErrBookNotFound))
+
+			// the returned copy is held for budi, first in line
+			_, err = loans.Return(loan.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{models.ReservationReady, models.ReservationWaiting}, reservationStatuses(t, db))
+			_, err = loans.Checkout(cici, book.ID)
+			assert.True(t, errors.Is(err, ErrNoCopyAvailable))
+
+			held, _, err := reservations.ListByUser(budi, ReservationFilter{Status: models.ReservationReady}, ListOptions{})
+			if assert.NoError(t, err) && assert.Len(t, held, 1) {
+				assert.NotNil(t, held[0].ReadyAt)
+				assert.WithinDuration(t, held[0].ReadyAt.Add(time.Hour), *held[0].ExpiresAt, time.Second)
+			}
+			waiting, _, err := reservations.ListByUser(cici, ReservationFilter{}, ListOptions{})
+			if assert.NoError(t, err) && assert.Len(t, waiting, 1) {
+				assert.Equal(t, 1, waiting[0].Position)
+			}
+
+			loan, err = loans.Checkout(budi, book.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{models.ReservationFulfilled, models.ReservationWaiting}, reservationStatuses(t, db))
+
+			// cici's hold lapses and the copy goes back on the shelf
+			_, err = loans.Return(loan.ID)
+			assert.NoError(t, err)
+			assert.NoError(t, db.Model(&models.Reservation{}).Where("id = ?", second.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+			assert.NoError(t, reservations.ExpireHolds())
+			assert.Equal(t, []string{models.ReservationFulfilled, models.ReservationExpired}, reservationStatuses(t, db))
+
+			found, err := books.GetByID(book.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1, found.AvailableCopies)
+			}
+		})
+	}
+}
+
+func TestLapsedHoldPassesToNextInLine(t *testing.T) {
+	db := config.InitDbTest()
+	books := newTestBookRepository(t, db)
+	loans := NewGormLoanRepository(db, LoanPolicy{Period: time.Hour, Limit: 5, HoldPeriod: time.Hour})
+	reservations := NewGormReservationRepository(db, time.Hour)
+
+	users := []models.User{{Email: "alta@gmail.com"}, {Email: "budi@gmail.com"}, {Email: "cici@gmail.com"}}
+	assert.NoError(t, db.Create(&users).Error)
+	book := models.Book{Title: "chemistry"}
+	assert.NoError(t, books.Create(&book))
+	addCopies(t, db, book.ID, 1)
+
+	loan, err := loans.Checkout(users[0].ID, book.ID)
+	assert.NoError(t, err)
+	for _, user := range users[1:] {
+		_, err := reservations.Reserve(user.ID, book.ID)
+		assert.NoError(t, err)
+	}
+	_, err = loans.Return(loan.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Model(&models.Reservation{}).Where("user_id = ?", users[1].ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	// the lapse is noticed by the checkout of the next in line
+	_, err = loans.Checkout(users[2].ID, book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{models.ReservationExpired, models.ReservationFulfilled}, reservationStatuses(t, db))
+
+	found, err := books.GetByID(book.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, found.AvailableCopies)
+	}
+}
+
+func TestFineLedger(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			policy := FinePolicy{
+				Fees:  models.FeeSchedule{PerDay: decimal.RequireFromString("0.25"), Cap: decimal.RequireFromString("1.00")},
+				Limit: decimal.RequireFromString("0.60"),
+			}
+			loans := NewGormLoanRepository(db, LoanPolicy{Period: 14 * 24 * time.Hour, Limit: 5, HoldPeriod: time.Hour, Fines: policy})
+			fines := NewGormFineRepository(db, policy)
+
+			users := []models.User{{Email: "alta@gmail.com"}, {Email: "budi@gmail.com", Role: models.RoleLibrarian}}
+			assert.NoError(t, db.Create(&users).Error)
+			alta, librarian := users[0].ID, users[1].ID
+			for _, title := range []string{"chemistry", "physics"} {
+				book := models.Book{Title: title}
+				assert.NoError(t, books.Create(&book))
+				addCopies(t, db, book.ID, 1)
+			}
+
+			balance := func() models.FineBalance {
+				t.Helper()
+				balance, err := fines.Balance(alta)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return balance
+			}
+
+			// three days overdue accrue 0.75, over the limit
+			loan, err := loans.Checkout(alta, 1)
+			assert.NoError(t, err)
+			assert.NoError(t, db.Model(&loan).Update("due_at", time.Now().Add(-3*24*time.Hour+time.Minute)).Error)
+			assert.Equal(t, "0.75", balance().Accruing.StringFixed(2))
+			assert.True(t, balance().Blocked())
+			_, err = loans.Checkout(alta, 2)
+			assert.True(t, errors.Is(err, ErrFinesOwed), "got %v", err)
+
+			// returning charges the accrued fee
+			_, err = loans.Return(loan.ID)
+			assert.NoError(t, err)
+			owed := balance()
+			assert.Equal(t, "0.75", owed.Balance.StringFixed(2))
+			assert.True(t, owed.Accruing.IsZero())
+
+			overpaid := models.FineEntry{UserID: alta, Kind: models.FinePayment, Amount: decimal.RequireFromString("-1"), RecordedBy: &librarian}
+			assert.True(t, errors.Is(fines.Record(&overpaid), ErrAmountExceedsBalance))
+			payment := models.FineEntry{UserID: alta, Kind: models.FinePayment, Amount: decimal.RequireFromString("-0.50"), RecordedBy: &librarian}
+			assert.NoError(t, fines.Record(&payment))
+			assert.Equal(t, "0.25", balance().Balance.StringFixed(2))
+			assert.False(t, balance().Blocked())
+			_, err = loans.Checkout(alta, 2)
+			assert.NoError(t, err)
+
+			waiver := models.FineEntry{UserID: alta, LoanID: &loan.ID, Kind: models.FineWaiver, Amount: decimal.RequireFromString("-0.25"), Note: "first offence", RecordedBy: &librarian}
+			assert.NoError(t, fines.Record(&waiver))
+			assert.True(t, balance().Balance.IsZero())
+
+			entries, _, err := fines.List(alta, FineFilter{}, ListOptions{})
+			if assert.NoError(t, err) && assert.Len(t, entries, 3) {
+				assert.Equal(t, []string{models.FineWaiver, models.FinePayment, models.FineCharge}, []string{entries[0].Kind, entries[1].Kind, entries[2].Kind})
+				assert.Equal(t, librarian, *entries[0].RecordedBy)
+				assert.Nil(t, entries[2].RecordedBy)
+				assert.Equal(t, loan.ID, *entries[2].LoanID)
+			}
+			entries, _, err = fines.List(alta, FineFilter{Kind: models.FinePayment}, ListOptions{})
+			if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+				assert.Equal(t, "-0.50", entries[0].Amount.StringFixed(2))
+			}
+
+			foreign := models.FineEntry{UserID: librarian, LoanID: &loan.ID, Kind: models.FineCharge, Amount: decimal.RequireFromString("1")}
+			assert.True(t, errors.Is(fines.Record(&foreign), ErrLoanNotFound))
+			_, err = fines.Balance(999)
+			assert.True(t, errors.Is(err, ErrUserNotFound))
+			_, _, err = fines.List(999, FineFilter{}, ListOptions{})
+			assert.True(t, errors.Is(err, ErrUserNotFound))
+		})
+	}
+}
+
+func TestReviewsKeepBookRating(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			reviews := NewGormReviewRepository(db)
+
+			users := []models.User{{Name: "alta", Email: "alta@gmail.com"}, {Name: "budi", Email: "budi@gmail.com"}}
+			assert.NoError(t, db.Create(&users).Error)
+			alta, budi := users[0].ID, users[1].ID
+			book := models.Book{Title: "chemistry"}
+			assert.NoError(t, books.Create(&book))
+
+			rating := func() (int, float64) {
+				t.Helper()
+				stored, err := books.GetByID(book.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return stored.ReviewCount, stored.AverageRating()
+			}
+
+			first := models.Review{UserID: alta, BookID: book.ID, Rating: 5, Text: "clear and thorough"}
+			assert.NoError(t, reviews.Create(&first))
+			assert.Equal(t, "alta", first.User.Name)
+			second := models.Review{UserID: budi, BookID: book.ID, Rating: 2}
+			assert.NoError(t, reviews.Create(&second))
+			count, average := rating()
+			assert.Equal(t, 2, count)
+			assert.Equal(t, 3.5, average)
+
+			again := models.Review{UserID: alta, BookID: book.ID, Rating: 1}
+			assert.Equal(t, ErrAlreadyReviewed, reviews.Create(&again))
+			missing := models.Review{UserID: alta, BookID: 999, Rating: 1}
+			assert.Equal(t, ErrBookNotFound, reviews.Create(&missing))
+
+			updated, err := reviews.Update(second.ID, models.Review{Rating: 3})
+			if assert.NoError(t, err) {
+				assert.Equal(t, 3, updated.Rating)
+				assert.Equal(t, "budi", updated.User.Name)
+			}
+			updated, err = reviews.Update(second.ID, models.Review{Text: "too dense"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, 3, updated.Rating)
+				assert.Equal(t, "too dense", updated.Text)
+			}
+			count, average = rating()
+			assert.Equal(t, 2, count)
+			assert.Equal(t, 4.0, average)
+
+			listed, page, err := reviews.ListByBook(book.ID, ListOptions{Sort: "-rating"})
+			if assert.NoError(t, err) && assert.Len(t, listed, 2) {
+				assert.Equal(t, int64(2), page.Total)
+				assert.Equal(t, []uint{first.ID, second.ID}, []uint{listed[0].ID, listed[1].ID})
+			}
+			listed, page, err = reviews.ListByBook(book.ID, ListOptions{Limit: 1, Sort: "rating"})
+			if assert.NoError(t, err) && assert.Len(t, listed, 1) {
+				listed, _, err = reviews.ListByBook(book.ID, ListOptions{Limit: 1, Sort: "rating", Cursor: page.NextCursor})
+				if assert.NoError(t, err) && assert.Len(t, listed, 1) {
+					assert.Equal(t, first.ID, listed[0].ID)
+				}
+			}
+
+			assert.NoError(t, reviews.Delete(first.ID))
+			assert.Equal(t, ErrReviewNotFound, reviews.Delete(first.ID))
+			_, err = reviews.GetByID(first.ID)
+			assert.Equal(t, ErrReviewNotFound, err)
+			count, average = rating()
+			assert.Equal(t, 1, count)
+			assert.Equal(t, 3.0, average)
+
+			_, _, err = reviews.ListByBook(999, ListOptions{})
+			assert.Equal(t, ErrBookNotFound, err)
+		})
+	}
+}
+
+func TestReadingLists(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			books := newTestBookRepository(t, db)
+			lists := NewGormReadingListRepository(db)
+
+			users := []models.User{{Name: "alta", Email: "alta@gmail.com"}, {Name: "budi", Email: "budi@gmail.com"}}
+			assert.NoError(t, db.Create(&users).Error)
+			alta, budi := users[0].ID, users[1].ID
+			bookIds := []uint{}
+			for _, title := range []string{"chemistry", "physics", "biology"} {
+				book := models.Book{Title: title}
+				assert.NoError(t, books.Create(&book))
+				bookIds = append(bookIds, book.ID)
+			}
+
+			bookOrder := func(list models.ReadingList) []uint {
+				ids := []uint{}
+				for i, item := range list.Items {
+					assert.Equal(t, i+1, item.Position)
+					ids = append(ids, item.BookID)
+				}
+				assert.Equal(t, len(ids), list.BookCount)
+				return ids
+			}
+
+			reading := models.ReadingList{UserID: alta, Name: "Reading", Kind: models.ListReading}
+			assert.NoError(t, lists.Create(&reading))
+			assert.Regexp(t, `^reading-[0-9a-f]{8}$`, reading.Slug)
+			assert.Equal(t, "alta", reading.User.Name)
+			again := models.ReadingList{UserID: alta, Name: "Reading too", Kind: models.ListReading}
+			assert.Equal(t, ErrListKindTaken, lists.Create(&again))
+			other := models.ReadingList{UserID: budi, Name: "Reading", Kind: models.ListReading}
+			assert.NoError(t, lists.Create(&other))
+			assert.NotEqual(t, reading.Slug, other.Slug)
+			for _, name := range []string{"Summer", "Summer"} {
+				custom := models.ReadingList{UserID: alta, Name: name, Kind: models.ListCustom}
+				assert.NoError(t, lists.Create(&custom))
+			}
+
+			for _, bookId := range bookIds {
+				_, err := lists.AddBook(reading.ID, bookId)
+				assert.NoError(t, err)
+			}
+			_, err := lists.AddBook(reading.ID, bookIds[0])
+			assert.Equal(t, ErrAlreadyListed, err)
+			_, err = lists.AddBook(reading.ID, 999)
+			assert.Equal(t, ErrBookNotFound, err)
+
+			list, err := lists.Reorder(reading.ID, []uint{bookIds[2], bookIds[0], bookIds[1]})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []uint{bookIds[2], bookIds[0], bookIds[1]}, bookOrder(list))
+				assert.Equal(t, "biology", list.Items[0].Book.Title)
+			}
+			for _, order := range [][]uint{{bookIds[0], bookIds[1]}, {bookIds[0], bookIds[0], bookIds[1]}, {bookIds[0], bookIds[1], 999}} {
+				_, err = lists.Reorder(reading.ID, order)
+				assert.Equal(t, ErrListOrderMismatch, err, "%v", order)
+			}
+
+			list, err = lists.RemoveBook(reading.ID, bookIds[2])
+			if assert.NoError(t, err) {
+				assert.Equal(t, []uint{bookIds[0], bookIds[1]}, bookOrder(list))
+			}
+			_, err = lists.RemoveBook(reading.ID, bookIds[2])
+			assert.Equal(t, ErrListBookNotFound, err)
+
+			// deleted books stay on lists
+			assert.NoError(t, books.Delete(bookIds[1]))
+			list, err = lists.GetByID(reading.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, []uint{bookIds[0], bookIds[1]}, bookOrder(list))
+				assert.Equal(t, "physics", list.Items[1].Book.Title)
+			}
+			_, err = lists.AddBook(other.ID, bookIds[1])
+			assert.Equal(t, ErrBookNotFound, err)
+
+			_, err = lists.GetPublicBySlug(reading.Slug)
+			assert.Equal(t, ErrReadingListNotFound, err)
+			public := true
+			list, err = lists.Update(reading.ID, models.ReadingListChanges{Name: "Now reading", Public: &public})
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Now reading", list.Name)
+				assert.Equal(t, reading.Slug, list.Slug)
+			}
+			_, err = lists.Update(reading.ID, models.ReadingListChanges{})
+			assert.NoError(t, err)
+			list, err = lists.GetPublicBySlug(reading.Slug)
+			if assert.NoError(t, err) {
+				assert.Equal(t, reading.ID, list.ID)
+				assert.Len(t, list.Items, 2)
+			}
+
+			owned, page, err := lists.ListByUser(alta, ReadingListFilter{}, ListOptions{})
+			if assert.NoError(t, err) {
+				assert.Equal(t, int64(3), page.Total)
+				assert.Len(t, owned, 3)
+				assert.Nil(t, owned[0].Items)
+			}
+			owned, _, err = lists.ListByUser(alta, ReadingListFilter{Kind: models.ListCustom}, ListOptions{Sort: "name"})
+			if assert.NoError(t, err) {
+				assert.Len(t, owned, 2)
+			}
+
+			assert.NoError(t, lists.Delete(reading.ID))
+			_, err = lists.GetByID(reading.ID)
+			assert.Equal(t, ErrReadingListNotFound, err)
+			var items int64
+			assert.NoError(t, db.Model(&models.ReadingListItem{}).Where("reading_list_id = ?", reading.ID).Count(&items).Error)
+			assert.Zero(t, items)
+			assert.Equal(t, ErrReadingListNotFound, lists.Delete(reading.ID))
+		})
+	}
+}
+
+func TestListReadingListsByNameCursor(t *testing.T) {
+	for name, db := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			lists := NewGormReadingListRepository(db)
+
+			user := models.User{Email: "alta@gmail.com"}
+			assert.NoError(t, db.Create(&user).Error)
+			for _, name := range []string{"banana", "Apple", "cherry", "apple"} {
+				list := models.ReadingList{UserID: user.ID, Name: name, Kind: models.ListCustom}
+				assert.NoError(t, lists.Create(&list))
+			}
+
+			names := []string{}
+			opts := ListOptions{Limit: 1, Sort: "name"}
+			for i := 0; i < 10; i++ {
+				page, next, err := lists.ListByUser(user.ID, ReadingListFilter{}, opts)
+				if !assert.NoError(t, err) || !assert.Len(t, page, 1) {
+					return
+				}
+				names = append(names, page[0].Name)
+				if !next.HasMore {
+					break
+				}
+				opts.Cursor = next.NextCursor
+			}
+			assert.ElementsMatch(t, []string{"banana", "Apple", "cherry", "apple"}, names)
+		})
+	}
+}
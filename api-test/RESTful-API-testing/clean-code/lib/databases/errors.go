@@ -0,0 +1,146 @@
+package databases
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound and ErrConflict classify database failures independently of the
+// driver. Resource specific errors wrap them, so callers check with errors.Is.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("already exists")
+
+	ErrBookNotFound   = fmt.Errorf("book %w", ErrNotFound)
+	ErrUserNotFound   = fmt.Errorf("user %w", ErrNotFound)
+	ErrAuthorNotFound = fmt.Errorf("author %w", ErrNotFound)
+	ErrLoanNotFound   = fmt.Errorf("loan %w", ErrNotFound)
+
+	ErrReservationNotFound = fmt.Errorf("reservation %w", ErrNotFound)
+	ErrCopyNotFound        = fmt.Errorf("copy %w", ErrNotFound)
+	ErrReviewNotFound      = fmt.Errorf("review %w", ErrNotFound)
+	ErrReadingListNotFound = fmt.Errorf("reading list %w", ErrNotFound)
+	ErrListBookNotFound    = fmt.Errorf("book on this list %w", ErrNotFound)
+
+	// ErrAuthorHasBooks is returned when an author credited on books is
+	// deleted.
+	ErrAuthorHasBooks = errors.New("author still has books")
+
+	// ErrNoCopyAvailable is returned when a book is checked out while every
+	// copy is on loan.
+	ErrNoCopyAvailable = errors.New("no copy of this book is available")
+
+	// ErrLoanLimitReached is returned when a user with as many books out as
+	// the lending policy allows checks out another.
+	ErrLoanLimitReached = errors.New("loan limit reached")
+
+	// ErrAlreadyBorrowed is returned when a user checks out a book they
+	// already have out.
+	ErrAlreadyBorrowed = errors.New("book is already on loan to this user")
+
+	// ErrLoanReturned is returned when a loan is returned twice.
+	ErrLoanReturned = errors.New("loan is already returned")
+
+	// ErrCopyAvailable is returned when a book is reserved while a copy is
+	// on the shelf to be borrowed.
+	ErrCopyAvailable = errors.New("a copy of this book is available to borrow")
+
+	// ErrAlreadyReserved is returned when a user reserves a book they are
+	// already queued or held for.
+	ErrAlreadyReserved = errors.New("book is already reserved by this user")
+
+	// ErrCopyOnLoan is returned when a copy that is on loan is changed or
+	// deleted.
+	ErrCopyOnLoan = errors.New("copy is on loan")
+
+	// ErrCopyHeld is returned when a copy is taken off the shelf while every
+	// copy on it is held for a reservation.
+	ErrCopyHeld = errors.New("every copy on the shelf is held for a reservation")
+
+	// ErrFinesOwed is returned when a user who owes more than the fine
+	// limit checks out a book.
+	ErrFinesOwed = errors.New("outstanding fines exceed the limit for borrowing")
+
+	// ErrAmountExceedsBalance is returned when a payment or waiver is larger
+	// than what the user owes.
+	ErrAmountExceedsBalance = errors.New("amount exceeds the outstanding balance")
+
+	// ErrAlreadyListed is returned when a book is added to a reading list
+	// it is on already.
+	ErrAlreadyListed = errors.New("book is already on this list")
+
+	// ErrListFull is returned when a book is added to a reading list
+	// holding models.MaxListBooks books.
+	ErrListFull = errors.New("reading list is full")
+
+	// ErrListOrderMismatch is returned when a reading list is reordered
+	// with book ids that are not exactly the books on it.
+	ErrListOrderMismatch = errors.New("book ids must name every book on the list once")
+
+	// ErrEmailTaken is returned when a user is created with, or changes to,
+	// an email another account already has.
+	ErrEmailTaken = fmt.Errorf("a user with this email %w", ErrConflict)
+
+	// ErrISBNTaken is returned when a book is stored with the ISBN of another
+	// book.
+	ErrISBNTaken = fmt.Errorf("a book with this ISBN %w", ErrConflict)
+
+	// ErrAlreadyReviewed is returned when a user reviews a book they have
+	// reviewed already.
+	ErrAlreadyReviewed = fmt.Errorf("a review of this book by this user %w", ErrConflict)
+
+	// ErrListKindTaken is returned when a user creates a second reading
+	// list of a kind other than custom.
+	ErrListKindTaken = fmt.Errorf("a list of this kind %w", ErrConflict)
+
+	// ErrBarcodeTaken is returned when a copy is stored with the barcode of
+	// another copy, deleted copies included.
+	ErrBarcodeTaken = fmt.Errorf("a copy with this barcode %w", ErrConflict)
+)
+
+// Unique key violations as reported by each supported database.
+const (
+	mysqlDuplicateEntry     = 1062
+	postgresUniqueViolation = "23505"
+)
+
+// translateError maps driver errors onto the package's sentinel errors and
+// passes everything else through unchanged. notFound is returned in place of
+// gorm.ErrRecordNotFound.
+func translateError(err error, notFound error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) && mysqlError.Number == mysqlDuplicateEntry {
+		return fmt.Errorf("%w: %s", ErrConflict, mysqlError.Message)
+	}
+
+	var postgresError *pgconn.PgError
+	if errors.As(err, &postgresError) && postgresError.Code == postgresUniqueViolation {
+		return fmt.Errorf("%w: %s", ErrConflict, postgresError.Message)
+	}
+
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) && isSQLiteUniqueViolation(sqliteError) {
+		return fmt.Errorf("%w: %s", ErrConflict, sqliteError.Error())
+	}
+
+	return err
+}
+
+// isSQLiteUniqueViolation reports both unique index and primary key
+// violations, which the other databases do not distinguish either.
+func isSQLiteUniqueViolation(err sqlite3.Error) bool {
+	return err.ExtendedCode == sqlite3.ErrConstraintUnique || err.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
@@ -0,0 +1,32 @@
+package databases
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	assert.Nil(t, translateError(nil, ErrBookNotFound))
+	assert.Equal(t, ErrBookNotFound, translateError(gorm.ErrRecordNotFound, ErrBookNotFound))
+
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'email'"}
+	assert.True(t, errors.Is(translateError(duplicate, ErrUserNotFound), ErrConflict))
+
+	postgresDuplicate := &pgconn.PgError{Code: "23505", Message: `duplicate key value violates unique constraint "users_pkey"`}
+	assert.True(t, errors.Is(translateError(postgresDuplicate, ErrUserNotFound), ErrConflict))
+
+	sqliteDuplicate := sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}
+	assert.True(t, errors.Is(translateError(sqliteDuplicate, ErrUserNotFound), ErrConflict))
+
+	notNull := sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintNotNull}
+	assert.False(t, errors.Is(translateError(notNull, ErrUserNotFound), ErrConflict))
+
+	other := errors.New("connection refused")
+	assert.Equal(t, other, translateError(other, ErrUserNotFound))
+}
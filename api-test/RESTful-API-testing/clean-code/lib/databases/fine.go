@@ -0,0 +1,140 @@
+package databases
+
+import (
+	"cleancode/models"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FinePolicy is what overdue loans cost and how much a user may owe and
+// still borrow.
+type FinePolicy struct {
+	Fees  models.FeeSchedule
+	Limit decimal.Decimal
+}
+
+// FineFilter narrows a fine ledger to one kind of entry.
+type FineFilter struct {
+	Kind string
+}
+
+var fineList = listQuery{
+	columns: map[string]listColumn{
+		"id":         {expr: "id", field: "ID", kind: idColumn},
+		"created_at": {expr: "created_at", field: "CreatedAt", kind: timeColumn},
+	},
+	defaultSort: "-id",
+}
+
+// GormFineRepository keeps the fine ledgers of users with GORM. Amounts are
+// summed in Go with exact decimals rather than by the database, which for
+// SQLite would mean floating point.
+type GormFineRepository struct {
+	db     *gorm.DB
+	policy FinePolicy
+}
+
+func NewGormFineRepository(db *gorm.DB, policy FinePolicy) *GormFineRepository {
+	return &GormFineRepository{db: db, policy: policy}
+}
+
+// List lists the ledger of a user, newest first unless opts sorts it
+// otherwise.
+func (r *GormFineRepository) List(userId uint, filter FineFilter, opts ListOptions) ([]models.FineEntry, Page, error) {
+	if err := r.db.Select("id").First(&models.User{}, userId).Error; err != nil {
+		return nil, Page{}, translateError(err, ErrUserNotFound)
+	}
+
+	query := r.db.Model(&models.FineEntry{}).Where("user_id = ?", userId)
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+
+	entries := []models.FineEntry{}
+	page, err := fineList.find(query, opts, &entries)
+	if err != nil {
+		return nil, page, err
+	}
+
+	return entries, page, nil
+}
+
+// Balance sums the ledger of a user and the fees accruing on their overdue
+// loans.
+func (r *GormFineRepository) Balance(userId uint) (models.FineBalance, error) {
+	if err := r.db.Select("id").First(&models.User{}, userId).Error; err != nil {
+		return models.FineBalance{}, translateError(err, ErrUserNotFound)
+	}
+
+	return fineBalance(r.db, userId, r.policy, time.Now())
+}
+
+// Record appends an entry to the ledger of entry.UserID. The user's row is
+// locked while the balance is checked, so concurrent payments cannot
+// together take it below zero. A loan the entry refers to must be the
+// user's.
+func (r *GormFineRepository) Record(entry *models.FineEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, entry.UserID).Error; err != nil {
+			return translateError(err, ErrUserNotFound)
+		}
+		if entry.LoanID != nil {
+			err := tx.Where("user_id = ?", entry.UserID).Select("id").First(&models.Loan{}, *entry.LoanID).Error
+			if err != nil {
+				return translateError(err, ErrLoanNotFound)
+			}
+		}
+
+		if entry.Amount.IsNegative() {
+			balance, err := ledgerBalance(tx, entry.UserID)
+			if err != nil {
+				return err
+			}
+			if balance.Add(entry.Amount).IsNegative() {
+				return ErrAmountExceedsBalance
+			}
+		}
+
+		return tx.Create(entry).Error
+	})
+}
+
+// fineBalance is what a user owes at now.
+func fineBalance(tx *gorm.DB, userId uint, policy FinePolicy, now time.Time) (models.FineBalance, error) {
+	balance := models.FineBalance{UserID: userId, Limit: policy.Limit}
+
+	var err error
+	balance.Balance, err = ledgerBalance(tx, userId)
+	if err != nil {
+		return balance, err
+	}
+
+	var overdue []models.Loan
+	err = tx.Where("user_id = ? AND returned_at IS NULL AND due_at < ?", userId, now).Find(&overdue).Error
+	if err != nil {
+		return balance, err
+	}
+	balance.Accruing = decimal.Zero
+	for _, loan := range overdue {
+		balance.Accruing = balance.Accruing.Add(policy.Fees.Fee(loan.DaysOverdue(now)))
+	}
+	return balance, nil
+}
+
+// ledgerBalance sums the amounts of a user's fine entries.
+func ledgerBalance(tx *gorm.DB, userId uint) (decimal.Decimal, error) {
+	var amounts []decimal.Decimal
+	if err := tx.Model(&models.FineEntry{}).Where("user_id = ?", userId).Pluck("amount", &amounts).Error; err != nil {
+		return decimal.Zero, err
+	}
+
+	sum := decimal.Zero
+	for _, amount := range amounts {
+		sum = sum.Add(amount)
+	}
+	return sum, nil
+}
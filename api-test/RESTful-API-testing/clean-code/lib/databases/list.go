@@ -0,0 +1,287 @@
+package databases
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPageSize is used when a list request gives no limit.
+	DefaultPageSize = 20
+	// MaxPageSize is the most rows one list request returns.
+	MaxPageSize = 100
+)
+
+var (
+	// ErrInvalidCursor is returned for a cursor that is malformed or was
+	// issued for a different sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrInvalidSort is returned when a list is sorted by an unknown column.
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// ListOptions selects one page of a list. Pages are addressed either by
+// number or, for stable iteration over changing rows, by the Cursor returned
+// with the previous page. Sort is a comma separated list of columns, each
+// optionally prefixed with "-" for descending order.
+type ListOptions struct {
+	Limit  int
+	Page   int
+	Cursor string
+	Sort   string
+}
+
+// Page describes the rows returned by a list call. Page and Total are only
+// set when pages are addressed by number; NextCursor is set whenever more
+// rows follow.
+type Page struct {
+	Limit      int
+	Page       int
+	Total      int64
+	HasMore    bool
+	NextCursor string
+}
+
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	dateColumn
+	timeColumn
+	idColumn
+	intColumn
+)
+
+// noDate stands in for a missing date when sorting, so books without one
+// come first in ascending order.
+const noDate = "0001-01-01"
+
+// listColumn is a column a list can be sorted by. expr is used in ORDER BY
+// and in cursor conditions, and field names the struct field holding the
+// value of a loaded row.
+type listColumn struct {
+	expr  string
+	field string
+	kind  columnKind
+}
+
+// listQuery pages through the rows of one model.
+type listQuery struct {
+	columns     map[string]listColumn
+	defaultSort string
+}
+
+type sortField struct {
+	name string
+	desc bool
+}
+
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// find loads the page of db selected by opts into dest, a pointer to a
+// slice of the model. One row more than the limit is read to learn whether
+// another page follows.
+func (q listQuery) find(db *gorm.DB, opts ListOptions, dest interface{}) (Page, error) {
+	page := Page{Limit: opts.Limit}
+	if page.Limit <= 0 {
+		page.Limit = DefaultPageSize
+	}
+	if page.Limit > MaxPageSize {
+		page.Limit = MaxPageSize
+	}
+
+	fields, err := q.parseSort(opts.Sort)
+	if err != nil {
+		return page, err
+	}
+	sortKey := encodeSort(fields)
+
+	db = db.Session(&gorm.Session{})
+	query := db
+	if opts.Cursor != "" {
+		condition, args, err := q.after(fields, sortKey, opts.Cursor)
+		if err != nil {
+			return page, err
+		}
+		query = query.Where(condition, args...)
+	} else {
+		page.Page = opts.Page
+		if page.Page < 1 {
+			page.Page = 1
+		}
+		if err := db.Count(&page.Total).Error; err != nil {
+			return page, err
+		}
+		query = query.Offset((page.Page - 1) * page.Limit)
+	}
+
+	for _, field := range fields {
+		order := q.columns[field.name].expr
+		if field.desc {
+			order += " DESC"
+		}
+		query = query.Order(order)
+	}
+
+	if err := query.Limit(page.Limit + 1).Find(dest).Error; err != nil {
+		return page, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() <= page.Limit {
+		return page, nil
+	}
+	rows.Set(rows.Slice(0, page.Limit))
+	page.HasMore = true
+
+	last := reflect.Indirect(rows.Index(page.Limit - 1))
+	next := cursor{Sort: sortKey}
+	for _, field := range fields {
+		next.Values = append(next.Values, last.FieldByName(q.columns[field.name].field).Interface())
+	}
+	page.NextCursor, err = encodeCursor(next)
+	return page, err
+}
+
+// parseSort resolves raw against the sortable columns and ends the order
+// with the id, so rows never tie and cursors are stable.
+func (q listQuery) parseSort(raw string) ([]sortField, error) {
+	if raw == "" {
+		raw = q.defaultSort
+	}
+
+	fields := []sortField{}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		field := sortField{name: strings.TrimPrefix(part, "-"), desc: strings.HasPrefix(part, "-")}
+		if _, ok := q.columns[field.name]; !ok || seen[field.name] {
+			return nil, ErrInvalidSort
+		}
+		seen[field.name] = true
+		fields = append(fields, field)
+	}
+
+	if !seen["id"] {
+		fields = append(fields, sortField{name: "id"})
+	}
+	return fields, nil
+}
+
+// after builds the keyset condition selecting the rows that come after the
+// cursor in the order of fields: (a > ?) OR (a = ? AND b > ?) OR ...
+func (q listQuery) after(fields []sortField, sortKey string, raw string) (string, []interface{}, error) {
+	c, err := decodeCursor(raw)
+	if err != nil || c.Sort != sortKey || len(c.Values) != len(fields) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i], err = decodeValue(q.columns[field.name].kind, c.Values[i])
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+	}
+
+	alternatives := make([]string, 0, len(fields))
+	args := []interface{}{}
+	for i, field := range fields {
+		terms := make([]string, 0, i+1)
+		for _, equal := range fields[:i] {
+			terms = append(terms, q.columns[equal.name].expr+" = ?")
+		}
+		if field.desc {
+			terms = append(terms, q.columns[field.name].expr+" < ?")
+		} else {
+			terms = append(terms, q.columns[field.name].expr+" > ?")
+		}
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+		args = append(args, values[:i+1]...)
+	}
+	return strings.Join(alternatives, " OR "), args, nil
+}
+
+func encodeSort(fields []sortField) string {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field.desc {
+			parts = append(parts, "-"+field.name)
+		} else {
+			parts = append(parts, field.name)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(c cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(raw string) (cursor, error) {
+	c := cursor{}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+func decodeValue(kind columnKind, value interface{}) (interface{}, error) {
+	if kind == idColumn {
+		id, ok := value.(float64)
+		if !ok || id < 0 || id != float64(uint(id)) {
+			return nil, ErrInvalidCursor
+		}
+		return uint(id), nil
+	}
+
+	if kind == intColumn {
+		number, ok := value.(float64)
+		if !ok || number != float64(int(number)) {
+			return nil, ErrInvalidCursor
+		}
+		return int(number), nil
+	}
+
+	if kind == dateColumn && value == nil {
+		return noDate, nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	switch kind {
+	case dateColumn:
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			return nil, err
+		}
+	case timeColumn:
+		return time.Parse(time.RFC3339Nano, text)
+	}
+	return text, nil
+}
+
+// containsPattern is a LIKE pattern matching value anywhere in a lowercased
+// column. Wildcards in value are escaped with "!", so queries using it add
+// ESCAPE '!'.
+func containsPattern(value string) string {
+	escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(value))
+	return "%" + escaped + "%"
+}
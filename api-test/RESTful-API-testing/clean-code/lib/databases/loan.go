@@ -0,0 +1,212 @@
+package databases
+
+import (
+	"cleancode/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var loanList = listQuery{
+	columns: map[string]listColumn{
+		"id":          {expr: "id", field: "ID", kind: idColumn},
+		"due_at":      {expr: "due_at", field: "DueAt", kind: timeColumn},
+		"borrowed_at": {expr: "borrowed_at", field: "BorrowedAt", kind: timeColumn},
+	},
+	defaultSort: "due_at",
+}
+
+// LoanPolicy is how long books are lent for, how many books one user may
+// have out at once, how long a returned copy is held for the next user in
+// the queue for it and what returning a book late costs.
+type LoanPolicy struct {
+	Period     time.Duration
+	Limit      int
+	HoldPeriod time.Duration
+	Fines      FinePolicy
+}
+
+// GormLoanRepository lends books with GORM. Each book counts its copies on
+// the shelf in books.available_copies, which a checkout only decrements
+// while it is positive, so concurrent checkouts never lend more copies than
+// there are; the loan then takes the first copy on the shelf, by id. A
+// returned copy goes to the oldest reservation of the book before it goes
+// back on the shelf.
+type GormLoanRepository struct {
+	db     *gorm.DB
+	policy LoanPolicy
+}
+
+func NewGormLoanRepository(db *gorm.DB, policy LoanPolicy) *GormLoanRepository {
+	return &GormLoanRepository{db: db, policy: policy}
+}
+
+func (r *GormLoanRepository) GetByID(id uint) (models.Loan, error) {
+	loan := models.Loan{}
+	result := r.db.Scopes(withLoanBook).First(&loan, id)
+	if result.Error != nil {
+		return loan, translateError(result.Error, ErrLoanNotFound)
+	}
+
+	return loan, nil
+}
+
+// ListActive lists the loans of a user that are not returned, soonest due
+// first unless opts sorts them otherwise.
+func (r *GormLoanRepository) ListActive(userId uint, opts ListOptions) ([]models.Loan, Page, error) {
+	query := r.db.Model(&models.Loan{}).Where("user_id = ? AND returned_at IS NULL", userId)
+
+	loans := []models.Loan{}
+	page, err := loanList.find(query.Scopes(withLoanBook), opts, &loans)
+	if err != nil {
+		return nil, page, translateError(err, ErrLoanNotFound)
+	}
+
+	return loans, page, nil
+}
+
+// Checkout lends a copy of a book to a user: the copy held for the user's
+// reservation if there is one, else one from the shelf. The user's row is
+// locked for the transaction, so concurrent checkouts by one user cannot
+// overrun the limit together.
+func (r *GormLoanRepository) Checkout(userId uint, bookId uint) (models.Loan, error) {
+	loan := models.Loan{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userId).Error; err != nil {
+			return translateError(err, ErrUserNotFound)
+		}
+
+		var active []uint
+		err := tx.Model(&models.Loan{}).Where("user_id = ? AND returned_at IS NULL", userId).Pluck("book_id", &active).Error
+		if err != nil {
+			return err
+		}
+		for _, id := range active {
+			if id == bookId {
+				return ErrAlreadyBorrowed
+			}
+		}
+		if len(active) >= r.policy.Limit {
+			return ErrLoanLimitReached
+		}
+		fines, err := fineBalance(tx, userId, r.policy.Fines, time.Now())
+		if err != nil {
+			return err
+		}
+		if fines.Blocked() {
+			return ErrFinesOwed
+		}
+
+		book, err := lockBook(tx, bookId)
+		if err != nil {
+			return err
+		}
+		if book.DeletedAt.Valid {
+			return ErrBookNotFound
+		}
+		if err := expireHolds(tx, r.policy.HoldPeriod, "book_id = ?", bookId); err != nil {
+			return err
+		}
+		if err := takeCopy(tx, userId, bookId); err != nil {
+			return err
+		}
+		bookCopy, err := lendCopy(tx, bookId)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		loan = models.Loan{UserID: userId, BookID: bookId, CopyID: &bookCopy.ID, BorrowedAt: now, DueAt: now.Add(r.policy.Period)}
+		if err := tx.Create(&loan).Error; err != nil {
+			return err
+		}
+		return tx.Scopes(withLoanBook).First(&loan, loan.ID).Error
+	})
+	return loan, translateError(err, ErrLoanNotFound)
+}
+
+// Return takes back the copy of a loan, holding it for the next reservation
+// of the book or putting it back on the shelf, and charges the fee of an
+// overdue loan. Only the first return of a loan counts; the copy of a book
+// deleted meanwhile is counted all the same.
+func (r *GormLoanRepository) Return(id uint) (models.Loan, error) {
+	loan := models.Loan{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&loan, id).Error; err != nil {
+			return err
+		}
+		if _, err := lockBook(tx, loan.BookID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.Loan{}).
+			Where("id = ? AND returned_at IS NULL", id).
+			Updates(map[string]interface{}{"returned_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLoanReturned
+		}
+		loan.ReturnedAt = &now
+		if charge := r.policy.Fines.Fees.OverdueCharge(loan); charge != nil {
+			if err := tx.Create(charge).Error; err != nil {
+				return err
+			}
+		}
+
+		if loan.CopyID != nil {
+			err := tx.Model(&models.BookCopy{}).
+				Where("id = ? AND status = ?", *loan.CopyID, models.CopyOnLoan).
+				Update("status", models.CopyAvailable).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := releaseCopy(tx, loan.BookID, now, r.policy.HoldPeriod); err != nil {
+			return err
+		}
+		return tx.Scopes(withLoanBook).First(&loan, id).Error
+	})
+	if err != nil {
+		return loan, translateError(err, ErrLoanNotFound)
+	}
+
+	return loan, nil
+}
+
+// takeCopy takes the copy of a book held for the user, fulfilling their
+// reservation, or else a copy from the shelf. A user who was still waiting
+// for the book leaves the queue. The caller must hold the book's lock.
+func takeCopy(tx *gorm.DB, userId uint, bookId uint) error {
+	held := tx.Model(&models.Reservation{}).
+		Where("user_id = ? AND book_id = ? AND status = ?", userId, bookId, models.ReservationReady).
+		Update("status", models.ReservationFulfilled)
+	if held.Error != nil || held.RowsAffected > 0 {
+		return held.Error
+	}
+
+	shelf := tx.Model(&models.Book{}).
+		Where("id = ? AND available_copies > 0", bookId).
+		UpdateColumn("available_copies", gorm.Expr("available_copies - 1"))
+	if shelf.Error != nil {
+		return shelf.Error
+	}
+	if shelf.RowsAffected == 0 {
+		return ErrNoCopyAvailable
+	}
+
+	return tx.Model(&models.Reservation{}).
+		Where("user_id = ? AND book_id = ? AND status = ?", userId, bookId, models.ReservationWaiting).
+		Update("status", models.ReservationFulfilled).Error
+}
+
+// withLoanBook loads the book of loans, even when it has been deleted since.
+func withLoanBook(db *gorm.DB) *gorm.DB {
+	return db.Preload("Book", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	})
+}
@@ -0,0 +1,302 @@
+package databases
+
+import (
+	"cleancode/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReadingListFilter narrows the reading lists of a user to one kind.
+type ReadingListFilter struct {
+	Kind string
+}
+
+var readingListList = listQuery{
+	columns: map[string]listColumn{
+		"id":         {expr: "id", field: "ID", kind: idColumn},
+		"name":       {expr: "COALESCE(name, '')", field: "Name", kind: textColumn},
+		"created_at": {expr: "created_at", field: "CreatedAt", kind: timeColumn},
+	},
+	defaultSort: "id",
+}
+
+// GormReadingListRepository stores reading lists with GORM. Positions of
+// the books on a list run from 1 without gaps, and reading_lists.book_count
+// counts them. Every change to the books of a list locks the list's row
+// first, so concurrent changes cannot leave either out of step.
+type GormReadingListRepository struct {
+	db *gorm.DB
+}
+
+func NewGormReadingListRepository(db *gorm.DB) *GormReadingListRepository {
+	return &GormReadingListRepository{db: db}
+}
+
+// ListByUser lists the reading lists of a user without their books, by id
+// unless opts sorts them otherwise.
+func (r *GormReadingListRepository) ListByUser(userId uint, filter ReadingListFilter, opts ListOptions) ([]models.ReadingList, Page, error) {
+	if err := r.db.Select("id").First(&models.User{}, userId).Error; err != nil {
+		return nil, Page{}, translateError(err, ErrUserNotFound)
+	}
+
+	query := r.db.Model(&models.ReadingList{}).Where("user_id = ?", userId).Scopes(withListOwner)
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+
+	lists := []models.ReadingList{}
+	page, err := readingListList.find(query, opts, &lists)
+	if err != nil {
+		return nil, page, translateError(err, ErrReadingListNotFound)
+	}
+
+	return lists, page, nil
+}
+
+// GetByID returns a reading list with its books in list order.
+func (r *GormReadingListRepository) GetByID(id uint) (models.ReadingList, error) {
+	list := models.ReadingList{}
+	if err := r.db.Scopes(withListOwner, withListBooks).First(&list, id).Error; err != nil {
+		return list, translateError(err, ErrReadingListNotFound)
+	}
+
+	return list, nil
+}
+
+// GetPublicBySlug returns a public reading list with its books in list
+// order. Private lists are not found.
+func (r *GormReadingListRepository) GetPublicBySlug(slug string) (models.ReadingList, error) {
+	list := models.ReadingList{}
+	err := r.db.Scopes(withListOwner, withListBooks).Where("slug = ? AND public = ?", slug, true).Take(&list).Error
+	if err != nil {
+		return list, translateError(err, ErrReadingListNotFound)
+	}
+
+	return list, nil
+}
+
+// Create stores a new empty list for list.UserID and gives it a slug. The
+// user's row is locked while their lists are checked, so two requests
+// cannot both create the user's list of one fixed kind.
+func (r *GormReadingListRepository) Create(list *models.ReadingList) error {
+	slug, err := listSlug(list.Name)
+	if err != nil {
+		return err
+	}
+	list.Slug = slug
+	list.BookCount = 0
+	list.Items = nil
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, list.UserID).Error; err != nil {
+			return translateError(err, ErrUserNotFound)
+		}
+
+		if list.Kind != models.ListCustom {
+			var existing int64
+			err := tx.Model(&models.ReadingList{}).Where("user_id = ? AND kind = ?", list.UserID, list.Kind).Count(&existing).Error
+			if err != nil {
+				return err
+			}
+			if existing > 0 {
+				return ErrListKindTaken
+			}
+		}
+
+		if err := tx.Omit("User").Create(list).Error; err != nil {
+			return err
+		}
+		list.User = user
+		return nil
+	})
+	return translateError(err, ErrReadingListNotFound)
+}
+
+// Update writes the name and visibility set in changes. The slug is kept.
+func (r *GormReadingListRepository) Update(id uint, changes models.ReadingListChanges) (models.ReadingList, error) {
+	updates := map[string]interface{}{}
+	if changes.Name != "" {
+		updates["name"] = changes.Name
+	}
+	if changes.Public != nil {
+		updates["public"] = *changes.Public
+	}
+
+	list := models.ReadingList{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&list, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&list).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Scopes(withListOwner, withListBooks).First(&list, id).Error
+	})
+	if err != nil {
+		return list, translateError(err, ErrReadingListNotFound)
+	}
+
+	return list, nil
+}
+
+// Delete removes a reading list with its books.
+func (r *GormReadingListRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockList(tx, id); err != nil {
+			return err
+		}
+
+		if err := tx.Where("reading_list_id = ?", id).Delete(&models.ReadingListItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ReadingList{}, id).Error
+	})
+}
+
+// AddBook puts a book at the end of a list. Deleted books cannot be added,
+// and a book is on a list at most once.
+func (r *GormReadingListRepository) AddBook(id uint, bookId uint) (models.ReadingList, error) {
+	return r.changeBooks(id, func(tx *gorm.DB, list models.ReadingList) error {
+		if list.BookCount >= models.MaxListBooks {
+			return ErrListFull
+		}
+		if err := tx.Select("id").First(&models.Book{}, bookId).Error; err != nil {
+			return translateError(err, ErrBookNotFound)
+		}
+
+		item := models.ReadingListItem{ReadingListID: id, BookID: bookId, Position: list.BookCount + 1}
+		if err := tx.Omit("Book").Create(&item).Error; err != nil {
+			if errors.Is(translateError(err, ErrListBookNotFound), ErrConflict) {
+				return ErrAlreadyListed
+			}
+			return err
+		}
+		return addToList(tx, id, 1)
+	})
+}
+
+// RemoveBook takes a book off a list and closes the gap it leaves.
+func (r *GormReadingListRepository) RemoveBook(id uint, bookId uint) (models.ReadingList, error) {
+	return r.changeBooks(id, func(tx *gorm.DB, list models.ReadingList) error {
+		item := models.ReadingListItem{}
+		if err := tx.Where("reading_list_id = ? AND book_id = ?", id, bookId).Take(&item).Error; err != nil {
+			return translateError(err, ErrListBookNotFound)
+		}
+
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.ReadingListItem{}).
+			Where("reading_list_id = ? AND position > ?", id, item.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+		return addToList(tx, id, -1)
+	})
+}
+
+// Reorder puts the books of a list in the order of bookIds, which must name
+// every book of the list exactly once.
+func (r *GormReadingListRepository) Reorder(id uint, bookIds []uint) (models.ReadingList, error) {
+	return r.changeBooks(id, func(tx *gorm.DB, list models.ReadingList) error {
+		items := []models.ReadingListItem{}
+		if err := tx.Where("reading_list_id = ?", id).Find(&items).Error; err != nil {
+			return err
+		}
+
+		positions := map[uint]int{}
+		for i, bookId := range bookIds {
+			positions[bookId] = i + 1
+		}
+		if len(items) != len(bookIds) || len(positions) != len(bookIds) {
+			return ErrListOrderMismatch
+		}
+		for _, item := range items {
+			if _, ok := positions[item.BookID]; !ok {
+				return ErrListOrderMismatch
+			}
+		}
+
+		for _, item := range items {
+			if positions[item.BookID] == item.Position {
+				continue
+			}
+			err := tx.Model(&item).UpdateColumn("position", positions[item.BookID]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// changeBooks runs change with the list's row locked and returns the list
+// as it is afterwards.
+func (r *GormReadingListRepository) changeBooks(id uint, change func(tx *gorm.DB, list models.ReadingList) error) (models.ReadingList, error) {
+	list := models.ReadingList{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockList(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := change(tx, locked); err != nil {
+			return err
+		}
+		return tx.Scopes(withListOwner, withListBooks).First(&list, id).Error
+	})
+	if err != nil {
+		return list, translateError(err, ErrReadingListNotFound)
+	}
+
+	return list, nil
+}
+
+// lockList loads a reading list and locks its row until the end of tx.
+func lockList(tx *gorm.DB, id uint) (models.ReadingList, error) {
+	list := models.ReadingList{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&list, id).Error
+	return list, translateError(err, ErrReadingListNotFound)
+}
+
+// addToList adds count to the book count of a list.
+func addToList(tx *gorm.DB, id uint, count int) error {
+	return tx.Model(&models.ReadingList{}).
+		Where("id = ?", id).
+		UpdateColumn("book_count", gorm.Expr("book_count + ?", count)).Error
+}
+
+// listSlug is the slug of a new list named name: the name made readable in
+// URLs and a random suffix, so lists of the same name get distinct slugs.
+func listSlug(name string) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return models.Slugify(name) + "-" + hex.EncodeToString(suffix), nil
+}
+
+// withListOwner loads the owner of reading lists, even when their account
+// has been deleted since.
+func withListOwner(db *gorm.DB) *gorm.DB {
+	return db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	})
+}
+
+// withListBooks loads the books of reading lists in list order, including
+// books deleted from the catalogue since they were added.
+func withListBooks(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Preload("Items.Book", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		})
+}
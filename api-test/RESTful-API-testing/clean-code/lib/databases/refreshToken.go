@@ -0,0 +1,159 @@
+package databases
+
+import (
+	"cleancode/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+type GormRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRefreshTokenRepository(db *gorm.DB) *GormRefreshTokenRepository {
+	return &GormRefreshTokenRepository{db: db}
+}
+
+// Create stores a new refresh token for the user and returns its raw value,
+// which is never persisted. An empty familyId starts a new family.
+func (r *GormRefreshTokenRepository) Create(userId uint, familyId string, ttl time.Duration) (string, error) {
+	return createRefreshToken(r.db, userId, familyId, ttl)
+}
+
+// Rotate consumes raw and issues its replacement in the same family.
+// Presenting a token that was already consumed revokes the family.
+func (r *GormRefreshTokenRepository) Rotate(raw string, ttl time.Duration) (string, uint, error) {
+	stored, err := r.find(raw)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if stored.UsedAt != nil {
+		return "", 0, r.revokeFamilyAfterReuse(stored.FamilyID)
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return "", 0, ErrRefreshTokenInvalid
+	}
+
+	var newToken string
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		// the used_at check makes this a compare-and-set, so two concurrent
+		// refreshes with the same token cannot both succeed
+		used := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", stored.ID).
+			Update("used_at", time.Now())
+		if used.Error != nil {
+			return used.Error
+		}
+		if used.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var err error
+		newToken, err = createRefreshToken(tx, stored.UserID, stored.FamilyID, ttl)
+		return err
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return "", 0, r.revokeFamilyAfterReuse(stored.FamilyID)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	return newToken, stored.UserID, nil
+}
+
+// Revoke invalidates raw together with every token of its family.
+func (r *GormRefreshTokenRepository) Revoke(raw string) error {
+	stored, err := r.find(raw)
+	if err != nil {
+		return err
+	}
+	return r.revokeFamily(stored.FamilyID)
+}
+
+func (r *GormRefreshTokenRepository) find(raw string) (models.RefreshToken, error) {
+	stored := models.RefreshToken{}
+	if raw == "" {
+		return stored, ErrRefreshTokenInvalid
+	}
+
+	result := r.db.Where("token_hash = ?", hashRefreshToken(raw)).Limit(1).Find(&stored)
+	if result.Error != nil {
+		return stored, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return stored, ErrRefreshTokenInvalid
+	}
+	return stored, nil
+}
+
+func (r *GormRefreshTokenRepository) revokeFamily(familyId string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeUserRefreshTokens invalidates every token of every family of the user.
+func revokeUserRefreshTokens(db *gorm.DB, userId uint) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *GormRefreshTokenRepository) revokeFamilyAfterReuse(familyId string) error {
+	if err := r.revokeFamily(familyId); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func createRefreshToken(db *gorm.DB, userId uint, familyId string, ttl time.Duration) (string, error) {
+	raw, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	if familyId == "" {
+		familyId, err = randomString(16)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	token := models.RefreshToken{
+		UserID:    userId,
+		FamilyID:  familyId,
+		TokenHash: hashRefreshToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if err := db.Create(&token).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
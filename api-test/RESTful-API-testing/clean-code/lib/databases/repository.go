@@ -0,0 +1,154 @@
+package databases
+
+import (
+	"cleancode/models"
+	"time"
+)
+
+// BookRepository stores books. Lookups of a missing book return an error
+// wrapping ErrNotFound. Update only writes the fields that are set in
+// changes. Books are returned with their authors in credit order, and the
+// Author byline always names them. List and Search return at most
+// MaxPageSize books.
+type BookRepository interface {
+	List(filter BookFilter, opts ListOptions) ([]models.Book, Page, error)
+	Search(text string, opts ListOptions) ([]BookMatch, Page, error)
+	GetByID(id uint) (models.Book, error)
+	Create(book *models.Book) error
+	Update(id uint, changes models.Book) (models.Book, error)
+	Delete(id uint) error
+}
+
+// AuthorRepository stores authors. Lookups of a missing author return an
+// error wrapping ErrNotFound, and an author credited on a book cannot be
+// deleted. Renaming an author rewrites the Author byline of their books.
+type AuthorRepository interface {
+	List(filter AuthorFilter, opts ListOptions) ([]models.Author, Page, error)
+	GetByID(id uint) (models.Author, error)
+	Books(id uint, opts ListOptions) ([]models.Book, Page, error)
+	Create(author *models.Author) error
+	Update(id uint, changes models.Author) (models.Author, error)
+	Delete(id uint) error
+}
+
+// BookCopyRepository stores the physical copies of books and keeps the
+// Copies and AvailableCopies counts of their books. Lookups of a copy that
+// is missing or belongs to another book return an error wrapping
+// ErrNotFound. A copy on loan cannot be changed or deleted
+// (ErrCopyOnLoan), and no copy can be taken off the shelf while every copy
+// on it is held for a reservation (ErrCopyHeld). Barcodes are unique;
+// taking one in use fails with ErrBarcodeTaken. List returns at most
+// MaxPageSize copies.
+type BookCopyRepository interface {
+	List(bookId uint, filter BookCopyFilter, opts ListOptions) ([]models.BookCopy, Page, error)
+	GetByID(bookId uint, id uint) (models.BookCopy, error)
+	Create(bookCopy *models.BookCopy) error
+	Update(bookId uint, id uint, changes models.BookCopy) (models.BookCopy, error)
+	Delete(bookId uint, id uint) error
+}
+
+// LoanRepository lends books to users. A checkout fails with
+// ErrNoCopyAvailable when every copy is out, ErrAlreadyBorrowed when the user
+// has the book out already, ErrLoanLimitReached when the user has as many
+// books out as allowed and ErrFinesOwed when the user owes more than the
+// fine limit. Returning an overdue loan charges its fee to the user's fine
+// ledger, and returned copies are held for reservations first. ListActive
+// returns at most MaxPageSize loans.
+type LoanRepository interface {
+	GetByID(id uint) (models.Loan, error)
+	ListActive(userId uint, opts ListOptions) ([]models.Loan, Page, error)
+	Checkout(userId uint, bookId uint) (models.Loan, error)
+	Return(id uint) (models.Loan, error)
+}
+
+// FineRepository keeps an append-only fine ledger per user. Record fails
+// with ErrAmountExceedsBalance for a payment or waiver larger than the
+// user's balance. Balance includes the fees accruing on overdue loans,
+// which are charged to the ledger when the loans are returned. List
+// returns at most MaxPageSize entries.
+type FineRepository interface {
+	List(userId uint, filter FineFilter, opts ListOptions) ([]models.FineEntry, Page, error)
+	Balance(userId uint) (models.FineBalance, error)
+	Record(entry *models.FineEntry) error
+}
+
+// ReservationRepository queues users for books whose copies are all out.
+// Reserve fails with ErrCopyAvailable while a copy is on the shelf and with
+// ErrAlreadyReserved or ErrAlreadyBorrowed when the user is queued for or
+// has the book already. ListByUser returns at most MaxPageSize
+// reservations.
+type ReservationRepository interface {
+	Reserve(userId uint, bookId uint) (models.Reservation, error)
+	ListByUser(userId uint, filter ReservationFilter, opts ListOptions) ([]models.Reservation, Page, error)
+	ExpireHolds() error
+}
+
+// ReviewRepository stores reviews and keeps the ReviewCount and RatingSum
+// totals of their books. Lookups of a missing review return an error
+// wrapping ErrNotFound. A user reviews a book once; a second review fails
+// with ErrAlreadyReviewed. Update only writes the rating and text set in
+// changes. ListByBook returns at most MaxPageSize reviews.
+type ReviewRepository interface {
+	ListByBook(bookId uint, opts ListOptions) ([]models.Review, Page, error)
+	GetByID(id uint) (models.Review, error)
+	Create(review *models.Review) error
+	Update(id uint, changes models.Review) (models.Review, error)
+	Delete(id uint) error
+}
+
+// ReadingListRepository stores the reading lists of users. Lookups of a
+// missing list return an error wrapping ErrNotFound, and GetPublicBySlug
+// does not find private lists. A user has one list of each kind but custom;
+// a second fails with ErrListKindTaken. Adding a book fails with
+// ErrAlreadyListed when it is on the list and ErrListFull when the list
+// holds models.MaxListBooks books; Reorder fails with ErrListOrderMismatch
+// unless it names every book on the list once. ListByUser returns at most
+// MaxPageSize lists, without their books.
+type ReadingListRepository interface {
+	ListByUser(userId uint, filter ReadingListFilter, opts ListOptions) ([]models.ReadingList, Page, error)
+	GetByID(id uint) (models.ReadingList, error)
+	GetPublicBySlug(slug string) (models.ReadingList, error)
+	Create(list *models.ReadingList) error
+	Update(id uint, changes models.ReadingListChanges) (models.ReadingList, error)
+	Delete(id uint) error
+	AddBook(id uint, bookId uint) (models.ReadingList, error)
+	RemoveBook(id uint, bookId uint) (models.ReadingList, error)
+	Reorder(id uint, bookIds []uint) (models.ReadingList, error)
+}
+
+// UserRepository stores users. Emails are stored lowercased and are unique;
+// taking one in use fails with ErrEmailTaken. Passwords are stored exactly
+// as given, so callers hash them first. Update only writes the name, email and password
+// set in changes; the role is changed with UpdateRole. List returns at most
+// MaxPageSize users.
+type UserRepository interface {
+	List(filter UserFilter, opts ListOptions) ([]models.User, Page, error)
+	GetByID(id uint) (models.User, error)
+	GetByEmail(email string) (models.User, error)
+	Create(user *models.User) error
+	Update(id uint, changes models.User) (models.User, error)
+	UpdateRole(id uint, role string) (models.User, error)
+	UpdatePassword(id uint, hashed string) error
+	Delete(id uint) error
+}
+
+// RefreshTokenRepository stores opaque refresh tokens by hash and rotates them
+// within a token family.
+type RefreshTokenRepository interface {
+	Create(userId uint, familyId string, ttl time.Duration) (string, error)
+	Rotate(raw string, ttl time.Duration) (string, uint, error)
+	Revoke(raw string) error
+}
+
+var (
+	_ BookRepository         = (*GormBookRepository)(nil)
+	_ BookCopyRepository     = (*GormBookCopyRepository)(nil)
+	_ AuthorRepository       = (*GormAuthorRepository)(nil)
+	_ LoanRepository         = (*GormLoanRepository)(nil)
+	_ FineRepository         = (*GormFineRepository)(nil)
+	_ ReservationRepository  = (*GormReservationRepository)(nil)
+	_ ReviewRepository       = (*GormReviewRepository)(nil)
+	_ ReadingListRepository  = (*GormReadingListRepository)(nil)
+	_ UserRepository         = (*GormUserRepository)(nil)
+	_ RefreshTokenRepository = (*GormRefreshTokenRepository)(nil)
+)
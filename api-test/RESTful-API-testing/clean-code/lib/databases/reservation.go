@@ -0,0 +1,226 @@
+package databases
+
+import (
+	"cleancode/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReservationFilter narrows a reservation list to one status.
+type ReservationFilter struct {
+	Status string
+}
+
+var reservationList = listQuery{
+	columns: map[string]listColumn{
+		"id":         {expr: "id", field: "ID", kind: idColumn},
+		"created_at": {expr: "created_at", field: "CreatedAt", kind: timeColumn},
+	},
+	defaultSort: "-id",
+}
+
+// GormReservationRepository queues users for books with GORM. A copy held
+// for a reservation is not counted in books.available_copies, so it cannot
+// be checked out by anyone else. Lapsed holds are expired when their book
+// or their user's reservations are next touched.
+type GormReservationRepository struct {
+	db         *gorm.DB
+	holdPeriod time.Duration
+}
+
+// NewGormReservationRepository holds returned copies for holdPeriod.
+func NewGormReservationRepository(db *gorm.DB, holdPeriod time.Duration) *GormReservationRepository {
+	return &GormReservationRepository{db: db, holdPeriod: holdPeriod}
+}
+
+// Reserve puts a user at the end of the queue for a book. Reserving is only
+// possible while every copy is out.
+func (r *GormReservationRepository) Reserve(userId uint, bookId uint) (models.Reservation, error) {
+	reservation := models.Reservation{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.User{}, userId).Error; err != nil {
+			return translateError(err, ErrUserNotFound)
+		}
+		if _, err := lockBook(tx, bookId); err != nil {
+			return err
+		}
+		if err := expireHolds(tx, r.holdPeriod, "book_id = ?", bookId); err != nil {
+			return err
+		}
+		book := models.Book{}
+		if err := tx.First(&book, bookId).Error; err != nil {
+			return translateError(err, ErrBookNotFound)
+		}
+		if book.AvailableCopies > 0 {
+			return ErrCopyAvailable
+		}
+
+		var open int64
+		err := tx.Model(&models.Reservation{}).
+			Where("user_id = ? AND book_id = ? AND status IN ?", userId, bookId, []string{models.ReservationWaiting, models.ReservationReady}).
+			Count(&open).Error
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrAlreadyReserved
+		}
+
+		var borrowed int64
+		err = tx.Model(&models.Loan{}).Where("user_id = ? AND book_id = ? AND returned_at IS NULL", userId, bookId).Count(&borrowed).Error
+		if err != nil {
+			return err
+		}
+		if borrowed > 0 {
+			return ErrAlreadyBorrowed
+		}
+
+		reservation = models.Reservation{UserID: userId, BookID: bookId, Status: models.ReservationWaiting}
+		if err := tx.Create(&reservation).Error; err != nil {
+			return err
+		}
+		if err := tx.Scopes(withReservationBook).First(&reservation, reservation.ID).Error; err != nil {
+			return err
+		}
+		reservation.Position, err = queuePosition(tx, reservation)
+		return err
+	})
+	return reservation, translateError(err, ErrReservationNotFound)
+}
+
+// ListByUser lists the reservations of a user, newest first unless opts
+// sorts them otherwise.
+func (r *GormReservationRepository) ListByUser(userId uint, filter ReservationFilter, opts ListOptions) ([]models.Reservation, Page, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return expireHolds(tx, r.holdPeriod, "user_id = ?", userId)
+	})
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	query := r.db.Model(&models.Reservation{}).Where("user_id = ?", userId)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	reservations := []models.Reservation{}
+	page, err := reservationList.find(query.Scopes(withReservationBook), opts, &reservations)
+	if err == nil {
+		err = setPositions(r.db, reservations)
+	}
+	if err != nil {
+		return nil, page, translateError(err, ErrReservationNotFound)
+	}
+
+	return reservations, page, nil
+}
+
+// ExpireHolds expires every lapsed hold, passing its copy on.
+func (r *GormReservationRepository) ExpireHolds() error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return expireHolds(tx, r.holdPeriod)
+	})
+}
+
+// expireHolds expires the lapsed holds among the reservations matching
+// conds, or all of them without conds, and hands each held copy to the next
+// in line.
+func expireHolds(tx *gorm.DB, holdPeriod time.Duration, conds ...interface{}) error {
+	now := time.Now()
+	query := tx.Where("status = ? AND expires_at < ?", models.ReservationReady, now)
+	if len(conds) > 0 {
+		query = query.Where(conds[0], conds[1:]...)
+	}
+
+	var lapsed []models.Reservation
+	err := query.Order("id").Find(&lapsed).Error
+	if err != nil {
+		return err
+	}
+
+	for _, reservation := range lapsed {
+		if _, err := lockBook(tx, reservation.BookID); err != nil {
+			return err
+		}
+		result := tx.Model(&models.Reservation{}).
+			Where("id = ? AND status = ?", reservation.ID, models.ReservationReady).
+			Update("status", models.ReservationExpired)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := releaseCopy(tx, reservation.BookID, now, holdPeriod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseCopy hands a copy of a book that came free to the oldest waiting
+// reservation, holding it until holdPeriod from now, or puts it back on the
+// shelf when nobody is waiting. The caller must hold the book's lock.
+func releaseCopy(tx *gorm.DB, bookId uint, now time.Time, holdPeriod time.Duration) error {
+	next := models.Reservation{}
+	err := tx.Where("book_id = ? AND status = ?", bookId, models.ReservationWaiting).Order("id").Take(&next).Error
+	if err == gorm.ErrRecordNotFound {
+		return tx.Unscoped().Model(&models.Book{}).
+			Where("id = ?", bookId).
+			UpdateColumn("available_copies", gorm.Expr("available_copies + 1")).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	expires := now.Add(holdPeriod)
+	return tx.Model(&next).Updates(map[string]interface{}{
+		"status":     models.ReservationReady,
+		"ready_at":   now,
+		"expires_at": expires,
+	}).Error
+}
+
+// lockBook locks the row of a book, deleted or not, for the transaction, so
+// copies of one book change hands one at a time.
+func lockBook(tx *gorm.DB, bookId uint) (models.Book, error) {
+	book := models.Book{}
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, bookId).Error
+	return book, translateError(err, ErrBookNotFound)
+}
+
+// setPositions fills in the queue position of the waiting reservations.
+func setPositions(db *gorm.DB, reservations []models.Reservation) error {
+	for i := range reservations {
+		position, err := queuePosition(db, reservations[i])
+		if err != nil {
+			return err
+		}
+		reservations[i].Position = position
+	}
+	return nil
+}
+
+// queuePosition is the place of a waiting reservation in the queue for its
+// book, counting from 1, and 0 for any other.
+func queuePosition(db *gorm.DB, reservation models.Reservation) (int, error) {
+	if reservation.Status != models.ReservationWaiting {
+		return 0, nil
+	}
+
+	var ahead int64
+	err := db.Model(&models.Reservation{}).
+		Where("book_id = ? AND status = ? AND id < ?", reservation.BookID, models.ReservationWaiting, reservation.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
+
+// withReservationBook loads the book of reservations, even when it has been
+// deleted since.
+func withReservationBook(db *gorm.DB) *gorm.DB {
+	return db.Preload("Book", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	})
+}
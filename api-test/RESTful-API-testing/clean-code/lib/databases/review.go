@@ -0,0 +1,157 @@
+package databases
+
+import (
+	"cleancode/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var reviewList = listQuery{
+	columns: map[string]listColumn{
+		"id":         {expr: "id", field: "ID", kind: idColumn},
+		"created_at": {expr: "created_at", field: "CreatedAt", kind: timeColumn},
+		"rating":     {expr: "rating", field: "Rating", kind: intColumn},
+	},
+	defaultSort: "-id",
+}
+
+// GormReviewRepository stores reviews with GORM and keeps the totals of
+// their books with them: books.review_count counts the reviews of a book
+// and books.rating_sum adds up their ratings. The totals are changed in the
+// same transaction as the review, by increments, so reading a book's
+// average never aggregates its reviews.
+type GormReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewGormReviewRepository(db *gorm.DB) *GormReviewRepository {
+	return &GormReviewRepository{db: db}
+}
+
+// ListByBook lists the reviews of a book, newest first unless opts sorts
+// them otherwise.
+func (r *GormReviewRepository) ListByBook(bookId uint, opts ListOptions) ([]models.Review, Page, error) {
+	if err := r.db.Select("id").First(&models.Book{}, bookId).Error; err != nil {
+		return nil, Page{}, translateError(err, ErrBookNotFound)
+	}
+
+	query := r.db.Model(&models.Review{}).Where("book_id = ?", bookId).Scopes(withReviewer)
+
+	reviews := []models.Review{}
+	page, err := reviewList.find(query, opts, &reviews)
+	if err != nil {
+		return nil, page, translateError(err, ErrReviewNotFound)
+	}
+
+	return reviews, page, nil
+}
+
+func (r *GormReviewRepository) GetByID(id uint) (models.Review, error) {
+	review := models.Review{}
+	if err := r.db.Scopes(withReviewer).First(&review, id).Error; err != nil {
+		return review, translateError(err, ErrReviewNotFound)
+	}
+
+	return review, nil
+}
+
+// Create stores the review of review.UserID for review.BookID, which must
+// not be deleted.
+func (r *GormReviewRepository) Create(review *models.Review) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.User{}, review.UserID).Error; err != nil {
+			return translateError(err, ErrUserNotFound)
+		}
+		if err := tx.Select("id").First(&models.Book{}, review.BookID).Error; err != nil {
+			return translateError(err, ErrBookNotFound)
+		}
+
+		if err := tx.Omit("User").Create(review).Error; err != nil {
+			return err
+		}
+		if err := addToRating(tx, review.BookID, 1, review.Rating); err != nil {
+			return err
+		}
+		return tx.Scopes(withReviewer).First(review, review.ID).Error
+	})
+	return translateReviewError(err)
+}
+
+// Update writes the rating and text set in changes. The review's row is
+// locked while the rating is changed, so concurrent edits cannot leave the
+// book's rating sum off by the difference.
+func (r *GormReviewRepository) Update(id uint, changes models.Review) (models.Review, error) {
+	review := models.Review{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, id).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if changes.Rating != 0 && changes.Rating != review.Rating {
+			updates["rating"] = changes.Rating
+			if err := addToRating(tx, review.BookID, 0, changes.Rating-review.Rating); err != nil {
+				return err
+			}
+		}
+		if changes.Text != "" {
+			updates["text"] = changes.Text
+		}
+		if err := tx.Model(&review).Omit("User").Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Scopes(withReviewer).First(&review, id).Error
+	})
+	if err != nil {
+		return review, translateReviewError(err)
+	}
+
+	return review, nil
+}
+
+// Delete removes a review and takes it out of its book's totals.
+func (r *GormReviewRepository) Delete(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		review := models.Review{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return addToRating(tx, review.BookID, -1, -review.Rating)
+	})
+	return translateReviewError(err)
+}
+
+// addToRating adds count reviews and rating stars to the totals of a book,
+// deleted or not.
+func addToRating(tx *gorm.DB, bookId uint, count int, rating int) error {
+	return tx.Unscoped().Model(&models.Book{}).
+		Where("id = ?", bookId).
+		UpdateColumns(map[string]interface{}{
+			"review_count": gorm.Expr("review_count + ?", count),
+			"rating_sum":   gorm.Expr("rating_sum + ?", rating),
+		}).Error
+}
+
+// withReviewer loads the author of reviews, even when their account has
+// been deleted since.
+func withReviewer(db *gorm.DB) *gorm.DB {
+	return db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	})
+}
+
+// translateReviewError reports a unique violation as ErrAlreadyReviewed,
+// the only unique key of reviews.
+func translateReviewError(err error) error {
+	err = translateError(err, ErrReviewNotFound)
+	if errors.Is(err, ErrConflict) {
+		return ErrAlreadyReviewed
+	}
+	return err
+}
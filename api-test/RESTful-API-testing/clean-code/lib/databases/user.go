@@ -1,108 +1,171 @@
 package databases
 
 import (
-	"cleancode/config"
-	"cleancode/middlewares"
 	"cleancode/models"
-)
+	"errors"
 
-func GetAllUsers() (interface{}, int, error) {
-	users := []models.User{}
-	userOutput := []models.OutputUser{}
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
-	result := config.Db.Model(&users).Find(&userOutput)
-	if result.Error != nil {
-		return nil, 0, result.Error
-	}
+type GormUserRepository struct {
+	db *gorm.DB
+}
 
-	if result.RowsAffected > 0 {
-		return userOutput, 1, nil
-	}
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
 
-	return "user data not found", 0, nil
+// UserFilter narrows a user list. Name matches any part of the name ignoring
+// case, while Email and Role must match exactly.
+type UserFilter struct {
+	Name  string
+	Email string
+	Role  string
 }
 
-func GetSingleUser(userId int) (interface{}, int, error) {
-	user := models.User{}
-	userOutput := models.OutputUser{}
+var userList = listQuery{
+	columns: map[string]listColumn{
+		"id":         {expr: "id", field: "ID", kind: idColumn},
+		"name":       {expr: "COALESCE(name, '')", field: "Name", kind: textColumn},
+		"email":      {expr: "COALESCE(email, '')", field: "Email", kind: textColumn},
+		"created_at": {expr: "created_at", field: "CreatedAt", kind: timeColumn},
+	},
+	defaultSort: "id",
+}
 
-	result := config.Db.Model(&user).Find(&userOutput, userId)
-	if result.Error != nil {
-		return nil, 0, result.Error
+func (r *GormUserRepository) List(filter UserFilter, opts ListOptions) ([]models.User, Page, error) {
+	query := r.db.Model(&models.User{})
+	if filter.Name != "" {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '!'", containsPattern(filter.Name))
+	}
+	if filter.Email != "" {
+		query = query.Where("email = ?", models.NormalizeEmail(filter.Email))
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
 	}
 
-	if result.RowsAffected > 0 {
-		return userOutput, 1, nil
+	users := []models.User{}
+	page, err := userList.find(query, opts, &users)
+	if err != nil {
+		return nil, page, translateError(err, ErrUserNotFound)
 	}
 
-	return "user data not found", 0, nil
+	return users, page, nil
 }
 
-func CreateNewUser(user *models.User) (interface{}, error) {
-	result := config.Db.Create(&user)
+func (r *GormUserRepository) GetByID(id uint) (models.User, error) {
+	user := models.User{}
+	result := r.db.First(&user, id)
 	if result.Error != nil {
-		return nil, result.Error
+		return user, translateError(result.Error, ErrUserNotFound)
 	}
 
-	userOutput := models.OutputUser{}
-	userOutput.Name = user.Name
-	userOutput.Email = user.Email
-
-	return userOutput, nil
+	return user, nil
 }
 
-func DeleteUser(userId int) (interface{}, int, error) {
+func (r *GormUserRepository) GetByEmail(email string) (models.User, error) {
 	user := models.User{}
-	result := config.Db.Delete(&user, userId)
+	result := r.db.Where("email = ?", models.NormalizeEmail(email)).First(&user)
 	if result.Error != nil {
-		return nil, 0, result.Error
+		return user, translateError(result.Error, ErrUserNotFound)
 	}
 
-	if result.RowsAffected > 0 {
-		return "deleted", 1, nil
-	}
-	return "user data not found", 0, nil
+	return user, nil
 }
 
-func UpdateUser(userId int, newUser models.User) (interface{}, int, error) {
-	user := models.User{}
-	findResult := config.Db.Find(&user, userId)
-	if findResult.Error != nil {
-		return nil, 0, findResult.Error
+func (r *GormUserRepository) Create(user *models.User) error {
+	user.Email = models.NormalizeEmail(user.Email)
+	result := r.db.Create(user)
+	return translateUserError(result.Error)
+}
+
+// Update writes the fields set in changes. A new password revokes every
+// refresh token of the user in the same transaction, so sessions started
+// with the old password end with it.
+func (r *GormUserRepository) Update(id uint, changes models.User) (models.User, error) {
+	user, err := r.GetByID(id)
+	if err != nil {
+		return user, err
 	}
 
-	if findResult.RowsAffected > 0 {
-		updatedResult := config.Db.Model(&user).Updates(newUser)
-		if updatedResult.Error != nil {
-			return nil, 0, updatedResult.Error
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(userUpdates(changes)).Error; err != nil {
+			return err
 		}
-
-		userOutput := models.OutputUser{}
-		userOutput.Name = user.Name
-		userOutput.Email = user.Email
-
-		return userOutput, 1, nil
+		if changes.Password == "" {
+			return nil
+		}
+		return revokeUserRefreshTokens(tx, id)
+	})
+	if err != nil {
+		return user, translateUserError(err)
 	}
 
-	return "user data not found", 0, nil
+	return user, nil
 }
 
-func LoginUsers(user *models.User) (interface{}, error) {
-	result := config.Db.Where("email = ? AND password = ?", user.Email, user.Password).First(user)
-	if result.Error != nil {
-		return nil, result.Error
+// userUpdates picks the columns Update may write from the non-empty fields of
+// changes. The role has its own method, and the ID, timestamps and soft
+// delete marker are never copied.
+func userUpdates(changes models.User) map[string]interface{} {
+	updates := map[string]interface{}{}
+	if changes.Name != "" {
+		updates["name"] = changes.Name
+	}
+	if changes.Email != "" {
+		updates["email"] = models.NormalizeEmail(changes.Email)
 	}
+	if changes.Password != "" {
+		updates["password"] = changes.Password
+	}
+	return updates
+}
 
-	var err error
-	user.Token, err = middlewares.CreateToken(int(user.ID))
+func (r *GormUserRepository) UpdateRole(id uint, role string) (models.User, error) {
+	user, err := r.GetByID(id)
 	if err != nil {
-		return nil, err
+		return user, err
 	}
 
-	saveToken := config.Db.Save(&user)
-	if saveToken.Error != nil {
-		return nil, saveToken.Error
+	result := r.db.Model(&user).Update("role", role)
+	if result.Error != nil {
+		return user, translateError(result.Error, ErrUserNotFound)
 	}
 
 	return user, nil
 }
+
+func (r *GormUserRepository) UpdatePassword(id uint, hashed string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("password", hashed)
+	return translateError(result.Error, ErrUserNotFound)
+}
+
+// Delete soft deletes an account and releases its email, so the address can
+// be registered again.
+func (r *GormUserRepository) Delete(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "email").First(&user, id).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&user).UpdateColumn("email", models.ReleasedEmail(user.ID, user.Email)).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	return translateError(err, ErrUserNotFound)
+}
+
+// translateUserError reports a unique violation as ErrEmailTaken, the only
+// unique column a caller can write.
+func translateUserError(err error) error {
+	err = translateError(err, ErrUserNotFound)
+	if errors.Is(err, ErrConflict) {
+		return ErrEmailTaken
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+// Package isbn checks International Standard Book Numbers and converts them
+// to the one form books are stored under.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid is returned for a number that is not a well formed ISBN-10 or
+// ISBN-13 with a correct check digit.
+var ErrInvalid = errors.New("invalid ISBN")
+
+// Normalize returns the ISBN-13 of an ISBN-10 or ISBN-13, so both forms of
+// the same book compare equal. Hyphens and spaces are ignored, and the check
+// digit of an ISBN-10 may be a lower case x.
+func Normalize(raw string) (string, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(raw))
+
+	switch len(digits) {
+	case 10:
+		if !isDigits(digits[:9]) || checkDigit10(digits[:9]) != digits[9] {
+			return "", ErrInvalid
+		}
+		body := "978" + digits[:9]
+		return body + string(checkDigit13(body)), nil
+	case 13:
+		if !isDigits(digits) || checkDigit13(digits[:12]) != digits[12] {
+			return "", ErrInvalid
+		}
+		return digits, nil
+	}
+	return "", ErrInvalid
+}
+
+// Valid reports whether raw is an ISBN-10 or ISBN-13 accepted by Normalize.
+func Valid(raw string) bool {
+	_, err := Normalize(raw)
+	return err == nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDigit10 weighs the nine digits 10 down to 2; the check digit makes
+// the sum divisible by 11, with X standing for 10.
+func checkDigit10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 weighs the twelve digits alternately 1 and 3; the check digit
+// makes the sum divisible by 10.
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(body[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
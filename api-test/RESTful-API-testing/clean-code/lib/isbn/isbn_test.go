@@ -0,0 +1,36 @@
+package isbn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		expected string
+	}{
+		{"9780306406157", "9780306406157"},
+		{"978-0-306-40615-7", "9780306406157"},
+		{"0306406152", "9780306406157"},
+		{"0-306-40615-2", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"0 8044 2957 x", "9780804429573"},
+	}
+
+	for _, testCase := range testCases {
+		normalized, err := Normalize(testCase.raw)
+		if assert.NoError(t, err, testCase.raw) {
+			assert.Equal(t, testCase.expected, normalized, testCase.raw)
+		}
+	}
+}
+
+func TestNormalizeRejectsInvalid(t *testing.T) {
+	for _, raw := range []string{"", "9780306406158", "0306406153", "030640615", "97803064061570", "X306406152", "978030640615X", "abcdefghij"} {
+		_, err := Normalize(raw)
+		assert.Equal(t, ErrInvalid, err, raw)
+		assert.False(t, Valid(raw), raw)
+	}
+}
@@ -0,0 +1,164 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Migration{
+		Version: 2,
+		Name:    "unique_user_email",
+		Up:      uniqueUserEmailUp,
+		Down:    uniqueUserEmailDown,
+	})
+}
+
+// duplicateEmail is an address shared by several accounts once emails are
+// compared case-insensitively.
+type duplicateEmail struct {
+	Email   string
+	UserIDs string
+}
+
+// releasedEmailPrefix starts the email of a deleted account once it is
+// released, followed by the account's id and a colon. Deleting an account
+// releases its email the same way.
+const releasedEmailPrefix = "deleted:"
+
+// uniqueUserEmailUp releases the emails of soft deleted accounts, lowercases
+// every email and adds a unique index on it. It refuses to run while live
+// accounts share an address, listing them so they can be merged or renamed
+// first.
+func uniqueUserEmailUp(tx *gorm.DB) error {
+	if err := releaseDeletedEmails(tx); err != nil {
+		return err
+	}
+
+	duplicates, err := findDuplicateEmails(tx)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		problems := make([]string, 0, len(duplicates))
+		for _, duplicate := range duplicates {
+			problems = append(problems, fmt.Sprintf("%s (users %s)", duplicate.Email, duplicate.UserIDs))
+		}
+		return fmt.Errorf("cannot add a unique index on users.email, %d addresses belong to several accounts: %s",
+			len(duplicates), strings.Join(problems, ", "))
+	}
+
+	statements := []string{"UPDATE users SET email = LOWER(TRIM(email))"}
+	if tx.Dialector.Name() == "mysql" {
+		// MySQL cannot index LONGTEXT without a prefix length
+		statements = append(statements, "ALTER TABLE users MODIFY email VARCHAR(255)")
+	}
+	statements = append(statements, "CREATE UNIQUE INDEX idx_users_email ON users (email)")
+	return execAll(tx, statements)
+}
+
+// uniqueUserEmailDown drops the index and gives deleted accounts back their
+// emails. Emails stay lowercased.
+func uniqueUserEmailDown(tx *gorm.DB) error {
+	statements := []string{"DROP INDEX idx_users_email"}
+	if tx.Dialector.Name() == "mysql" {
+		statements = []string{
+			"DROP INDEX idx_users_email ON users",
+			"ALTER TABLE users MODIFY email LONGTEXT",
+		}
+	}
+	if err := execAll(tx, statements); err != nil {
+		return err
+	}
+	return restoreDeletedEmails(tx)
+}
+
+// releaseDeletedEmails rewrites the email of every soft deleted account to
+// releasedEmailPrefix, the account's id and the email, cut to the 255
+// characters the column holds. Emails released already are left alone.
+func releaseDeletedEmails(tx *gorm.DB) error {
+	var users []struct {
+		ID    uint
+		Email string
+	}
+	err := tx.Raw("SELECT id, email FROM users WHERE deleted_at IS NOT NULL AND email IS NOT NULL AND email NOT LIKE ?",
+		releasedEmailPrefix+"%").Scan(&users).Error
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		released := fmt.Sprintf("%s%d:%s", releasedEmailPrefix, user.ID, user.Email)
+		if len(released) > 255 {
+			released = released[:255]
+		}
+		if err := tx.Exec("UPDATE users SET email = ? WHERE id = ?", released, user.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreDeletedEmails undoes releaseDeletedEmails, and the releases done by
+// deleting accounts since.
+func restoreDeletedEmails(tx *gorm.DB) error {
+	var users []struct {
+		ID    uint
+		Email string
+	}
+	err := tx.Raw("SELECT id, email FROM users WHERE email LIKE ?", releasedEmailPrefix+"%").Scan(&users).Error
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		prefix := fmt.Sprintf("%s%d:", releasedEmailPrefix, user.ID)
+		if !strings.HasPrefix(user.Email, prefix) {
+			continue
+		}
+		email := strings.TrimPrefix(user.Email, prefix)
+		if err := tx.Exec("UPDATE users SET email = ? WHERE id = ?", email, user.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findDuplicateEmails(tx *gorm.DB) ([]duplicateEmail, error) {
+	var rows []struct {
+		ID    uint
+		Email string
+	}
+	err := tx.Raw("SELECT id, LOWER(TRIM(email)) AS email FROM users WHERE email IS NOT NULL ORDER BY id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string][]string{}
+	var order []string
+	for _, row := range rows {
+		if _, ok := ids[row.Email]; !ok {
+			order = append(order, row.Email)
+		}
+		ids[row.Email] = append(ids[row.Email], fmt.Sprint(row.ID))
+	}
+
+	var duplicates []duplicateEmail
+	for _, email := range order {
+		if len(ids[email]) > 1 {
+			duplicates = append(duplicates, duplicateEmail{Email: email, UserIDs: strings.Join(ids[email], ", ")})
+		}
+	}
+	return duplicates, nil
+}
+
+func execAll(tx *gorm.DB, statements []string) error {
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Migration{
+		Version: 4,
+		Name:    "book_published_date",
+		Up:      bookPublishedDateUp,
+		Down:    bookPublishedDateDown,
+	})
+}
+
+// publishedLayouts are the spellings of a publication date accepted from
+// the free text column, most specific first. A missing day or month is
+// taken as the first.
+var publishedLayouts = []string{
+	"2006-01-02",
+	"2006-1-2",
+	"2006/01/02",
+	"2006/1/2",
+	"January 2, 2006",
+	"2 January 2006",
+	"2006-01",
+	"2006/01",
+	"January 2006",
+	"2006",
+}
+
+// bookPublishedDateUp rewrites books.published_at from free text to a DATE.
+// Every value is first rewritten as YYYY-MM-DD, and blank ones as NULL. It
+// refuses to run while a value cannot be read as a date, listing the books
+// so they can be corrected first. SQLite keeps its TEXT column, since
+// YYYY-MM-DD text already sorts and compares as a date.
+func bookPublishedDateUp(tx *gorm.DB) error {
+	var rows []struct {
+		ID          uint
+		PublishedAt sql.NullString
+	}
+	if err := tx.Raw("SELECT id, published_at FROM books ORDER BY id").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	var problems []string
+	dates := map[uint]interface{}{}
+	for _, row := range rows {
+		value := strings.TrimSpace(row.PublishedAt.String)
+		if value == "" {
+			dates[row.ID] = nil
+			continue
+		}
+
+		date, ok := parsePublished(value)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%q (book %d)", value, row.ID))
+			continue
+		}
+		dates[row.ID] = date
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("cannot convert books.published_at to a date, %d values are not dates: %s",
+			len(problems), strings.Join(problems, ", "))
+	}
+
+	for id, date := range dates {
+		if err := tx.Exec("UPDATE books SET published_at = ? WHERE id = ?", date, id).Error; err != nil {
+			return err
+		}
+	}
+
+	switch tx.Dialector.Name() {
+	case "postgres":
+		return execAll(tx, []string{"ALTER TABLE books ALTER COLUMN published_at TYPE DATE USING published_at::date"})
+	case "mysql":
+		return execAll(tx, []string{"ALTER TABLE books MODIFY published_at DATE NULL"})
+	}
+	return nil
+}
+
+// bookPublishedDateDown turns the column back into text. The dates keep
+// their YYYY-MM-DD spelling; the original free text is not restored.
+func bookPublishedDateDown(tx *gorm.DB) error {
+	switch tx.Dialector.Name() {
+	case "postgres":
+		return execAll(tx, []string{"ALTER TABLE books ALTER COLUMN published_at TYPE TEXT USING TO_CHAR(published_at, 'YYYY-MM-DD')"})
+	case "mysql":
+		return execAll(tx, []string{"ALTER TABLE books MODIFY published_at LONGTEXT"})
+	}
+	return nil
+}
+
+func parsePublished(value string) (string, bool) {
+	for _, layout := range publishedLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
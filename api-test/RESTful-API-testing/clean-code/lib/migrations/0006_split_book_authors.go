@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Migration{
+		Version: 6,
+		Name:    "split_book_authors",
+		Up:      splitBookAuthorsUp,
+		Down:    splitBookAuthorsDown,
+	})
+}
+
+type splitAuthor struct {
+	ID        uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+}
+
+type splitBookAuthor struct {
+	BookID   uint
+	AuthorID uint
+	Position int
+}
+
+// splitBookAuthorsUp creates an author for every name found in the books'
+// bylines and credits the books to them. Names differing only in case are
+// taken as one author, spelled as first seen. The bylines are left as they
+// are.
+func splitBookAuthorsUp(tx *gorm.DB) error {
+	var books []struct {
+		ID     uint
+		Author sql.NullString
+	}
+	if err := tx.Raw("SELECT id, author FROM books ORDER BY id").Scan(&books).Error; err != nil {
+		return err
+	}
+
+	authorIds := map[string]uint{}
+	now := time.Now()
+	for _, book := range books {
+		for position, name := range splitAuthorNames(book.Author.String) {
+			key := strings.ToLower(name)
+			if _, ok := authorIds[key]; !ok {
+				author := splitAuthor{CreatedAt: now, UpdatedAt: now, Name: name}
+				if err := tx.Table("authors").Create(&author).Error; err != nil {
+					return err
+				}
+				authorIds[key] = author.ID
+			}
+
+			link := splitBookAuthor{BookID: book.ID, AuthorID: authorIds[key], Position: position}
+			if err := tx.Table("book_authors").Create(&link).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitBookAuthorsDown forgets every author, including those added since,
+// leaving the bylines in books.author as the only record.
+func splitBookAuthorsDown(tx *gorm.DB) error {
+	return execAll(tx, []string{"DELETE FROM book_authors", "DELETE FROM authors"})
+}
+
+var splitAuthorSeparators = regexp.MustCompile(`\s*(?:,|;|&|\band\b)\s*`)
+
+// splitAuthorNames splits a free text byline into author names, dropping
+// blanks and names repeated in a different case. It is a copy of
+// models.SplitAuthorNames as it was when this migration was written, so
+// later changes to the model leave the migration as it was.
+func splitAuthorNames(byline string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range splitAuthorSeparators.Split(byline, -1) {
+		name = strings.Join(strings.Fields(name), " ")
+		key := strings.ToLower(name)
+		if name != "" && !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Migration{
+		Version: 10,
+		Name:    "book_copies_from_counts",
+		Up:      bookCopiesFromCountsUp,
+		Down:    bookCopiesFromCountsDown,
+	})
+}
+
+// The condition and statuses of copies as models named them when this
+// migration was written.
+const (
+	countedConditionGood = "good"
+	countedCopyAvailable = "available"
+	countedCopyOnLoan    = "on-loan"
+)
+
+type countedCopy struct {
+	ID        uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	BookID    uint
+	Barcode   string
+	Condition string
+	Status    string
+}
+
+// bookCopiesFromCountsUp gives every book as many copies as books.copies
+// counts, barcoded LEGACY-<book id>-<n> until staff relabel them, and lends
+// the first of them to the loans still out, oldest first. A book with more
+// loans out than copies gets a copy for each loan.
+func bookCopiesFromCountsUp(tx *gorm.DB) error {
+	var books []struct {
+		ID     uint
+		Copies int
+	}
+	if err := tx.Raw("SELECT id, copies FROM books ORDER BY id").Scan(&books).Error; err != nil {
+		return err
+	}
+
+	var loans []struct {
+		ID     uint
+		BookID uint
+	}
+	if err := tx.Raw("SELECT id, book_id FROM loans WHERE returned_at IS NULL ORDER BY id").Scan(&loans).Error; err != nil {
+		return err
+	}
+	loansOf := map[uint][]uint{}
+	for _, loan := range loans {
+		loansOf[loan.BookID] = append(loansOf[loan.BookID], loan.ID)
+	}
+
+	now := time.Now()
+	for _, book := range books {
+		out := loansOf[book.ID]
+		copies := book.Copies
+		if copies < len(out) {
+			copies = len(out)
+			if err := tx.Exec("UPDATE books SET copies = ? WHERE id = ?", copies, book.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		for n := 0; n < copies; n++ {
+			counted := countedCopy{
+				CreatedAt: now,
+				UpdatedAt: now,
+				BookID:    book.ID,
+				Barcode:   fmt.Sprintf("LEGACY-%d-%d", book.ID, n+1),
+				Condition: countedConditionGood,
+				Status:    countedCopyAvailable,
+			}
+			if n < len(out) {
+				counted.Status = countedCopyOnLoan
+			}
+			if err := tx.Table("book_copies").Create(&counted).Error; err != nil {
+				return err
+			}
+			if n < len(out) {
+				if err := tx.Exec("UPDATE loans SET copy_id = ? WHERE id = ?", counted.ID, out[n]).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// bookCopiesFromCountsDown forgets every copy, including those added since,
+// leaving books.copies as the only record.
+func bookCopiesFromCountsDown(tx *gorm.DB) error {
+	return execAll(tx, []string{"UPDATE loans SET copy_id = NULL", "DELETE FROM book_copies"})
+}
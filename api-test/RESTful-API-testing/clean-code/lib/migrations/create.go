@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialects are the databases every SQL migration is written for.
+var Dialects = []string{"mysql", "postgres", "sqlite"}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create adds empty up and down files named after name for every dialect
+// under dir (normally lib/migrations/sql), numbered one past the highest
+// version in use, and returns their paths.
+func Create(dir, name string) ([]string, error) {
+	slug := strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return nil, fmt.Errorf("migration name %q has no letters or digits", name)
+	}
+
+	version, err := nextVersion(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, dialect := range Dialects {
+		if err := os.MkdirAll(filepath.Join(dir, dialect), 0o755); err != nil {
+			return paths, err
+		}
+
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s.%s.sql", version, slug, direction))
+			contents := fmt.Sprintf("-- %04d_%s %s migration for %s\n", version, slug, direction, dialect)
+			if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+func nextVersion(dir string) (int64, error) {
+	var highest int64
+	for version := range registered {
+		if version > highest {
+			highest = version
+		}
+	}
+
+	for _, dialect := range Dialects {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		for _, entry := range entries {
+			match := fileName.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+			version, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			if version > highest {
+				highest = version
+			}
+		}
+	}
+	return highest + 1, nil
+}
@@ -0,0 +1,373 @@
+// Package migrations keeps the database schema under version control.
+//
+// A migration is either a pair of SQL files embedded from sql/<dialect>,
+// named NNNN_name.up.sql and NNNN_name.down.sql, or Go code registered with
+// Register for changes SQL cannot express, such as reshaping existing data.
+// Applied versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var sqlFiles embed.FS
+
+// ErrSchemaBehind is returned by RequireCurrent when migrations are pending.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migration is one step of the schema history. Down is nil for a migration
+// that cannot be rolled back.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Status reports whether a migration has been applied. Missing marks a
+// version recorded in the database that this build does not know about.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Missing   bool
+}
+
+// schemaMigration is a row of the schema_migrations table.
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var registered = map[int64]Migration{}
+
+// Register adds a Go migration, applied on every dialect. It is meant to be
+// called from init and panics on a version that is already taken.
+func Register(m Migration) {
+	if _, ok := registered[m.Version]; ok {
+		panic(fmt.Sprintf("migrations: version %d registered twice", m.Version))
+	}
+	registered[m.Version] = m
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New loads the migrations for the dialect of db.
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load returns the SQL migrations of dialect merged with the registered Go
+// migrations, ordered by version.
+func Load(dialect string) ([]Migration, error) {
+	byVersion := map[int64]Migration{}
+	for version, m := range registered {
+		byVersion[version] = m
+	}
+
+	files, err := loadSQL(sqlFiles, path.Join("sql", dialect))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range files {
+		if _, ok := byVersion[m.Version]; ok {
+			return nil, fmt.Errorf("migrations: version %d is both a SQL and a Go migration", m.Version)
+		}
+		byVersion[m.Version] = m
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Status lists every known migration followed by applied versions this build
+// does not know.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range applied {
+		statuses = append(statuses, Status{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: row.AppliedAt, Missing: true})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return !statuses[i].Missing && statuses[j].Missing
+	})
+	return statuses, nil
+}
+
+// Pending returns the migrations not applied yet, oldest first.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// RequireCurrent fails with ErrSchemaBehind when migrations are pending, so
+// the server never runs against a schema older than its code.
+func (m *Migrator) RequireCurrent() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations starting at %04d_%s, run \"migrate up\"",
+			ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the ones applied. A failed migration is rolled back with its
+// version left unrecorded, except on MySQL: it commits every DDL statement
+// as it runs, so schema changes made before the failing statement stay and
+// must be undone by hand before the migration is run again.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns the ones rolled back. As with Up, a failing down migration on
+// MySQL keeps whatever DDL it ran before the failure.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[int64]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	if steps < len(versions) {
+		versions = versions[:steps]
+	}
+
+	var rolledBack []Migration
+	for _, version := range versions {
+		migration, ok := known[version]
+		if !ok {
+			return rolledBack, fmt.Errorf("migration %04d_%s is not known to this build", version, applied[version].Name)
+		}
+		if migration.Down == nil {
+			return rolledBack, fmt.Errorf("migration %04d_%s cannot be rolled back", version, migration.Name)
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, version).Error
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
+// Reset rolls back every applied migration and applies them all again,
+// leaving an empty database at the latest schema.
+func (m *Migrator) Reset() error {
+	if _, err := m.Down(len(m.migrations)); err != nil {
+		return err
+	}
+	_, err := m.Up()
+	return err
+}
+
+// applied returns the rows of schema_migrations by version, creating the
+// table on first use.
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	err := m.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version BIGINT NOT NULL PRIMARY KEY, " +
+		"name VARCHAR(255) NOT NULL, " +
+		"applied_at TIMESTAMP NOT NULL)").Error
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// loadSQL reads the migration files in dir. Every version needs an up file;
+// a missing down file makes the migration irreversible.
+func loadSQL(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("migrations: no migrations for dialect %q", path.Base(dir))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	hasUp := map[int64]bool{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("migrations: unexpected file %s", path.Join(dir, entry.Name()))
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrations: version %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = execSQL(string(contents))
+			hasUp[version] = true
+		} else {
+			migration.Down = execSQL(string(contents))
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, migration := range byVersion {
+		if !hasUp[version] {
+			return nil, fmt.Errorf("migrations: version %d in %s has no up file", version, dir)
+		}
+		migrations = append(migrations, *migration)
+	}
+	return migrations, nil
+}
+
+// execSQL runs the statements of a migration file one at a time, since not
+// every driver accepts several statements in one Exec.
+func execSQL(contents string) func(tx *gorm.DB) error {
+	statements := splitStatements(contents)
+	return func(tx *gorm.DB) error {
+		return execAll(tx, statements)
+	}
+}
+
+// splitStatements splits contents on semicolons ending a line and drops
+// "--" comment lines. Statements must not contain such semicolons inside
+// string literals or function bodies.
+func splitStatements(contents string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(contents, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,464 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDb(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrations.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestEveryDialectHasTheSameMigrations(t *testing.T) {
+	sqlite, err := Load("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dialect := range Dialects {
+		migrations, err := Load(dialect)
+		if assert.NoError(t, err, dialect) && assert.Len(t, migrations, len(sqlite), dialect) {
+			for i, migration := range migrations {
+				assert.Equal(t, sqlite[i].Version, migration.Version, dialect)
+				assert.Equal(t, sqlite[i].Name, migration.Name, dialect)
+				assert.NotNil(t, migration.Down, "%s %04d has no down migration", dialect, migration.Version)
+			}
+		}
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(migrator.Migrations())
+
+	assert.True(t, errors.Is(migrator.RequireCurrent(), ErrSchemaBehind))
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, total)
+	assert.NoError(t, migrator.RequireCurrent())
+	assert.True(t, db.Migrator().HasTable("books"))
+
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status()
+	if assert.NoError(t, err) && assert.Len(t, statuses, total) {
+		for _, status := range statuses {
+			assert.True(t, status.Applied)
+			assert.False(t, status.AppliedAt.IsZero())
+		}
+	}
+
+	rolledBack, err := migrator.Down(total)
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, total)
+	assert.False(t, db.Migrator().HasTable("books"))
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, total)
+}
+
+func TestDownRefusesUnknownVersions(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&schemaMigration{Version: 9999, Name: "from_the_future"}).Error)
+
+	statuses, err := migrator.Status()
+	if assert.NoError(t, err) {
+		last := statuses[len(statuses)-1]
+		assert.Equal(t, int64(9999), last.Version)
+		assert.True(t, last.Missing)
+	}
+
+	_, err = migrator.Down(1)
+	assert.Error(t, err)
+}
+
+func TestUpStopsAtFailingMigration(t *testing.T) {
+	db := openTestDb(t)
+	migrator := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "create_shelves", Up: execSQL("CREATE TABLE shelves (id INTEGER);")},
+		{Version: 2, Name: "broken", Up: execSQL("CREATE TABLE shelves (id INTEGER);")},
+	}}
+
+	applied, err := migrator.Up()
+	assert.Error(t, err)
+	assert.Len(t, applied, 1)
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, int64(2), pending[0].Version)
+	}
+}
+
+func TestLoadSQL(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/sqlite/0001_first.up.sql":     {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"sql/sqlite/0001_first.down.sql":   {Data: []byte("DROP TABLE a;")},
+		"sql/sqlite/0002_second.up.sql":    {Data: []byte("CREATE TABLE b (id INTEGER);")},
+		"sql/broken/0001_first.down.sql":   {Data: []byte("DROP TABLE a;")},
+		"sql/renamed/0001_first.up.sql":    {Data: []byte("")},
+		"sql/renamed/0001_second.down.sql": {Data: []byte("")},
+	}
+
+	migrations, err := loadSQL(fsys, "sql/sqlite")
+	if assert.NoError(t, err) && assert.Len(t, migrations, 2) {
+		for _, migration := range migrations {
+			assert.Equal(t, migration.Version == 1, migration.Down != nil)
+		}
+	}
+
+	_, err = loadSQL(fsys, "sql/broken")
+	assert.Error(t, err)
+	_, err = loadSQL(fsys, "sql/renamed")
+	assert.Error(t, err)
+	_, err = loadSQL(fsys, "sql/oracle")
+	assert.Error(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	contents := `-- a comment;
+CREATE TABLE a (
+	id INTEGER
+);
+
+CREATE INDEX idx_a_id ON a (id);
+DROP TABLE b`
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n\tid INTEGER\n)",
+		"CREATE INDEX idx_a_id ON a (id)",
+		"DROP TABLE b",
+	}, splitStatements(contents))
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	first, err := nextVersion(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := Create(dir, "Add ISBN to books")
+	if assert.NoError(t, err) {
+		assert.Len(t, paths, len(Dialects)*2)
+		assert.Contains(t, paths, filepath.Join(dir, "postgres", fmt.Sprintf("%04d_add_isbn_to_books.up.sql", first)))
+	}
+
+	paths, err = Create(dir, "second")
+	if assert.NoError(t, err) {
+		assert.Contains(t, paths, filepath.Join(dir, "sqlite", fmt.Sprintf("%04d_second.down.sql", first+1)))
+	}
+
+	migrations, err := loadSQL(os.DirFS(dir), "mysql")
+	if assert.NoError(t, err) {
+		assert.Len(t, migrations, 2)
+	}
+
+	_, err = Create(dir, "!!!")
+	assert.Error(t, err)
+
+	_, err = fs.Stat(os.DirFS(dir), fmt.Sprintf("sqlite/%04d_.up.sql", first+2))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestUniqueUserEmailReportsDuplicates(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := &Migrator{db: db, migrations: migrator.Migrations()[:1]}
+	_, err = before.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range []string{"alta@gmail.com", "budi@gmail.com", " ALTA@gmail.com", "Budi@Gmail.com", "cici@gmail.com"} {
+		assert.NoError(t, db.Exec("INSERT INTO users (email) VALUES (?)", email).Error)
+	}
+
+	_, err = migrator.Up()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "2 addresses belong to several accounts: alta@gmail.com (users 1, 3), budi@gmail.com (users 2, 4)")
+	}
+	assert.True(t, errors.Is(migrator.RequireCurrent(), ErrSchemaBehind))
+
+	assert.NoError(t, db.Exec("DELETE FROM users WHERE id IN (3, 4)").Error)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	var emails []string
+	assert.NoError(t, db.Raw("SELECT email FROM users ORDER BY id").Scan(&emails).Error)
+	assert.Equal(t, []string{"alta@gmail.com", "budi@gmail.com", "cici@gmail.com"}, emails)
+	assert.Error(t, db.Exec("INSERT INTO users (email) VALUES ('cici@gmail.com')").Error)
+}
+
+func TestBookPublishedDateConvertsText(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := &Migrator{db: db, migrations: migrator.Migrations()[:3]}
+	_, err = before.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, published := range []string{"2021-03-01", "2021", "May 2019", "someday", "", "1687/7/5", "last year"} {
+		assert.NoError(t, db.Exec("INSERT INTO books (title, published_at) VALUES ('book', ?)", published).Error)
+	}
+
+	_, err = migrator.Up()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `2 values are not dates: "someday" (book 4), "last year" (book 7)`)
+	}
+
+	assert.NoError(t, db.Exec("UPDATE books SET published_at = '2020-01-01' WHERE id IN (4, 7)").Error)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	var published []string
+	assert.NoError(t, db.Raw("SELECT COALESCE(published_at, 'none') FROM books ORDER BY id").Scan(&published).Error)
+	assert.Equal(t, []string{"2021-03-01", "2021-01-01", "2019-05-01", "2020-01-01", "none", "1687-07-05", "2020-01-01"}, published)
+}
+
+func TestSplitBookAuthors(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := &Migrator{db: db, migrations: migrator.Migrations()[:5]}
+	_, err = before.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, author := range []string{"Kernighan & Ritchie", "", "ritchie", "Abelson and Sussman"} {
+		assert.NoError(t, db.Exec("INSERT INTO books (title, author) VALUES ('book', ?)", author).Error)
+	}
+
+	split := &Migrator{db: db, migrations: migrator.Migrations()[:6]}
+	_, err = split.Up()
+	assert.NoError(t, err)
+
+	var authors []string
+	assert.NoError(t, db.Raw("SELECT name FROM authors ORDER BY id").Scan(&authors).Error)
+	assert.Equal(t, []string{"Kernighan", "Ritchie", "Abelson", "Sussman"}, authors)
+
+	var credits []struct {
+		BookID   uint
+		AuthorID uint
+		Position int
+	}
+	assert.NoError(t, db.Raw("SELECT book_id, author_id, position FROM book_authors ORDER BY book_id, position").Scan(&credits).Error)
+	assert.Equal(t, []struct {
+		BookID   uint
+		AuthorID uint
+		Position int
+	}{{1, 1, 0}, {1, 2, 1}, {3, 2, 0}, {4, 3, 0}, {4, 4, 1}}, credits)
+
+	_, err = split.Down(1)
+	assert.NoError(t, err)
+	var count int64
+	assert.NoError(t, db.Table("authors").Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestBookCopiesFromCounts(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := &Migrator{db: db, migrations: migrator.Migrations()[:9]}
+	_, err = before.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, db.Exec("INSERT INTO users (email) VALUES ('alta@gmail.com')").Error)
+	for _, copies := range []int{2, 1, 0} {
+		assert.NoError(t, db.Exec("INSERT INTO books (title, copies, available_copies) VALUES ('book', ?, ?)", copies, copies).Error)
+	}
+	for _, loan := range []struct {
+		bookId   uint
+		returned bool
+	}{{1, true}, {1, false}, {2, false}} {
+		returned := interface{}(nil)
+		if loan.returned {
+			returned = "2021-01-02 00:00:00"
+		}
+		assert.NoError(t, db.Exec("INSERT INTO loans (user_id, book_id, borrowed_at, due_at, returned_at) VALUES (1, ?, '2021-01-01 00:00:00', '2021-01-15 00:00:00', ?)", loan.bookId, returned).Error)
+	}
+
+	counted := &Migrator{db: db, migrations: migrator.Migrations()[:10]}
+	_, err = counted.Up()
+	assert.NoError(t, err)
+
+	var copies []struct {
+		BookID  uint
+		Barcode string
+		Status  string
+	}
+	assert.NoError(t, db.Raw("SELECT book_id, barcode, status FROM book_copies ORDER BY id").Scan(&copies).Error)
+	assert.Equal(t, []struct {
+		BookID  uint
+		Barcode string
+		Status  string
+	}{{1, "LEGACY-1-1", "on-loan"}, {1, "LEGACY-1-2", "available"}, {2, "LEGACY-2-1", "on-loan"}}, copies)
+
+	var copyIds []uint
+	assert.NoError(t, db.Raw("SELECT COALESCE(copy_id, 0) FROM loans ORDER BY id").Scan(&copyIds).Error)
+	assert.Equal(t, []uint{0, 1, 3}, copyIds)
+
+	_, err = counted.Down(1)
+	assert.NoError(t, err)
+	var count int64
+	assert.NoError(t, db.Table("book_copies").Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+// baselineUser and baselineBook are the models as AutoMigrate created their
+// tables before versioned migrations, when users had no role.
+type baselineUser struct {
+	gorm.Model
+	Name     string
+	Email    string
+	Password string
+	Token    string
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineBook struct {
+	gorm.Model
+	Title        string
+	Author       string
+	Published_at string
+}
+
+func (baselineBook) TableName() string { return "books" }
+
+func TestUpgradeFromAutoMigrateBaseline(t *testing.T) {
+	db := openTestDb(t)
+	if err := db.AutoMigrate(&baselineUser{}, &baselineBook{}); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, db.Create(&baselineUser{Name: "alta", Email: "alta@gmail.com"}).Error)
+	assert.False(t, db.Migrator().HasColumn(&baselineUser{}, "role"))
+
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.RequireCurrent())
+
+	var roles []string
+	assert.NoError(t, db.Raw("SELECT role FROM users ORDER BY id").Scan(&roles).Error)
+	assert.Equal(t, []string{"member"}, roles)
+	assert.NoError(t, db.Exec("INSERT INTO users (email) VALUES ('budi@gmail.com')").Error)
+	assert.NoError(t, db.Exec("UPDATE users SET role = 'admin' WHERE email = 'alta@gmail.com'").Error)
+	assert.NoError(t, db.Raw("SELECT role FROM users ORDER BY id").Scan(&roles).Error)
+	assert.Equal(t, []string{"admin", "member"}, roles)
+}
+
+func TestUserRoleColumnDownAndUp(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, db.Exec("INSERT INTO users (email, role) VALUES ('alta@gmail.com', 'admin')").Error)
+
+	steps := 0
+	for i, migration := range migrator.Migrations() {
+		if migration.Name == "user_role_column" {
+			steps = len(migrator.Migrations()) - i
+		}
+	}
+	_, err = migrator.Down(steps)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasColumn(&baselineUser{}, "role"))
+	assert.True(t, db.Migrator().HasIndex(&baselineUser{}, "idx_users_email"))
+	assert.Error(t, db.Exec("INSERT INTO users (email) VALUES ('alta@gmail.com')").Error)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	var roles []string
+	assert.NoError(t, db.Raw("SELECT role FROM users ORDER BY id").Scan(&roles).Error)
+	assert.Equal(t, []string{"member"}, roles)
+}
+
+func TestUniqueUserEmailReleasesDeletedAccounts(t *testing.T) {
+	db := openTestDb(t)
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := &Migrator{db: db, migrations: migrator.Migrations()[:1]}
+	_, err = before.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, db.Exec("INSERT INTO users (email, deleted_at) VALUES ('Alta@gmail.com', CURRENT_TIMESTAMP)").Error)
+	assert.NoError(t, db.Exec("INSERT INTO users (email) VALUES ('alta@gmail.com')").Error)
+
+	emails := func() []string {
+		var emails []string
+		assert.NoError(t, db.Raw("SELECT email FROM users ORDER BY id").Scan(&emails).Error)
+		return emails
+	}
+
+	total := len(migrator.Migrations())
+	for i := 0; i < 2; i++ {
+		_, err = migrator.Up()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"deleted:1:alta@gmail.com", "alta@gmail.com"}, emails())
+
+		_, err = migrator.Down(total - 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alta@gmail.com", "alta@gmail.com"}, emails())
+	}
+}
@@ -0,0 +1,41 @@
+-- The tables as AutoMigrate used to create them. IF NOT EXISTS lets a
+-- database created before versioned migrations adopt this as its baseline;
+-- columns such a database lacks are added by later migrations.
+CREATE TABLE IF NOT EXISTS users (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	deleted_at DATETIME(3) NULL,
+	name LONGTEXT,
+	email LONGTEXT,
+	password LONGTEXT,
+	PRIMARY KEY (id),
+	INDEX idx_users_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS books (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	deleted_at DATETIME(3) NULL,
+	title LONGTEXT,
+	author LONGTEXT,
+	published_at LONGTEXT,
+	PRIMARY KEY (id),
+	INDEX idx_books_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	user_id BIGINT UNSIGNED,
+	family_id VARCHAR(64),
+	token_hash VARCHAR(64),
+	expires_at DATETIME(3) NULL,
+	used_at DATETIME(3) NULL,
+	revoked_at DATETIME(3) NULL,
+	created_at DATETIME(3) NULL,
+	PRIMARY KEY (id),
+	INDEX idx_refresh_tokens_user_id (user_id),
+	INDEX idx_refresh_tokens_family_id (family_id),
+	UNIQUE INDEX idx_refresh_tokens_token_hash (token_hash)
+);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS book_tags;
+DROP TABLE IF EXISTS tags;
+ALTER TABLE books
+	DROP INDEX idx_books_isbn,
+	DROP COLUMN isbn,
+	DROP COLUMN description,
+	DROP COLUMN language,
+	DROP COLUMN pages,
+	DROP COLUMN publisher,
+	DROP COLUMN cover_url;
@@ -0,0 +1,27 @@
+-- Catalogue details of a book and its tags. isbn holds the ISBN-13 and is
+-- NULL for books without one, and for deleted books, so they do not collide in
+-- the unique index.
+ALTER TABLE books
+	ADD COLUMN isbn VARCHAR(13) NULL,
+	ADD COLUMN description TEXT,
+	ADD COLUMN language VARCHAR(35),
+	ADD COLUMN pages BIGINT,
+	ADD COLUMN publisher VARCHAR(255),
+	ADD COLUMN cover_url VARCHAR(2048),
+	ADD UNIQUE INDEX idx_books_isbn (isbn);
+
+CREATE TABLE tags (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name VARCHAR(50) NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE INDEX idx_tags_name (name)
+);
+
+CREATE TABLE book_tags (
+	book_id BIGINT UNSIGNED NOT NULL,
+	tag_id BIGINT UNSIGNED NOT NULL,
+	PRIMARY KEY (book_id, tag_id),
+	INDEX idx_book_tags_tag_id (tag_id),
+	CONSTRAINT fk_book_tags_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+	CONSTRAINT fk_book_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
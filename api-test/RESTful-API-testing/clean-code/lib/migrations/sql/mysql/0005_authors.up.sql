@@ -0,0 +1,23 @@
+-- Authors and the books they are credited on, in credit order. The byline
+-- in books.author is kept alongside for sorting, filtering and search.
+CREATE TABLE authors (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	deleted_at DATETIME(3) NULL,
+	name VARCHAR(255),
+	bio TEXT,
+	PRIMARY KEY (id),
+	INDEX idx_authors_deleted_at (deleted_at),
+	INDEX idx_authors_name (name)
+);
+
+CREATE TABLE book_authors (
+	book_id BIGINT UNSIGNED NOT NULL,
+	author_id BIGINT UNSIGNED NOT NULL,
+	position INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (book_id, author_id),
+	INDEX idx_book_authors_author_id (author_id),
+	CONSTRAINT fk_book_authors_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+	CONSTRAINT fk_book_authors_author FOREIGN KEY (author_id) REFERENCES authors (id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS loans;
+ALTER TABLE books
+	DROP COLUMN copies,
+	DROP COLUMN available_copies;
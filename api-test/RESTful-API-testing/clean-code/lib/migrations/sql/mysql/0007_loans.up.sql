@@ -0,0 +1,21 @@
+-- Copies of a book the library owns and how many are on the shelf, and the
+-- loans of those copies. Every book stored so far counts as one copy.
+ALTER TABLE books
+	ADD COLUMN copies INT NOT NULL DEFAULT 1,
+	ADD COLUMN available_copies INT NOT NULL DEFAULT 1;
+
+CREATE TABLE loans (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	user_id BIGINT UNSIGNED NOT NULL,
+	book_id BIGINT UNSIGNED NOT NULL,
+	borrowed_at DATETIME(3) NOT NULL,
+	due_at DATETIME(3) NOT NULL,
+	returned_at DATETIME(3) NULL,
+	PRIMARY KEY (id),
+	INDEX idx_loans_user_id (user_id),
+	INDEX idx_loans_book_id (book_id),
+	CONSTRAINT fk_loans_user FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT fk_loans_book FOREIGN KEY (book_id) REFERENCES books (id)
+);
@@ -0,0 +1,17 @@
+-- Reservations queue for a book in the order they were made, so the queue
+-- of a book is its waiting reservations by id.
+CREATE TABLE reservations (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	user_id BIGINT UNSIGNED NOT NULL,
+	book_id BIGINT UNSIGNED NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	ready_at DATETIME(3) NULL,
+	expires_at DATETIME(3) NULL,
+	PRIMARY KEY (id),
+	INDEX idx_reservations_user_id (user_id),
+	INDEX idx_reservations_book_id (book_id, status),
+	CONSTRAINT fk_reservations_user FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT fk_reservations_book FOREIGN KEY (book_id) REFERENCES books (id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE loans DROP FOREIGN KEY fk_loans_copy;
+ALTER TABLE loans DROP COLUMN copy_id;
+DROP TABLE IF EXISTS book_copies;
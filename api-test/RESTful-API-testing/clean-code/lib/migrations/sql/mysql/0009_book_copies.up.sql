@@ -0,0 +1,24 @@
+-- The physical copies of each book. Loans record the copy they lent; loans
+-- older than this migration are matched to copies by the next one.
+-- CONDITION is a reserved word in MySQL, hence the quotes.
+CREATE TABLE book_copies (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	deleted_at DATETIME(3) NULL,
+	book_id BIGINT UNSIGNED NOT NULL,
+	barcode VARCHAR(32) NOT NULL,
+	shelf_location VARCHAR(64) NULL,
+	`condition` VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	PRIMARY KEY (id),
+	INDEX idx_book_copies_deleted_at (deleted_at),
+	INDEX idx_book_copies_book_id (book_id, status),
+	UNIQUE INDEX idx_book_copies_barcode (barcode),
+	CONSTRAINT fk_book_copies_book FOREIGN KEY (book_id) REFERENCES books (id)
+);
+
+ALTER TABLE loans
+	ADD COLUMN copy_id BIGINT UNSIGNED NULL,
+	ADD INDEX idx_loans_copy_id (copy_id),
+	ADD CONSTRAINT fk_loans_copy FOREIGN KEY (copy_id) REFERENCES book_copies (id);
@@ -0,0 +1,17 @@
+-- The fine ledger. Amounts are exact decimals.
+CREATE TABLE fine_entries (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	user_id BIGINT UNSIGNED NOT NULL,
+	loan_id BIGINT UNSIGNED NULL,
+	kind VARCHAR(20) NOT NULL,
+	amount DECIMAL(12, 2) NOT NULL,
+	note VARCHAR(255) NULL,
+	recorded_by BIGINT UNSIGNED NULL,
+	PRIMARY KEY (id),
+	INDEX idx_fine_entries_user_id (user_id),
+	INDEX idx_fine_entries_loan_id (loan_id),
+	CONSTRAINT fk_fine_entries_user FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT fk_fine_entries_loan FOREIGN KEY (loan_id) REFERENCES loans (id),
+	CONSTRAINT fk_fine_entries_recorded_by FOREIGN KEY (recorded_by) REFERENCES users (id)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reviews;
+ALTER TABLE books
+	DROP COLUMN review_count,
+	DROP COLUMN rating_sum;
@@ -0,0 +1,21 @@
+-- Reviews of books, one per user and book. Each book keeps the number of
+-- its reviews and the sum of their ratings, so its average rating is read
+-- without aggregating the reviews.
+ALTER TABLE books
+	ADD COLUMN review_count INT NOT NULL DEFAULT 0,
+	ADD COLUMN rating_sum INT NOT NULL DEFAULT 0;
+
+CREATE TABLE reviews (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	user_id BIGINT UNSIGNED NOT NULL,
+	book_id BIGINT UNSIGNED NOT NULL,
+	rating TINYINT NOT NULL,
+	text TEXT NULL,
+	PRIMARY KEY (id),
+	UNIQUE INDEX idx_reviews_book_user (book_id, user_id),
+	INDEX idx_reviews_user_id (user_id),
+	CONSTRAINT fk_reviews_user FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT fk_reviews_book FOREIGN KEY (book_id) REFERENCES books (id)
+);
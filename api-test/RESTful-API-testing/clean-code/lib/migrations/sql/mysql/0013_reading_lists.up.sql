@@ -0,0 +1,29 @@
+-- Reading lists of users and the books on them in list order. Each list
+-- keeps the number of its books, and public lists are shared by slug.
+CREATE TABLE reading_lists (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	updated_at DATETIME(3) NULL,
+	user_id BIGINT UNSIGNED NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	slug VARCHAR(120) NOT NULL,
+	kind VARCHAR(20) NOT NULL,
+	public BOOLEAN NOT NULL DEFAULT FALSE,
+	book_count INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (id),
+	INDEX idx_reading_lists_user_id (user_id, kind),
+	UNIQUE INDEX idx_reading_lists_slug (slug),
+	CONSTRAINT fk_reading_lists_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE reading_list_items (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	created_at DATETIME(3) NULL,
+	reading_list_id BIGINT UNSIGNED NOT NULL,
+	book_id BIGINT UNSIGNED NOT NULL,
+	position INT NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE INDEX idx_reading_list_items_book (reading_list_id, book_id),
+	CONSTRAINT fk_reading_list_items_list FOREIGN KEY (reading_list_id) REFERENCES reading_lists (id),
+	CONSTRAINT fk_reading_list_items_book FOREIGN KEY (book_id) REFERENCES books (id)
+);
@@ -0,0 +1,38 @@
+-- The tables as AutoMigrate used to create them. IF NOT EXISTS lets a
+-- database created before versioned migrations adopt this as its baseline;
+-- columns such a database lacks are added by later migrations.
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ,
+	name TEXT,
+	email TEXT,
+	password TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS books (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ,
+	title TEXT,
+	author TEXT,
+	published_at TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_books_deleted_at ON books (deleted_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT,
+	family_id VARCHAR(64),
+	token_hash VARCHAR(64),
+	expires_at TIMESTAMPTZ,
+	used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
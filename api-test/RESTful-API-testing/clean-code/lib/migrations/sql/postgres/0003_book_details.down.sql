@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS book_tags;
+DROP TABLE IF EXISTS tags;
+DROP INDEX IF EXISTS idx_books_isbn;
+ALTER TABLE books
+	DROP COLUMN isbn,
+	DROP COLUMN description,
+	DROP COLUMN language,
+	DROP COLUMN pages,
+	DROP COLUMN publisher,
+	DROP COLUMN cover_url;
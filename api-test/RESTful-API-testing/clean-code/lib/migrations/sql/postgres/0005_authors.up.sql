@@ -0,0 +1,20 @@
+-- Authors and the books they are credited on, in credit order. The byline
+-- in books.author is kept alongside for sorting, filtering and search.
+CREATE TABLE authors (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ,
+	name VARCHAR(255),
+	bio TEXT
+);
+CREATE INDEX idx_authors_deleted_at ON authors (deleted_at);
+CREATE INDEX idx_authors_name ON authors (name);
+
+CREATE TABLE book_authors (
+	book_id BIGINT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+	author_id BIGINT NOT NULL REFERENCES authors (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (book_id, author_id)
+);
+CREATE INDEX idx_book_authors_author_id ON book_authors (author_id);
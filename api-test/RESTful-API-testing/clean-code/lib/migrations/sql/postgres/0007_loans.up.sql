@@ -0,0 +1,18 @@
+-- Copies of a book the library owns and how many are on the shelf, and the
+-- loans of those copies. Every book stored so far counts as one copy.
+ALTER TABLE books
+	ADD COLUMN copies INTEGER NOT NULL DEFAULT 1,
+	ADD COLUMN available_copies INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE loans (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	book_id BIGINT NOT NULL REFERENCES books (id),
+	borrowed_at TIMESTAMPTZ NOT NULL,
+	due_at TIMESTAMPTZ NOT NULL,
+	returned_at TIMESTAMPTZ
+);
+CREATE INDEX idx_loans_user_id ON loans (user_id);
+CREATE INDEX idx_loans_book_id ON loans (book_id);
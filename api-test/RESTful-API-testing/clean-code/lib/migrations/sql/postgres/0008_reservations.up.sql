@@ -0,0 +1,14 @@
+-- Reservations queue for a book in the order they were made, so the queue
+-- of a book is its waiting reservations by id.
+CREATE TABLE reservations (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	book_id BIGINT NOT NULL REFERENCES books (id),
+	status VARCHAR(20) NOT NULL,
+	ready_at TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ
+);
+CREATE INDEX idx_reservations_user_id ON reservations (user_id);
+CREATE INDEX idx_reservations_book_id ON reservations (book_id, status);
@@ -0,0 +1,19 @@
+-- The physical copies of each book. Loans record the copy they lent; loans
+-- older than this migration are matched to copies by the next one.
+CREATE TABLE book_copies (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ,
+	book_id BIGINT NOT NULL REFERENCES books (id),
+	barcode VARCHAR(32) NOT NULL,
+	shelf_location VARCHAR(64),
+	condition VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL
+);
+CREATE INDEX idx_book_copies_deleted_at ON book_copies (deleted_at);
+CREATE INDEX idx_book_copies_book_id ON book_copies (book_id, status);
+CREATE UNIQUE INDEX idx_book_copies_barcode ON book_copies (barcode);
+
+ALTER TABLE loans ADD COLUMN copy_id BIGINT REFERENCES book_copies (id);
+CREATE INDEX idx_loans_copy_id ON loans (copy_id);
@@ -0,0 +1,13 @@
+-- The fine ledger. Amounts are exact decimals.
+CREATE TABLE fine_entries (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	loan_id BIGINT REFERENCES loans (id),
+	kind VARCHAR(20) NOT NULL,
+	amount NUMERIC(12, 2) NOT NULL,
+	note VARCHAR(255),
+	recorded_by BIGINT REFERENCES users (id)
+);
+CREATE INDEX idx_fine_entries_user_id ON fine_entries (user_id);
+CREATE INDEX idx_fine_entries_loan_id ON fine_entries (loan_id);
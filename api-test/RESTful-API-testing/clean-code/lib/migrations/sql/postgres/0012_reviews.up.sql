@@ -0,0 +1,18 @@
+-- Reviews of books, one per user and book. Each book keeps the number of
+-- its reviews and the sum of their ratings, so its average rating is read
+-- without aggregating the reviews.
+ALTER TABLE books
+	ADD COLUMN review_count INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN rating_sum INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE reviews (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	book_id BIGINT NOT NULL REFERENCES books (id),
+	rating SMALLINT NOT NULL,
+	text TEXT
+);
+CREATE UNIQUE INDEX idx_reviews_book_user ON reviews (book_id, user_id);
+CREATE INDEX idx_reviews_user_id ON reviews (user_id);
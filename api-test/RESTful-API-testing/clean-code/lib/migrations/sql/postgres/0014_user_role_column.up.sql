@@ -0,0 +1,3 @@
+-- Roles of accounts. Existing accounts, including those of a database that
+-- adopted the initial schema from AutoMigrate, become members.
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member';
@@ -0,0 +1,38 @@
+-- The tables as AutoMigrate used to create them. IF NOT EXISTS lets a
+-- database created before versioned migrations adopt this as its baseline;
+-- columns such a database lacks are added by later migrations.
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	name TEXT,
+	email TEXT,
+	password TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS books (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	title TEXT,
+	author TEXT,
+	published_at TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_books_deleted_at ON books (deleted_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER,
+	family_id VARCHAR(64),
+	token_hash VARCHAR(64),
+	expires_at DATETIME,
+	used_at DATETIME,
+	revoked_at DATETIME,
+	created_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS book_tags;
+DROP TABLE IF EXISTS tags;
+
+-- This SQLite cannot drop columns, so books is rebuilt without them.
+CREATE TABLE books_before_details (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	title TEXT,
+	author TEXT,
+	published_at TEXT
+);
+INSERT INTO books_before_details (id, created_at, updated_at, deleted_at, title, author, published_at)
+	SELECT id, created_at, updated_at, deleted_at, title, author, published_at FROM books;
+DROP TABLE books;
+ALTER TABLE books_before_details RENAME TO books;
+CREATE INDEX idx_books_deleted_at ON books (deleted_at);
@@ -0,0 +1,23 @@
+-- Catalogue details of a book and its tags. isbn holds the ISBN-13 and is
+-- NULL for books without one, and for deleted books, so they do not collide in
+-- the unique index.
+ALTER TABLE books ADD COLUMN isbn VARCHAR(13);
+ALTER TABLE books ADD COLUMN description TEXT;
+ALTER TABLE books ADD COLUMN language VARCHAR(35);
+ALTER TABLE books ADD COLUMN pages INTEGER;
+ALTER TABLE books ADD COLUMN publisher VARCHAR(255);
+ALTER TABLE books ADD COLUMN cover_url VARCHAR(2048);
+CREATE UNIQUE INDEX idx_books_isbn ON books (isbn);
+
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(50) NOT NULL
+);
+CREATE UNIQUE INDEX idx_tags_name ON tags (name);
+
+CREATE TABLE book_tags (
+	book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (book_id, tag_id)
+);
+CREATE INDEX idx_book_tags_tag_id ON book_tags (tag_id);
@@ -0,0 +1,24 @@
+DROP TABLE IF EXISTS loans;
+
+-- This SQLite cannot drop columns, so books is rebuilt without them.
+CREATE TABLE books_before_loans (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	title TEXT,
+	author TEXT,
+	published_at TEXT,
+	isbn VARCHAR(13),
+	description TEXT,
+	language VARCHAR(35),
+	pages INTEGER,
+	publisher VARCHAR(255),
+	cover_url VARCHAR(2048)
+);
+INSERT INTO books_before_loans (id, created_at, updated_at, deleted_at, title, author, published_at, isbn, description, language, pages, publisher, cover_url)
+	SELECT id, created_at, updated_at, deleted_at, title, author, published_at, isbn, description, language, pages, publisher, cover_url FROM books;
+DROP TABLE books;
+ALTER TABLE books_before_loans RENAME TO books;
+CREATE INDEX idx_books_deleted_at ON books (deleted_at);
+CREATE UNIQUE INDEX idx_books_isbn ON books (isbn);
@@ -0,0 +1,17 @@
+-- Copies of a book the library owns and how many are on the shelf, and the
+-- loans of those copies. Every book stored so far counts as one copy.
+ALTER TABLE books ADD COLUMN copies INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE books ADD COLUMN available_copies INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE loans (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	book_id INTEGER NOT NULL REFERENCES books (id),
+	borrowed_at DATETIME NOT NULL,
+	due_at DATETIME NOT NULL,
+	returned_at DATETIME
+);
+CREATE INDEX idx_loans_user_id ON loans (user_id);
+CREATE INDEX idx_loans_book_id ON loans (book_id);
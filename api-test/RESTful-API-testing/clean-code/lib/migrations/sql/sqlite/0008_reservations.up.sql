@@ -0,0 +1,14 @@
+-- Reservations queue for a book in the order they were made, so the queue
+-- of a book is its waiting reservations by id.
+CREATE TABLE reservations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	book_id INTEGER NOT NULL REFERENCES books (id),
+	status VARCHAR(20) NOT NULL,
+	ready_at DATETIME,
+	expires_at DATETIME
+);
+CREATE INDEX idx_reservations_user_id ON reservations (user_id);
+CREATE INDEX idx_reservations_book_id ON reservations (book_id, status);
@@ -0,0 +1,19 @@
+-- This SQLite cannot drop columns, so loans is rebuilt without copy_id.
+CREATE TABLE loans_before_copies (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	book_id INTEGER NOT NULL REFERENCES books (id),
+	borrowed_at DATETIME NOT NULL,
+	due_at DATETIME NOT NULL,
+	returned_at DATETIME
+);
+INSERT INTO loans_before_copies (id, created_at, updated_at, user_id, book_id, borrowed_at, due_at, returned_at)
+	SELECT id, created_at, updated_at, user_id, book_id, borrowed_at, due_at, returned_at FROM loans;
+DROP TABLE loans;
+ALTER TABLE loans_before_copies RENAME TO loans;
+CREATE INDEX idx_loans_user_id ON loans (user_id);
+CREATE INDEX idx_loans_book_id ON loans (book_id);
+
+DROP TABLE IF EXISTS book_copies;
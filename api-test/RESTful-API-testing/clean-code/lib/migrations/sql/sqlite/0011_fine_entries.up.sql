@@ -0,0 +1,15 @@
+-- The fine ledger. Amounts are exact decimals; SQLite has no decimal type
+-- and would round NUMERIC values through floating point, so they are kept
+-- as text here.
+CREATE TABLE fine_entries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	loan_id INTEGER REFERENCES loans (id),
+	kind VARCHAR(20) NOT NULL,
+	amount TEXT NOT NULL,
+	note VARCHAR(255),
+	recorded_by INTEGER REFERENCES users (id)
+);
+CREATE INDEX idx_fine_entries_user_id ON fine_entries (user_id);
+CREATE INDEX idx_fine_entries_loan_id ON fine_entries (loan_id);
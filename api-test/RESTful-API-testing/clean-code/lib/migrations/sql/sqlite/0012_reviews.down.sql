@@ -0,0 +1,26 @@
+DROP TABLE IF EXISTS reviews;
+
+-- This SQLite cannot drop columns, so books is rebuilt without them.
+CREATE TABLE books_before_reviews (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	title TEXT,
+	author TEXT,
+	published_at TEXT,
+	isbn VARCHAR(13),
+	description TEXT,
+	language VARCHAR(35),
+	pages INTEGER,
+	publisher VARCHAR(255),
+	cover_url VARCHAR(2048),
+	copies INTEGER NOT NULL DEFAULT 1,
+	available_copies INTEGER NOT NULL DEFAULT 1
+);
+INSERT INTO books_before_reviews (id, created_at, updated_at, deleted_at, title, author, published_at, isbn, description, language, pages, publisher, cover_url, copies, available_copies)
+	SELECT id, created_at, updated_at, deleted_at, title, author, published_at, isbn, description, language, pages, publisher, cover_url, copies, available_copies FROM books;
+DROP TABLE books;
+ALTER TABLE books_before_reviews RENAME TO books;
+CREATE INDEX idx_books_deleted_at ON books (deleted_at);
+CREATE UNIQUE INDEX idx_books_isbn ON books (isbn);
@@ -0,0 +1,17 @@
+-- Reviews of books, one per user and book. Each book keeps the number of
+-- its reviews and the sum of their ratings, so its average rating is read
+-- without aggregating the reviews.
+ALTER TABLE books ADD COLUMN review_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN rating_sum INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE reviews (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	book_id INTEGER NOT NULL REFERENCES books (id),
+	rating INTEGER NOT NULL,
+	text TEXT
+);
+CREATE UNIQUE INDEX idx_reviews_book_user ON reviews (book_id, user_id);
+CREATE INDEX idx_reviews_user_id ON reviews (user_id);
@@ -0,0 +1,24 @@
+-- Reading lists of users and the books on them in list order. Each list
+-- keeps the number of its books, and public lists are shared by slug.
+CREATE TABLE reading_lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	name VARCHAR(100) NOT NULL,
+	slug VARCHAR(120) NOT NULL,
+	kind VARCHAR(20) NOT NULL,
+	public NUMERIC NOT NULL DEFAULT false,
+	book_count INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_reading_lists_user_id ON reading_lists (user_id, kind);
+CREATE UNIQUE INDEX idx_reading_lists_slug ON reading_lists (slug);
+
+CREATE TABLE reading_list_items (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	reading_list_id INTEGER NOT NULL REFERENCES reading_lists (id),
+	book_id INTEGER NOT NULL REFERENCES books (id),
+	position INTEGER NOT NULL
+);
+CREATE UNIQUE INDEX idx_reading_list_items_book ON reading_list_items (reading_list_id, book_id);
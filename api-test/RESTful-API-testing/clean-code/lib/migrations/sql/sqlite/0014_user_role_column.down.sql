@@ -0,0 +1,17 @@
+-- This SQLite cannot drop columns, so users is rebuilt without role. Columns
+-- AutoMigrate left on an adopted database are not kept.
+CREATE TABLE users_before_role (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at DATETIME,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	name TEXT,
+	email TEXT,
+	password TEXT
+);
+INSERT INTO users_before_role (id, created_at, updated_at, deleted_at, name, email, password)
+	SELECT id, created_at, updated_at, deleted_at, name, email, password FROM users;
+DROP TABLE users;
+ALTER TABLE users_before_role RENAME TO users;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
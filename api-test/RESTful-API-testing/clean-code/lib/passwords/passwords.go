@@ -0,0 +1,81 @@
+package passwords
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cost is the bcrypt work factor used for newly hashed passwords.
+var cost = bcrypt.DefaultCost
+
+// dummyHash is what CompareNothing compares against, hashed at the current
+// cost on first use after the cost changes.
+var (
+	dummyMu   sync.Mutex
+	dummyHash []byte
+)
+
+// SetCost changes the bcrypt work factor used by Hash. Hashes created with a
+// different cost are upgraded the next time their owner logs in.
+func SetCost(newCost int) error {
+	if newCost < bcrypt.MinCost || newCost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, newCost)
+	}
+	cost = newCost
+	return nil
+}
+
+func Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Compare reports whether password matches the stored value and whether the
+// stored value should be replaced with a fresh hash. Stored values that are not
+// bcrypt hashes are treated as legacy plaintext passwords.
+func Compare(stored, password string) (match bool, needsRehash bool) {
+	if !IsHashed(stored) {
+		match = subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+		return match, match
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+		return false, false
+	}
+
+	storedCost, err := bcrypt.Cost([]byte(stored))
+	return true, err != nil || storedCost != cost
+}
+
+// CompareNothing takes as long as Compare with a wrong password against a
+// hash of the current cost. Logins for emails without an account call it, so
+// the response time does not tell which accounts exist.
+func CompareNothing(password string) {
+	bcrypt.CompareHashAndPassword(dummy(), []byte(password))
+}
+
+func dummy() []byte {
+	dummyMu.Lock()
+	defer dummyMu.Unlock()
+
+	if dummyCost, err := bcrypt.Cost(dummyHash); err != nil || dummyCost != cost {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("no account has this password"), cost)
+	}
+	return dummyHash
+}
+
+func IsHashed(stored string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(stored, prefix) {
+			return true
+		}
+	}
+	return false
+}
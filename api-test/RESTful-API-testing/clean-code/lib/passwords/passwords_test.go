@@ -0,0 +1,63 @@
+package passwords
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashAndCompare(t *testing.T) {
+	hashed, err := Hash("urnik123")
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, "urnik123", hashed)
+		assert.True(t, IsHashed(hashed))
+
+		match, needsRehash := Compare(hashed, "urnik123")
+		assert.True(t, match)
+		assert.False(t, needsRehash)
+
+		match, needsRehash = Compare(hashed, "wrong")
+		assert.False(t, match)
+		assert.False(t, needsRehash)
+	}
+}
+
+func TestCompareLegacyPlaintext(t *testing.T) {
+	match, needsRehash := Compare("123", "123")
+	assert.True(t, match)
+	assert.True(t, needsRehash)
+
+	match, needsRehash = Compare("123", "1234")
+	assert.False(t, match)
+	assert.False(t, needsRehash)
+}
+
+func TestCompareCostChanged(t *testing.T) {
+	defer SetCost(bcrypt.DefaultCost)
+
+	hashed, err := Hash("urnik123")
+	assert.NoError(t, err)
+
+	assert.NoError(t, SetCost(bcrypt.MinCost))
+	match, needsRehash := Compare(hashed, "urnik123")
+	assert.True(t, match)
+	assert.True(t, needsRehash)
+}
+
+func TestCompareNothingFollowsCost(t *testing.T) {
+	defer SetCost(bcrypt.DefaultCost)
+
+	CompareNothing("urnik123")
+	assert.NoError(t, SetCost(bcrypt.MinCost))
+	CompareNothing("urnik123")
+
+	dummyCost, err := bcrypt.Cost(dummy())
+	assert.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, dummyCost)
+}
+
+func TestSetCostOutOfRange(t *testing.T) {
+	assert.Error(t, SetCost(bcrypt.MinCost-1))
+	assert.Error(t, SetCost(bcrypt.MaxCost+1))
+}
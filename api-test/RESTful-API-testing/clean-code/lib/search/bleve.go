@@ -0,0 +1,123 @@
+package search
+
+import (
+	"cleancode/models"
+	"strconv"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// fuzziness is the edit distance within which a query term still matches,
+// so one typo per word is tolerated.
+const fuzziness = 1
+
+// fields are the indexed book fields and how much a match in each counts.
+var fields = map[string]float64{
+	"title":  2,
+	"author": 1,
+}
+
+// BleveIndex is a BookIndex embedded in the process.
+type BleveIndex struct {
+	index bleve.Index
+}
+
+// NewMemoryIndex creates an index that lives only as long as the process.
+func NewMemoryIndex() (*BleveIndex, error) {
+	index, err := bleve.NewMemOnly(newMapping())
+	if err != nil {
+		return nil, err
+	}
+	return &BleveIndex{index: index}, nil
+}
+
+// OpenIndex opens the index stored in the directory path, creating it when
+// the directory does not exist yet.
+func OpenIndex(path string) (*BleveIndex, error) {
+	index, err := bleve.Open(path)
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		index, err = bleve.New(path, newMapping())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BleveIndex{index: index}, nil
+}
+
+func newMapping() mapping.IndexMapping {
+	book := bleve.NewDocumentStaticMapping()
+	for field := range fields {
+		text := bleve.NewTextFieldMapping()
+		text.Analyzer = standard.Name
+		book.AddFieldMappingsAt(field, text)
+	}
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = book
+	return indexMapping
+}
+
+func (b *BleveIndex) Index(book models.Book) error {
+	return b.index.Index(documentID(book.ID), map[string]interface{}{
+		"title":  book.Title,
+		"author": book.Author,
+	})
+}
+
+func (b *BleveIndex) Delete(id uint) error {
+	return b.index.Delete(documentID(id))
+}
+
+// Search finds books whose title or author matches any term of text;
+// books matching more of the terms rank higher. Exact matches rank above
+// those that are only within the typo tolerance, and title matches above
+// author matches.
+func (b *BleveIndex) Search(text string, limit int, offset int) (Result, error) {
+	alternatives := []query.Query{}
+	for field, boost := range fields {
+		exact := bleve.NewMatchQuery(text)
+		exact.SetField(field)
+		exact.SetBoost(2 * boost)
+
+		fuzzy := bleve.NewMatchQuery(text)
+		fuzzy.SetField(field)
+		fuzzy.SetFuzziness(fuzziness)
+		fuzzy.SetBoost(boost)
+
+		alternatives = append(alternatives, exact, fuzzy)
+	}
+
+	request := bleve.NewSearchRequestOptions(bleve.NewDisjunctionQuery(alternatives...), limit, offset, false)
+	request.Highlight = bleve.NewHighlightWithStyle(html.Name)
+
+	found, err := b.index.Search(request)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Hits: make([]Hit, 0, len(found.Hits)), Total: found.Total}
+	for _, match := range found.Hits {
+		id, err := strconv.ParseUint(match.ID, 10, 64)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Hits = append(result.Hits, Hit{ID: uint(id), Score: match.Score, Highlights: match.Fragments})
+	}
+	return result, nil
+}
+
+func (b *BleveIndex) Count() (uint64, error) {
+	return b.index.DocCount()
+}
+
+func (b *BleveIndex) Close() error {
+	return b.index.Close()
+}
+
+func documentID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
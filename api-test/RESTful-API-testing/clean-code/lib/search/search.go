@@ -0,0 +1,32 @@
+// Package search keeps a full-text index of the book catalogue next to the
+// database. The database stays the source of truth; the index only maps a
+// query onto the ids of matching books.
+package search
+
+import "cleancode/models"
+
+// Hit is one book matching a query. Highlights holds, per field, the
+// fragments of the field containing the match, with the matched terms
+// wrapped in <mark>.
+type Hit struct {
+	ID         uint
+	Score      float64
+	Highlights map[string][]string
+}
+
+// Result is one page of hits, best match first, and the number of books
+// matching in total.
+type Result struct {
+	Hits  []Hit
+	Total uint64
+}
+
+// BookIndex indexes the title and author of books. Indexing a book that is
+// already indexed replaces it.
+type BookIndex interface {
+	Index(book models.Book) error
+	Delete(id uint) error
+	Search(query string, limit int, offset int) (Result, error)
+	Count() (uint64, error)
+	Close() error
+}
@@ -0,0 +1,241 @@
+// Package validation checks request DTOs against their validate struct tags
+// and reports every invalid field at once.
+package validation
+
+import (
+	"cleancode/lib/isbn"
+	"cleancode/models"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+)
+
+// maxAmount bounds the "amount" rule to what a decimal(12,2) column holds.
+var maxAmount = decimal.New(1, 10)
+
+// FieldError describes one invalid field. Field is the name the client sent,
+// Code the rule that failed (for example "required", "email" or "max").
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is returned by Validate for an invalid request.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	problems := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		problems = append(problems, field.Field+" "+field.Message)
+	}
+	return "validation failed: " + strings.Join(problems, "; ")
+}
+
+// Validator is the echo.Validator of the API.
+type Validator struct {
+	validate *validator.Validate
+}
+
+func New() *Validator {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(fieldName)
+	if err := validate.RegisterValidation("date", isDate); err != nil {
+		panic(err)
+	}
+	if err := validate.RegisterValidation("sort", isSort); err != nil {
+		panic(err)
+	}
+	if err := validate.RegisterValidation("isbn", isISBN); err != nil {
+		panic(err)
+	}
+	if err := validate.RegisterValidation("http_url", isHTTPURL); err != nil {
+		panic(err)
+	}
+	if err := validate.RegisterValidation("amount", isAmount); err != nil {
+		panic(err)
+	}
+	if err := validate.RegisterValidation("max_bytes", isMaxBytes); err != nil {
+		panic(err)
+	}
+	return &Validator{validate: validate}
+}
+
+// Validate returns an *Error listing every invalid field of i.
+func (v *Validator) Validate(i interface{}) error {
+	err := v.validate.Struct(i)
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	fields := make([]FieldError, 0, len(invalid))
+	for _, fieldError := range invalid {
+		fields = append(fields, FieldError{
+			Field:   fieldError.Field(),
+			Code:    fieldError.Tag(),
+			Message: message(fieldError, i),
+		})
+	}
+	return &Error{Fields: fields}
+}
+
+func message(fieldError validator.FieldError, i interface{}) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if isNumber(fieldError.Kind()) {
+			return "must be at least " + fieldError.Param()
+		}
+		if isList(fieldError.Kind()) {
+			return fmt.Sprintf("must have at least %s items", fieldError.Param())
+		}
+		return fmt.Sprintf("must be at least %s characters", fieldError.Param())
+	case "max":
+		if isNumber(fieldError.Kind()) {
+			return "must be at most " + fieldError.Param()
+		}
+		if isList(fieldError.Kind()) {
+			return fmt.Sprintf("must have at most %s items", fieldError.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters", fieldError.Param())
+	case "date":
+		return "must be a date formatted as YYYY-MM-DD"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+	case "sort":
+		return "must be a comma separated list of: " + strings.ReplaceAll(fieldError.Param(), " ", ", ") + ", each optionally prefixed with -"
+	case "isbn":
+		return "must be a valid ISBN-10 or ISBN-13"
+	case "http_url":
+		return "must be an http or https URL"
+	case "amount":
+		return "must be a positive amount with at most 2 decimal places"
+	case "max_bytes":
+		return fmt.Sprintf("must be at most %s bytes", fieldError.Param())
+	case "printascii":
+		return "must only contain printable ASCII characters"
+	case "bcp47_language_tag":
+		return "must be a language tag such as en or pt-BR"
+	case "excluded_with":
+		return "cannot be combined with " + otherField(i, fieldError.Param())
+	case "required_if":
+		params := strings.Fields(fieldError.Param())
+		return "is required when " + otherField(i, params[0]) + " is " + strings.Join(params[1:], " ")
+	case "required_without":
+		return "is required unless " + otherField(i, fieldError.Param()) + " is given"
+	}
+	return "is invalid"
+}
+
+// otherField names the field of i a cross-field rule refers to by its Go
+// name the way requests spell it.
+func otherField(i interface{}, name string) string {
+	t := reflect.Indirect(reflect.ValueOf(i)).Type()
+	if t.Kind() == reflect.Struct {
+		if field, ok := t.FieldByName(name); ok {
+			return fieldName(field)
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// fieldName reports fields by their JSON name, or their query parameter for
+// query strings, falling back to the Go name.
+func fieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "" {
+		name = field.Tag.Get("query")
+	}
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func isList(kind reflect.Kind) bool {
+	return kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isMaxBytes bounds the length of a string in bytes rather than characters,
+// as in `validate:"max_bytes=72"`.
+func isMaxBytes(fl validator.FieldLevel) bool {
+	limit, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		panic(fmt.Sprintf("max_bytes: invalid limit %q", fl.Param()))
+	}
+	return len(fl.Field().String()) <= limit
+}
+
+// isDate accepts dates in models.DateLayout, the only format the API reads.
+func isDate(fl validator.FieldLevel) bool {
+	_, err := time.Parse(models.DateLayout, fl.Field().String())
+	return err == nil
+}
+
+// isSort accepts a comma separated list of distinct columns named in the
+// rule's parameter, each optionally prefixed with "-", as in
+// `validate:"sort=id title"`.
+func isSort(fl validator.FieldLevel) bool {
+	allowed := map[string]bool{}
+	for _, column := range strings.Fields(fl.Param()) {
+		allowed[column] = true
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(fl.Field().String(), ",") {
+		column := strings.TrimPrefix(part, "-")
+		if !allowed[column] || seen[column] {
+			return false
+		}
+		seen[column] = true
+	}
+	return true
+}
+
+func isISBN(fl validator.FieldLevel) bool {
+	return isbn.Valid(fl.Field().String())
+}
+
+// isHTTPURL accepts absolute http and https URLs with a host, leaving out
+// schemes such as javascript: that the generic url rule allows.
+func isHTTPURL(fl validator.FieldLevel) bool {
+	u, err := url.Parse(fl.Field().String())
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isAmount accepts positive decimal amounts of money such as "12.50", with
+// at most two decimal places and below maxAmount.
+func isAmount(fl validator.FieldLevel) bool {
+	amount, err := decimal.NewFromString(fl.Field().String())
+	if err != nil {
+		return false
+	}
+	return amount.IsPositive() && amount.LessThan(maxAmount) && amount.Equal(amount.Truncate(2))
+}
@@ -0,0 +1,177 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bookInput struct {
+	Title       string `json:"title" validate:"required,max=10"`
+	Email       string `json:"email" validate:"omitempty,email"`
+	PublishedAt string `json:"publishedAt" validate:"required,date"`
+	Role        string `json:"role" validate:"omitempty,oneof=admin member"`
+}
+
+func TestValidate(t *testing.T) {
+	v := New()
+
+	assert.NoError(t, v.Validate(bookInput{Title: "chemistry", PublishedAt: "2021-03-01"}))
+
+	err := v.Validate(bookInput{Title: "organic chemistry", Email: "alta", PublishedAt: "2021", Role: "owner"})
+
+	var invalid *Error
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, []FieldError{
+			{Field: "title", Code: "max", Message: "must be at most 10 characters"},
+			{Field: "email", Code: "email", Message: "must be a valid email address"},
+			{Field: "publishedAt", Code: "date", Message: "must be a date formatted as YYYY-MM-DD"},
+			{Field: "role", Code: "oneof", Message: "must be one of: admin, member"},
+		}, invalid.Fields)
+	}
+}
+
+func TestValidateRequired(t *testing.T) {
+	err := New().Validate(&bookInput{})
+
+	var invalid *Error
+	if assert.True(t, errors.As(err, &invalid)) && assert.Len(t, invalid.Fields, 2) {
+		assert.Equal(t, "title", invalid.Fields[0].Field)
+		assert.Equal(t, "required", invalid.Fields[0].Code)
+		assert.Equal(t, "publishedAt", invalid.Fields[1].Field)
+	}
+	assert.Equal(t, "validation failed: title is required; publishedAt is required", err.Error())
+}
+
+func TestValidateRejectsImpossibleDates(t *testing.T) {
+	assert.Error(t, New().Validate(bookInput{Title: "chemistry", PublishedAt: "2021-02-30"}))
+}
+
+type listQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Page   int    `query:"page" validate:"omitempty,excluded_with=Cursor"`
+	Cursor string `query:"cursor"`
+	Sort   string `query:"sort" validate:"omitempty,sort=id title"`
+}
+
+func TestValidateQuery(t *testing.T) {
+	v := New()
+
+	for _, sort := range []string{"id", "-title", "title,-id"} {
+		assert.NoError(t, v.Validate(listQuery{Limit: 10, Sort: sort}), sort)
+	}
+
+	err := v.Validate(listQuery{Limit: 500, Page: 2, Cursor: "abc", Sort: "title,author"})
+
+	var invalid *Error
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, []FieldError{
+			{Field: "limit", Code: "max", Message: "must be at most 100"},
+			{Field: "page", Code: "excluded_with", Message: "cannot be combined with cursor"},
+			{Field: "sort", Code: "sort", Message: "must be a comma separated list of: id, title, each optionally prefixed with -"},
+		}, invalid.Fields)
+	}
+
+	for _, sort := range []string{"title,title", "--id", "id,", " id"} {
+		assert.Error(t, v.Validate(listQuery{Sort: sort}), sort)
+	}
+}
+
+type detailsInput struct {
+	ISBN     string   `json:"isbn" validate:"omitempty,isbn"`
+	CoverURL string   `json:"coverUrl" validate:"omitempty,http_url"`
+	Language string   `json:"language" validate:"omitempty,bcp47_language_tag"`
+	Tags     []string `json:"tags" validate:"omitempty,max=2"`
+}
+
+func TestValidateBookDetails(t *testing.T) {
+	v := New()
+
+	assert.NoError(t, v.Validate(detailsInput{ISBN: "978-0-306-40615-7", CoverURL: "https://example.com/cover.jpg", Language: "pt-BR", Tags: []string{"a"}}))
+	assert.NoError(t, v.Validate(detailsInput{ISBN: "0-306-40615-2"}))
+
+	err := v.Validate(detailsInput{ISBN: "978-0-306-40615-8", CoverURL: "javascript:alert(1)", Language: "not a language", Tags: []string{"a", "b", "c"}})
+
+	var invalid *Error
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, []FieldError{
+			{Field: "isbn", Code: "isbn", Message: "must be a valid ISBN-10 or ISBN-13"},
+			{Field: "coverUrl", Code: "http_url", Message: "must be an http or https URL"},
+			{Field: "language", Code: "bcp47_language_tag", Message: "must be a language tag such as en or pt-BR"},
+			{Field: "tags", Code: "max", Message: "must have at most 2 items"},
+		}, invalid.Fields)
+	}
+}
+
+type creditInput struct {
+	Author    string `json:"author" validate:"required_without=AuthorIDs"`
+	AuthorIDs []uint `json:"authorIds" validate:"required_without=Author"`
+}
+
+func TestValidateNamesOtherFieldAsSent(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Validate(creditInput{AuthorIDs: []uint{1}}))
+
+	err := v.Validate(creditInput{})
+
+	var invalid *Error
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, []FieldError{
+			{Field: "author", Code: "required_without", Message: "is required unless authorIds is given"},
+			{Field: "authorIds", Code: "required_without", Message: "is required unless author is given"},
+		}, invalid.Fields)
+	}
+}
+
+func TestValidateRequiredIfNamesCondition(t *testing.T) {
+	v := New()
+	type list struct {
+		Name string `json:"name" validate:"required_if=Kind custom"`
+		Kind string `json:"kind"`
+	}
+	assert.NoError(t, v.Validate(list{Kind: "reading"}))
+
+	err := v.Validate(list{Kind: "custom"})
+
+	var invalid *Error
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, []FieldError{
+			{Field: "name", Code: "required_if", Message: "is required when kind is custom"},
+		}, invalid.Fields)
+	}
+}
+
+func TestValidateAmount(t *testing.T) {
+	v := New()
+	type payment struct {
+		Amount string `json:"amount" validate:"required,amount"`
+	}
+
+	for _, amount := range []string{"1", "0.5", "12.50", "9999999999.99"} {
+		assert.NoError(t, v.Validate(payment{Amount: amount}), amount)
+	}
+	for _, amount := range []string{"0", "-1", "0.001", "1e2x", "ten", "10000000000"} {
+		err := v.Validate(payment{Amount: amount})
+		var invalid *Error
+		if assert.True(t, errors.As(err, &invalid), amount) {
+			assert.Equal(t, "must be a positive amount with at most 2 decimal places", invalid.Fields[0].Message)
+		}
+	}
+}
+
+func TestValidateMaxBytes(t *testing.T) {
+	v := New()
+	type login struct {
+		Password string `json:"password" validate:"max_bytes=72"`
+	}
+
+	// "é" is two bytes, so 40 of them are under 72 characters but over 72 bytes
+	assert.NoError(t, v.Validate(login{Password: strings.Repeat("é", 36)}))
+	err := v.Validate(login{Password: strings.Repeat("é", 40)})
+	var invalid *Error
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, "must be at most 72 bytes", invalid.Fields[0].Message)
+	}
+}
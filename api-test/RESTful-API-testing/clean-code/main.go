@@ -1,14 +1,50 @@
 package main
 
 import (
+	"cleancode/app"
 	"cleancode/config"
+	"cleancode/lib/passwords"
 	"cleancode/middlewares"
 	"cleancode/routes"
+	"log"
+	"os"
 )
 
 func main() {
-	config.InitDb()
-	e := routes.New()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "promote" {
+		if err := runPromote(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := passwords.SetCost(cfg.BcryptCost); err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := config.OpenDatabase(cfg.DatabaseDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	a, err := app.New(cfg, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	e := routes.New(a)
 	middlewares.LogMiddleware(e)
-	e.Logger.Fatal(e.Start(":8000"))
+	e.Logger.Fatal(e.Start(cfg.ListenAddress))
 }
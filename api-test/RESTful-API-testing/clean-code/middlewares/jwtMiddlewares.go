@@ -1,21 +1,111 @@
 package middlewares
 
 import (
-	"cleancode/constants"
+	"cleancode/config"
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
-func CreateToken(userId int) (string, error) {
+// TokenManager issues and verifies the access tokens described by a
+// config.JWTConfig.
+type TokenManager struct {
+	config config.JWTConfig
+	keys   *KeyRing
+}
+
+func NewTokenManager(jwtConfig config.JWTConfig) (*TokenManager, error) {
+	keys, err := LoadKeyRing(jwtConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenManager{config: jwtConfig, keys: keys}, nil
+}
+
+func (m *TokenManager) Keys() *KeyRing {
+	return m.keys
+}
+
+func (m *TokenManager) Lifetime() time.Duration {
+	return m.config.AccessTokenTTL
+}
+
+func (m *TokenManager) RefreshLifetime() time.Duration {
+	return m.config.RefreshTokenTTL
+}
+
+func (m *TokenManager) CreateToken(userId int, role string) (string, error) {
+	now := time.Now()
+
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
 	claims["userId"] = userId
-	claims["exp"] = time.Now().Add(time.Hour * 1).Unix()
+	claims["role"] = role
+	claims["iss"] = m.config.Issuer
+	if m.config.Audience != "" {
+		claims["aud"] = m.config.Audience
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(m.config.AccessTokenTTL).Unix()
+
+	key := m.keys.SigningKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.SignKey)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(constants.SECRET_JWT))
+// JWT returns the middleware that rejects requests without a valid access
+// token with 401 and stores the parsed token under the "user" context key.
+func (m *TokenManager) JWT() echo.MiddlewareFunc {
+	return middleware.JWTWithConfig(middleware.JWTConfig{
+		ParseTokenFunc: m.parseToken,
+		ErrorHandlerWithContext: func(err error, c echo.Context) error {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing access token").SetInternal(err)
+		},
+	})
+}
+
+func (m *TokenManager) parseToken(auth string, c echo.Context) (interface{}, error) {
+	token, err := jwt.Parse(auth, m.verificationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if !claims.VerifyIssuer(m.config.Issuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if m.config.Audience != "" && !claims.VerifyAudience(m.config.Audience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+
+	return token, nil
+}
+
+// verificationKey picks the key named by the token's kid header and insists
+// the token uses that key's algorithm, so a public key can never be replayed as
+// an HMAC secret. Tokens without a kid predate key rotation and are checked
+// against the HMAC secret.
+func (m *TokenManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := m.keys.Lookup(kid)
+	if kid == "" {
+		key, ok = m.keys.legacyHMACKey()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return key.VerifyKey, nil
 }
 
 func ExtractToken(c echo.Context) int {
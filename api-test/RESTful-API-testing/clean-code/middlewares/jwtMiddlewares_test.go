@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"cleancode/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var jwtConfigTest = config.JWTConfig{
+	Secret:         "0123456789abcdef0123456789abcdef",
+	Issuer:         "cleancode-test",
+	Audience:       "cleancode-test",
+	AccessTokenTTL: time.Hour * 1,
+}
+
+func newTokenManagerTest(t *testing.T, jwtConfig config.JWTConfig) *TokenManager {
+	tokens, err := NewTokenManager(jwtConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func serveWithToken(tokens *TokenManager, token string) (*httptest.ResponseRecorder, error) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %v", token))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := tokens.JWT()(func(c echo.Context) error {
+		return c.JSON(http.StatusOK, ExtractToken(c))
+	})(c)
+	return rec, err
+}
+
+func TestTokenManagerRoundTrip(t *testing.T) {
+	tokens := newTokenManagerTest(t, jwtConfigTest)
+
+	token, err := tokens.CreateToken(7, "member")
+	if assert.NoError(t, err) {
+		rec, err := serveWithToken(tokens, token)
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "7\n", rec.Body.String())
+		}
+	}
+}
+
+func TestTokenManagerRejectsForeignTokens(t *testing.T) {
+	tokens := newTokenManagerTest(t, jwtConfigTest)
+
+	otherSecret := jwtConfigTest
+	otherSecret.Secret = "fedcba9876543210fedcba9876543210"
+
+	otherIssuer := jwtConfigTest
+	otherIssuer.Issuer = "someone-else"
+
+	otherAudience := jwtConfigTest
+	otherAudience.Audience = "another-api"
+
+	expired := jwtConfigTest
+	expired.AccessTokenTTL = -time.Minute
+
+	testCases := []struct {
+		name   string
+		config config.JWTConfig
+	}{
+		{"other secret", otherSecret},
+		{"other issuer", otherIssuer},
+		{"other audience", otherAudience},
+		{"expired", expired},
+	}
+
+	for _, testCase := range testCases {
+		token, err := newTokenManagerTest(t, testCase.config).CreateToken(7, "member")
+		if !assert.NoError(t, err, testCase.name) {
+			continue
+		}
+
+		_, err = serveWithToken(tokens, token)
+		if assert.Error(t, err, testCase.name) {
+			httpError, ok := err.(*echo.HTTPError)
+			if assert.True(t, ok, testCase.name) {
+				assert.Equal(t, http.StatusUnauthorized, httpError.Code, testCase.name)
+			}
+		}
+	}
+}
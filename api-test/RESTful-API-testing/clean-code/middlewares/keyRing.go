@@ -0,0 +1,254 @@
+package middlewares
+
+import (
+	"cleancode/config"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Key is one entry of a KeyRing. SignKey is nil for keys that are only kept
+// to verify tokens signed before a rotation.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+// KeyRing holds the key new tokens are signed with and every key tokens are
+// still accepted from, indexed by the "kid" header.
+type KeyRing struct {
+	signing *Key
+	keys    map[string]*Key
+	order   []string
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeyRing(signing *Key) *KeyRing {
+	ring := &KeyRing{signing: signing, keys: map[string]*Key{}}
+	ring.Add(signing)
+	return ring
+}
+
+// LoadKeyRing signs with the PEM private key in SigningKeyFile when it is set
+// and with the HMAC secret otherwise. VerificationKeyFiles, and the secret when
+// a signing key file is used, stay valid for verification only, which is how
+// keys are rotated without invalidating tokens already handed out.
+func LoadKeyRing(jwtConfig config.JWTConfig) (*KeyRing, error) {
+	signing := NewHMACKey(jwtConfig.Secret)
+	if jwtConfig.SigningKeyFile != "" {
+		var err error
+		signing, err = LoadPEMKey(jwtConfig.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if signing.SignKey == nil {
+			return nil, fmt.Errorf("%s: signing key file must contain a private key", jwtConfig.SigningKeyFile)
+		}
+	}
+
+	ring := NewKeyRing(signing)
+	for _, path := range jwtConfig.VerificationKeyFiles {
+		key, err := LoadPEMKey(path)
+		if err != nil {
+			return nil, err
+		}
+		ring.Add(verificationOnly(key))
+	}
+
+	if jwtConfig.SigningKeyFile != "" && jwtConfig.Secret != "" {
+		ring.Add(verificationOnly(NewHMACKey(jwtConfig.Secret)))
+	}
+
+	return ring, nil
+}
+
+// Add registers a verification key. A key already in the ring under the same
+// id, such as the signing key, is kept.
+func (r *KeyRing) Add(key *Key) {
+	if _, ok := r.keys[key.ID]; !ok {
+		r.keys[key.ID] = key
+		r.order = append(r.order, key.ID)
+	}
+}
+
+func (r *KeyRing) SigningKey() *Key {
+	return r.signing
+}
+
+func (r *KeyRing) Lookup(kid string) (*Key, bool) {
+	key, ok := r.keys[kid]
+	return key, ok
+}
+
+// legacyHMACKey returns the HMAC key used to verify tokens issued before kid
+// headers existed.
+func (r *KeyRing) legacyHMACKey() (*Key, bool) {
+	for _, id := range r.order {
+		if key := r.keys[id]; key.Method == jwt.SigningMethodHS256 {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS lists the public half of every asymmetric key in the ring, signing key
+// first. HMAC keys are never published.
+func (r *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, id := range r.order {
+		if jwk, ok := toJWK(r.keys[id]); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func NewHMACKey(secret string) *Key {
+	sum := sha256.Sum256([]byte(secret))
+	return &Key{
+		ID:        "hs256-" + hex.EncodeToString(sum[:8]),
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   []byte(secret),
+		VerifyKey: []byte(secret),
+	}
+}
+
+// LoadPEMKey reads an RSA or Ed25519 key from a PEM file. Private keys may be
+// PKCS#1 or PKCS#8, public keys PKIX or PKCS#1.
+func LoadPEMKey(path string) (*Key, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key, err := newAsymmetricKey(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+func newAsymmetricKey(parsed interface{}) (*Key, error) {
+	key := &Key{}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.SignKey, key.VerifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.VerifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.SignKey, key.VerifyKey = jwt.SigningMethodEdDSA, k, k.Public().(ed25519.PublicKey)
+	case ed25519.PublicKey:
+		key.Method, key.VerifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	if rsaKey, ok := key.VerifyKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	jwk, _ := toJWK(key)
+	key.ID = thumbprint(jwk)
+	return key, nil
+}
+
+func verificationOnly(key *Key) *Key {
+	return &Key{ID: key.ID, Method: key.Method, VerifyKey: key.VerifyKey}
+}
+
+func toJWK(key *Key) (JWK, bool) {
+	switch public := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+			N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(public),
+		}, true
+	}
+	return JWK{}, false
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint, used as the kid so the same key
+// always gets the same id wherever it is loaded.
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	if jwk.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+
+	canonical, _ := json.Marshal(members)
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,197 @@
+package middlewares
+
+import (
+	"cleancode/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEM(t *testing.T, name string, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	content := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeRSAKey(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		writePEM(t, "rsa.pub.pem", "PUBLIC KEY", public)
+}
+
+func writeEd25519Key(t *testing.T) (string, string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writePEM(t, "ed25519.pem", "PRIVATE KEY", privateDER),
+		writePEM(t, "ed25519.pub.pem", "PUBLIC KEY", publicDER)
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	rsaPrivate, _ := writeRSAKey(t)
+	edPrivate, _ := writeEd25519Key(t)
+
+	testCases := []struct {
+		name      string
+		keyFile   string
+		algorithm string
+		keyType   string
+	}{
+		{"rsa", rsaPrivate, "RS256", "RSA"},
+		{"ed25519", edPrivate, "EdDSA", "OKP"},
+	}
+
+	for _, testCase := range testCases {
+		jwtConfig := jwtConfigTest
+		jwtConfig.Secret = ""
+		jwtConfig.SigningKeyFile = testCase.keyFile
+		tokens := newTokenManagerTest(t, jwtConfig)
+
+		token, err := tokens.CreateToken(7, "member")
+		if !assert.NoError(t, err, testCase.name) {
+			continue
+		}
+
+		parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+		if assert.NoError(t, err, testCase.name) {
+			assert.Equal(t, testCase.algorithm, parsed.Header["alg"], testCase.name)
+			assert.Equal(t, tokens.Keys().SigningKey().ID, parsed.Header["kid"], testCase.name)
+		}
+
+		rec, err := serveWithToken(tokens, token)
+		if assert.NoError(t, err, testCase.name) {
+			assert.Equal(t, http.StatusOK, rec.Code, testCase.name)
+		}
+
+		jwks := tokens.Keys().JWKS()
+		if assert.Len(t, jwks.Keys, 1, testCase.name) {
+			assert.Equal(t, testCase.keyType, jwks.Keys[0].KeyType, testCase.name)
+			assert.Equal(t, testCase.algorithm, jwks.Keys[0].Algorithm, testCase.name)
+			assert.Equal(t, tokens.Keys().SigningKey().ID, jwks.Keys[0].KeyID, testCase.name)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := writeRSAKey(t)
+	newPrivate, _ := writeEd25519Key(t)
+
+	oldConfig := jwtConfigTest
+	oldConfig.SigningKeyFile = oldPrivate
+	oldTokens := newTokenManagerTest(t, oldConfig)
+
+	oldToken, err := oldTokens.CreateToken(7, "member")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyToken, err := newTokenManagerTest(t, jwtConfigTest).CreateToken(7, "member")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedConfig := jwtConfigTest
+	rotatedConfig.SigningKeyFile = newPrivate
+	rotatedConfig.VerificationKeyFiles = []string{oldPublic}
+	rotated := newTokenManagerTest(t, rotatedConfig)
+
+	for name, token := range map[string]string{"old rsa token": oldToken, "hs256 token": legacyToken} {
+		rec, err := serveWithToken(rotated, token)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, http.StatusOK, rec.Code, name)
+		}
+	}
+
+	jwks := rotated.Keys().JWKS()
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, rotated.Keys().SigningKey().ID, jwks.Keys[0].KeyID)
+		assert.Equal(t, oldTokens.Keys().SigningKey().ID, jwks.Keys[1].KeyID)
+	}
+
+	retired := jwtConfigTest
+	retired.Secret = ""
+	retired.SigningKeyFile = newPrivate
+	_, err = serveWithToken(newTokenManagerTest(t, retired), oldToken)
+	assert.Error(t, err)
+}
+
+func TestPublicKeyIsNotAcceptedAsHMACSecret(t *testing.T) {
+	private, public := writeRSAKey(t)
+
+	jwtConfig := jwtConfigTest
+	jwtConfig.Secret = ""
+	jwtConfig.SigningKeyFile = private
+	tokens := newTokenManagerTest(t, jwtConfig)
+
+	publicPEM, err := os.ReadFile(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId": 1,
+		"iss":    jwtConfig.Issuer,
+		"aud":    jwtConfig.Audience,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	forged.Header["kid"] = tokens.Keys().SigningKey().ID
+	token, err := forged.SignedString(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = serveWithToken(tokens, token)
+	assert.Error(t, err)
+}
+
+func TestLoadKeyRingErrors(t *testing.T) {
+	_, public := writeRSAKey(t)
+
+	testCases := []struct {
+		name   string
+		config config.JWTConfig
+	}{
+		{"missing file", config.JWTConfig{SigningKeyFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"public key as signing key", config.JWTConfig{SigningKeyFile: public}},
+		{"not pem", config.JWTConfig{SigningKeyFile: writePEM(t, "junk.pem", "CERTIFICATE", []byte("junk"))}},
+	}
+
+	for _, testCase := range testCases {
+		_, err := LoadKeyRing(testCase.config)
+		assert.Error(t, err, testCase.name)
+	}
+}
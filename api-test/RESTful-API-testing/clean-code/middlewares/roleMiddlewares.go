@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireRole only lets requests through whose access token carries one of
+// roles. It must run after the JWT middleware.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role := ExtractRole(c)
+			for _, allowed := range roles {
+				if role == allowed {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden")
+		}
+	}
+}
+
+func ExtractRole(c echo.Context) string {
+	user, ok := c.Get("user").(*jwt.Token)
+	if ok && user.Valid {
+		claims := user.Claims.(jwt.MapClaims)
+		role, _ := claims["role"].(string)
+		return role
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	tokens := newTokenManagerTest(t, jwtConfigTest)
+
+	testCases := []struct {
+		role         string
+		expectedCode int
+	}{
+		{"admin", http.StatusOK},
+		{"librarian", http.StatusOK},
+		{"member", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+
+	for _, testCase := range testCases {
+		token, err := tokens.CreateToken(7, testCase.role)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %v", token))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := tokens.JWT()(RequireRole("admin", "librarian")(func(c echo.Context) error {
+			return c.String(http.StatusOK, ExtractRole(c))
+		}))
+
+		err = handler(c)
+		if testCase.expectedCode == http.StatusOK {
+			if assert.NoError(t, err, testCase.role) {
+				assert.Equal(t, testCase.expectedCode, rec.Code, testCase.role)
+			}
+			continue
+		}
+
+		httpError, ok := err.(*echo.HTTPError)
+		if assert.True(t, ok, testCase.role) {
+			assert.Equal(t, testCase.expectedCode, httpError.Code, testCase.role)
+		}
+	}
+}
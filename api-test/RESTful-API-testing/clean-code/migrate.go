@@ -0,0 +1,110 @@
+package main
+
+import (
+	"cleancode/config"
+	"cleancode/lib/migrations"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage: cleancode migrate [-dir path] up|down [steps]|status|create <name>
+
+  up              apply every pending migration
+  down [steps]    roll back the last steps migrations (default 1)
+  status          list migrations and whether they are applied
+  create <name>   add empty up and down files for every dialect under -dir`
+
+// runMigrate implements the migrate subcommand. Every command except create
+// connects to the database from the usual configuration.
+func runMigrate(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() { fmt.Fprintln(out, migrateUsage) }
+	dir := flags.String("dir", "lib/migrations/sql", "directory holding the SQL migrations, used by create")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.New("migrate: missing command")
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New("migrate create: expected a migration name")
+		}
+		paths, err := migrations.Create(*dir, args[1])
+		for _, path := range paths {
+			fmt.Fprintln(out, "created", path)
+		}
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	db, err := config.Connect(cfg.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("migrate down: invalid steps %q", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, migration := range rolledBack {
+			fmt.Fprintf(out, "rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Missing {
+				state += " (unknown to this build)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, state)
+		}
+		return w.Flush()
+	}
+
+	flags.Usage()
+	return fmt.Errorf("migrate: unknown command %q", args[0])
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Author is a person credited on books. Names are not unique, since two
+// authors may share one.
+type Author struct {
+	gorm.Model
+	Name string `json:"name" form:"name" gorm:"size:255;index"`
+	Bio  string `json:"bio" form:"bio"`
+}
+
+// BookAuthor links a book to one of its authors. Position orders the
+// co-authors of a book, the first author first.
+type BookAuthor struct {
+	BookID   uint `gorm:"primaryKey;autoIncrement:false"`
+	AuthorID uint `gorm:"primaryKey;autoIncrement:false"`
+	Position int
+}
+
+func (BookAuthor) TableName() string {
+	return "book_authors"
+}
+
+// authorSeparators split a byline such as "Kernighan & Ritchie" or
+// "Abelson, Sussman and Sussman" into names.
+var authorSeparators = regexp.MustCompile(`\s*(?:,|;|&|\band\b)\s*`)
+
+// SplitAuthorNames splits a free text byline into author names, dropping
+// blanks and names repeated in a different case.
+func SplitAuthorNames(byline string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range authorSeparators.Split(byline, -1) {
+		name = strings.Join(strings.Fields(name), " ")
+		key := strings.ToLower(name)
+		if name != "" && !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Byline joins the names of authors as Book.Author holds them.
+func Byline(authors []Author) string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		names = append(names, author.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// CreateAuthorInput is the request body of POST /jwt/authors.
+type CreateAuthorInput struct {
+	Name string `json:"name" form:"name" validate:"required,max=255"`
+	Bio  string `json:"bio" form:"bio" validate:"omitempty,max=5000"`
+}
+
+// UpdateAuthorInput is the request body of PUT /jwt/authors/:id; empty
+// fields are left unchanged.
+type UpdateAuthorInput struct {
+	Name string `json:"name" form:"name" validate:"omitempty,max=255"`
+	Bio  string `json:"bio" form:"bio" validate:"omitempty,max=5000"`
+}
+
+// Author maps the whitelisted fields of the request onto a new author.
+func (in CreateAuthorInput) Author() Author {
+	return Author{Name: strings.TrimSpace(in.Name), Bio: in.Bio}
+}
+
+// Author maps the whitelisted fields of the request onto the changes for an
+// update.
+func (in UpdateAuthorInput) Author() Author {
+	return Author{Name: strings.TrimSpace(in.Name), Bio: in.Bio}
+}
+
+// AuthorListQuery is the query string of GET /authors.
+type AuthorListQuery struct {
+	ListQuery
+	Sort string `query:"sort" validate:"omitempty,sort=id name created_at"`
+	Name string `query:"name" validate:"omitempty,max=255"`
+}
+
+type OutputAuthor struct {
+	ID   uint
+	Name string
+	Bio  string
+}
+
+func (a Author) Output() OutputAuthor {
+	return OutputAuthor{
+		ID:   a.ID,
+		Name: a.Name,
+		Bio:  a.Bio,
+	}
+}
+
+func OutputAuthors(authors []Author) []OutputAuthor {
+	outputs := make([]OutputAuthor, 0, len(authors))
+	for _, author := range authors {
+		outputs = append(outputs, author.Output())
+	}
+	return outputs
+}
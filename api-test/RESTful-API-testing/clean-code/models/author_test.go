@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitAuthorNames(t *testing.T) {
+	testCases := map[string][]string{
+		"urnik":                             {"urnik"},
+		"Kernighan & Ritchie":               {"Kernighan", "Ritchie"},
+		"Abelson, Sussman and  Sussman":     {"Abelson", "Sussman"},
+		"Gamma; Helm;Johnson and Vlissides": {"Gamma", "Helm", "Johnson", "Vlissides"},
+		"Alexandra Anderson":                {"Alexandra Anderson"},
+		" , ":                               {},
+		"":                                  {},
+		"Martin Fowler, martin fowler, KENT BECK": {"Martin Fowler", "KENT BECK"},
+	}
+
+	for byline, expected := range testCases {
+		assert.Equal(t, expected, SplitAuthorNames(byline), byline)
+	}
+}
+
+func TestByline(t *testing.T) {
+	assert.Equal(t, "", Byline(nil))
+	assert.Equal(t, "Kernighan, Ritchie", Byline([]Author{{Name: "Kernighan"}, {Name: "Ritchie"}}))
+}
@@ -1,16 +1,249 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"cleancode/lib/isbn"
+	"math"
+	"strings"
 
+	"gorm.io/gorm"
+)
+
+// Book is a title in the catalogue. ISBN holds the ISBN-13 of the book, or
+// nil when it has none or has been deleted; an ISBN-10 is converted on the
+// way in. Author is the byline kept by the repository from Authors, the
+// book's authors in credit order. Copies counts the book's copies and
+// AvailableCopies those on the shelf that are not held for a reservation;
+// both are kept by the copy repository. ReviewCount and RatingSum total the
+// book's reviews and are kept by the review repository.
 type Book struct {
 	gorm.Model
-	Title        string `json:"title" form:"title"`
-	Author       string `json:"author" form:"author"`
-	Published_at string `json:"publishedAt" form:"publishedAt"`
+	Title        string   `json:"title" form:"title"`
+	Author       string   `json:"author" form:"author"`
+	Authors      []Author `json:"authors" form:"-" gorm:"-"`
+	Published_at Date     `json:"publishedAt" form:"publishedAt" gorm:"type:date"`
+	ISBN         *string  `json:"isbn" form:"isbn" gorm:"size:13;uniqueIndex"`
+	Description  string   `json:"description" form:"description"`
+	Language     string   `json:"language" form:"language" gorm:"size:35"`
+	Pages        int      `json:"pages" form:"pages"`
+	Publisher    string   `json:"publisher" form:"publisher"`
+	CoverURL     string   `json:"coverUrl" form:"coverUrl"`
+	Tags         []Tag    `json:"tags" form:"-" gorm:"many2many:book_tags"`
+
+	Copies          int `json:"copies" form:"-"`
+	AvailableCopies int `json:"availableCopies" form:"-"`
+
+	ReviewCount int `json:"reviewCount" form:"-"`
+	RatingSum   int `json:"-" form:"-"`
+}
+
+// AverageRating is the mean rating of the book's reviews rounded to two
+// decimal places, or 0 when it has none.
+func (b Book) AverageRating() float64 {
+	if b.ReviewCount == 0 {
+		return 0
+	}
+	return math.Round(float64(b.RatingSum)/float64(b.ReviewCount)*100) / 100
+}
+
+// Tag is a label shared by any number of books. Names are stored in the
+// form NormalizeTag returns and are unique.
+type Tag struct {
+	ID   uint   `json:"id" gorm:"primarykey"`
+	Name string `json:"name" gorm:"size:50;uniqueIndex"`
+}
+
+// NormalizeTag is the form tag names are stored and looked up in.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// TagNames lists the names of tags in order.
+func TagNames(tags []Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// CreateBookInput is the request body of POST /jwt/books. The authors are
+// given either by id, in credit order, or as a byline whose names are
+// matched to existing authors or added as new ones.
+type CreateBookInput struct {
+	Title       string   `json:"title" form:"title" validate:"required,max=255"`
+	Author      string   `json:"author" form:"author" validate:"required_without=AuthorIDs,max=255"`
+	AuthorIDs   []uint   `json:"authorIds" form:"authorIds" validate:"required_without=Author,max=20,dive,min=1"`
+	PublishedAt string   `json:"publishedAt" form:"publishedAt" validate:"required,date"`
+	ISBN        string   `json:"isbn" form:"isbn" validate:"omitempty,isbn"`
+	Description string   `json:"description" form:"description" validate:"omitempty,max=5000"`
+	Language    string   `json:"language" form:"language" validate:"omitempty,max=35,bcp47_language_tag"`
+	Pages       int      `json:"pages" form:"pages" validate:"omitempty,min=1,max=100000"`
+	Publisher   string   `json:"publisher" form:"publisher" validate:"omitempty,max=255"`
+	CoverURL    string   `json:"coverUrl" form:"coverUrl" validate:"omitempty,max=2048,http_url"`
+	Tags        []string `json:"tags" form:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+// UpdateBookInput is the request body of PUT /jwt/books/:id; empty fields
+// are left unchanged. Authors and tags replace the book's own when present,
+// so an empty tag list removes them all.
+type UpdateBookInput struct {
+	Title       string   `json:"title" form:"title" validate:"omitempty,max=255"`
+	Author      string   `json:"author" form:"author" validate:"omitempty,max=255"`
+	AuthorIDs   []uint   `json:"authorIds" form:"authorIds" validate:"omitempty,min=1,max=20,dive,min=1"`
+	PublishedAt string   `json:"publishedAt" form:"publishedAt" validate:"omitempty,date"`
+	ISBN        string   `json:"isbn" form:"isbn" validate:"omitempty,isbn"`
+	Description string   `json:"description" form:"description" validate:"omitempty,max=5000"`
+	Language    string   `json:"language" form:"language" validate:"omitempty,max=35,bcp47_language_tag"`
+	Pages       int      `json:"pages" form:"pages" validate:"omitempty,min=1,max=100000"`
+	Publisher   string   `json:"publisher" form:"publisher" validate:"omitempty,max=255"`
+	CoverURL    string   `json:"coverUrl" form:"coverUrl" validate:"omitempty,max=2048,http_url"`
+	Tags        []string `json:"tags" form:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+// Book maps the whitelisted fields of the request onto a new book.
+func (in CreateBookInput) Book() Book {
+	published, _ := ParseDate(in.PublishedAt)
+	return Book{
+		Title:        in.Title,
+		Author:       in.Author,
+		Authors:      authorsByID(in.AuthorIDs),
+		Published_at: published,
+		ISBN:         normalizeISBN(in.ISBN),
+		Description:  in.Description,
+		Language:     in.Language,
+		Pages:        in.Pages,
+		Publisher:    in.Publisher,
+		CoverURL:     in.CoverURL,
+		Tags:         tags(in.Tags),
+	}
+}
+
+// Book maps the whitelisted fields of the request onto the changes for an
+// update.
+func (in UpdateBookInput) Book() Book {
+	published, _ := ParseDate(in.PublishedAt)
+	return Book{
+		Title:        in.Title,
+		Author:       in.Author,
+		Authors:      authorsByID(in.AuthorIDs),
+		Published_at: published,
+		ISBN:         normalizeISBN(in.ISBN),
+		Description:  in.Description,
+		Language:     in.Language,
+		Pages:        in.Pages,
+		Publisher:    in.Publisher,
+		CoverURL:     in.CoverURL,
+		Tags:         tags(in.Tags),
+	}
+}
+
+// authorsByID turns author ids into references to existing authors. An
+// empty list gives nil, so the byline is used instead.
+func authorsByID(ids []uint) []Author {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	authors := make([]Author, 0, len(ids))
+	for _, id := range ids {
+		authors = append(authors, Author{Model: gorm.Model{ID: id}})
+	}
+	return authors
+}
+
+// normalizeISBN returns the ISBN-13 of a validated ISBN, or nil for none.
+func normalizeISBN(raw string) *string {
+	normalized, err := isbn.Normalize(raw)
+	if err != nil {
+		return nil
+	}
+	return &normalized
+}
+
+// tags turns tag names into unsaved tags, dropping blank and repeated names. A nil
+// list stays nil, so updates can tell "no change" from "no tags".
+func tags(names []string) []Tag {
+	if names == nil {
+		return nil
+	}
+
+	tags := make([]Tag, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = NormalizeTag(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			tags = append(tags, Tag{Name: name})
+		}
+	}
+	return tags
+}
+
+// BookListQuery is the query string of GET /books.
+type BookListQuery struct {
+	ListQuery
+	Sort            string `query:"sort" validate:"omitempty,sort=id title author published_at created_at"`
+	Title           string `query:"title" validate:"omitempty,max=255"`
+	Author          string `query:"author" validate:"omitempty,max=255"`
+	PublishedAfter  string `query:"published_after" validate:"omitempty,date"`
+	PublishedBefore string `query:"published_before" validate:"omitempty,date"`
+}
+
+// BookSearchQuery is the query string of GET /books/search.
+type BookSearchQuery struct {
+	Q     string `query:"q" validate:"required,max=200"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Page  int    `query:"page" validate:"omitempty,min=1"`
 }
 
 type OutputBook struct {
+	ID           uint
 	Title        string
 	Author       string
-	Published_at string
+	Authors      []OutputAuthor
+	Published_at Date
+	ISBN         *string
+	Description  string
+	Language     string
+	Pages        int
+	Publisher    string
+	CoverURL     string
+	Tags         []string
+
+	Copies          int
+	AvailableCopies int
+
+	AverageRating float64
+	ReviewCount   int
+}
+
+func (b Book) Output() OutputBook {
+	return OutputBook{
+		ID:           b.ID,
+		Title:        b.Title,
+		Author:       b.Author,
+		Authors:      OutputAuthors(b.Authors),
+		Published_at: b.Published_at,
+		ISBN:         b.ISBN,
+		Description:  b.Description,
+		Language:     b.Language,
+		Pages:        b.Pages,
+		Publisher:    b.Publisher,
+		CoverURL:     b.CoverURL,
+		Tags:         TagNames(b.Tags),
+
+		Copies:          b.Copies,
+		AvailableCopies: b.AvailableCopies,
+
+		AverageRating: b.AverageRating(),
+		ReviewCount:   b.ReviewCount,
+	}
+}
+
+func OutputBooks(books []Book) []OutputBook {
+	outputs := make([]OutputBook, 0, len(books))
+	for _, book := range books {
+		outputs = append(outputs, book.Output())
+	}
+	return outputs
 }
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Copy statuses. A copy is on loan only while a loan of it is out; the
+// other statuses are set by staff.
+const (
+	CopyAvailable = "available"
+	CopyOnLoan    = "on-loan"
+	CopyLost      = "lost"
+	CopyRepair    = "repair"
+)
+
+// Copy conditions, best first.
+const (
+	ConditionNew  = "new"
+	ConditionGood = "good"
+	ConditionFair = "fair"
+	ConditionPoor = "poor"
+)
+
+// BookCopy is one physical copy of a book, known by the barcode on it.
+// Barcodes are stored uppercased and are unique.
+type BookCopy struct {
+	gorm.Model
+	BookID        uint   `gorm:"index"`
+	Barcode       string `gorm:"size:32;uniqueIndex"`
+	ShelfLocation string `gorm:"size:64"`
+	Condition     string `gorm:"size:20"`
+	Status        string `gorm:"size:20"`
+}
+
+// NormalizeBarcode is the form barcodes are stored and looked up in.
+func NormalizeBarcode(barcode string) string {
+	return strings.ToUpper(strings.TrimSpace(barcode))
+}
+
+// CreateBookCopyInput is the request body of POST /jwt/books/:id/copies. A
+// copy is in good condition and on the shelf unless told otherwise.
+type CreateBookCopyInput struct {
+	Barcode       string `json:"barcode" form:"barcode" validate:"required,max=32,printascii"`
+	ShelfLocation string `json:"shelfLocation" form:"shelfLocation" validate:"omitempty,max=64"`
+	Condition     string `json:"condition" form:"condition" validate:"omitempty,oneof=new good fair poor"`
+	Status        string `json:"status" form:"status" validate:"omitempty,oneof=available lost repair"`
+}
+
+// UpdateBookCopyInput is the request body of PUT /jwt/books/:id/copies/:copyId;
+// empty fields are left unchanged. Copies are put on and taken off loan by
+// checkouts and returns only.
+type UpdateBookCopyInput struct {
+	Barcode       string `json:"barcode" form:"barcode" validate:"omitempty,max=32,printascii"`
+	ShelfLocation string `json:"shelfLocation" form:"shelfLocation" validate:"omitempty,max=64"`
+	Condition     string `json:"condition" form:"condition" validate:"omitempty,oneof=new good fair poor"`
+	Status        string `json:"status" form:"status" validate:"omitempty,oneof=available lost repair"`
+}
+
+func (in CreateBookCopyInput) BookCopy() BookCopy {
+	bookCopy := BookCopy{
+		Barcode:       NormalizeBarcode(in.Barcode),
+		ShelfLocation: in.ShelfLocation,
+		Condition:     in.Condition,
+		Status:        in.Status,
+	}
+	if bookCopy.Condition == "" {
+		bookCopy.Condition = ConditionGood
+	}
+	if bookCopy.Status == "" {
+		bookCopy.Status = CopyAvailable
+	}
+	return bookCopy
+}
+
+func (in UpdateBookCopyInput) BookCopy() BookCopy {
+	return BookCopy{
+		Barcode:       NormalizeBarcode(in.Barcode),
+		ShelfLocation: in.ShelfLocation,
+		Condition:     in.Condition,
+		Status:        in.Status,
+	}
+}
+
+// BookCopyListQuery is the query string of GET /jwt/books/:id/copies.
+type BookCopyListQuery struct {
+	ListQuery
+	Sort   string `query:"sort" validate:"omitempty,sort=id barcode shelf_location"`
+	Status string `query:"status" validate:"omitempty,oneof=available on-loan lost repair"`
+}
+
+type OutputBookCopy struct {
+	ID            uint
+	BookID        uint
+	Barcode       string
+	ShelfLocation string
+	Condition     string
+	Status        string
+}
+
+func (c BookCopy) Output() OutputBookCopy {
+	return OutputBookCopy{
+		ID:            c.ID,
+		BookID:        c.BookID,
+		Barcode:       c.Barcode,
+		ShelfLocation: c.ShelfLocation,
+		Condition:     c.Condition,
+		Status:        c.Status,
+	}
+}
+
+func OutputBookCopies(copies []BookCopy) []OutputBookCopy {
+	outputs := make([]OutputBookCopy, 0, len(copies))
+	for _, bookCopy := range copies {
+		outputs = append(outputs, bookCopy.Output())
+	}
+	return outputs
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateJSON(t *testing.T) {
+	body, err := json.Marshal(struct {
+		Published Date
+		Missing   Date
+	}{Published: MustParseDate("2021-03-01")})
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"Published":"2021-03-01","Missing":null}`, string(body))
+	}
+
+	var decoded struct {
+		Published Date
+		Missing   Date
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"Published":"2021-03-01","Missing":null}`), &decoded))
+	assert.Equal(t, MustParseDate("2021-03-01"), decoded.Published)
+	assert.True(t, decoded.Missing.IsZero())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Published":"2021"}`), &decoded))
+}
+
+func TestDateScan(t *testing.T) {
+	expected := MustParseDate("2021-03-01")
+
+	for _, value := range []interface{}{
+		"2021-03-01",
+		[]byte("2021-03-01"),
+		"2021-03-01 00:00:00+00:00",
+		time.Date(2021, 3, 1, 0, 0, 0, 0, time.FixedZone("WIB", 7*60*60)),
+	} {
+		var date Date
+		if assert.NoError(t, date.Scan(value), "%v", value) {
+			assert.Equal(t, expected, date, "%v", value)
+		}
+	}
+
+	var date Date
+	assert.NoError(t, date.Scan(nil))
+	assert.True(t, date.IsZero())
+	assert.Error(t, date.Scan(42))
+
+	value, err := expected.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "2021-03-01", value)
+	value, err = Date{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestCreateBookInputBook(t *testing.T) {
+	book := CreateBookInput{
+		Title:       "physics",
+		Author:      "newton",
+		PublishedAt: "1687-07-05",
+		ISBN:        "0-306-40615-2",
+		Tags:        []string{"Science", " science ", "classics", " "},
+	}.Book()
+
+	assert.Equal(t, MustParseDate("1687-07-05"), book.Published_at)
+	if assert.NotNil(t, book.ISBN) {
+		assert.Equal(t, "9780306406157", *book.ISBN)
+	}
+	assert.Equal(t, []string{"science", "classics"}, TagNames(book.Tags))
+
+	book = CreateBookInput{Title: "physics"}.Book()
+	assert.Nil(t, book.ISBN)
+	assert.Empty(t, book.Tags)
+}
+
+func TestUpdateBookInputTags(t *testing.T) {
+	assert.Nil(t, UpdateBookInput{Title: "physics"}.Book().Tags)
+
+	tags := UpdateBookInput{Tags: []string{}}.Book().Tags
+	assert.NotNil(t, tags)
+	assert.Empty(t, tags)
+}
+
+func TestBookAverageRating(t *testing.T) {
+	assert.Equal(t, 0.0, Book{}.AverageRating())
+	assert.Equal(t, 4.0, Book{ReviewCount: 2, RatingSum: 8}.AverageRating())
+	assert.Equal(t, 3.67, Book{ReviewCount: 3, RatingSum: 11}.AverageRating())
+
+	output := Book{ReviewCount: 3, RatingSum: 7}.Output()
+	assert.Equal(t, 3, output.ReviewCount)
+	assert.Equal(t, 2.33, output.AverageRating)
+}
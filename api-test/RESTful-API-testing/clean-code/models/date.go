@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout is how dates are written in requests, responses and the
+// database.
+const DateLayout = "2006-01-02"
+
+// Date is a calendar day without a time of day or zone. The zero Date is
+// stored as NULL and encoded as JSON null.
+type Date struct {
+	time.Time
+}
+
+// ParseDate reads a date formatted as YYYY-MM-DD.
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{Time: t}, nil
+}
+
+// MustParseDate is ParseDate for dates known to be valid, and panics on any
+// other.
+func MustParseDate(value string) Date {
+	date, err := ParseDate(value)
+	if err != nil {
+		panic(err)
+	}
+	return date
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil || *value == "" {
+		*d = Date{}
+		return nil
+	}
+
+	date, err := ParseDate(*value)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+// Value writes the date as YYYY-MM-DD text, which every supported database
+// accepts for a DATE column and which SQLite compares correctly as text.
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.String(), nil
+}
+
+// Scan reads a DATE column, which drivers return either as a time or as
+// text.
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = Date{}
+		return nil
+	case time.Time:
+		*d = Date{Time: time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)}
+		return nil
+	case []byte:
+		return d.scanText(string(v))
+	case string:
+		return d.scanText(v)
+	}
+	return fmt.Errorf("cannot scan %T into a date", value)
+}
+
+func (d *Date) scanText(value string) error {
+	if value == "" {
+		*d = Date{}
+		return nil
+	}
+	if len(value) > len(DateLayout) {
+		value = value[:len(DateLayout)]
+	}
+
+	date, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
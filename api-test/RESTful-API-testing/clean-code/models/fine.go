@@ -0,0 +1,167 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fine entry kinds. A charge adds to what a user owes; a payment or a
+// waiver takes from it.
+const (
+	FineCharge  = "charge"
+	FinePayment = "payment"
+	FineWaiver  = "waiver"
+)
+
+// FineEntry is one line of a user's fine ledger. Entries are never changed
+// or deleted, so a mistake is corrected by a further entry. Amount is
+// signed: positive for charges, negative for payments and waivers, so the
+// balance is the sum of the amounts. RecordedBy is the librarian who
+// entered it, or nil for charges assessed when an overdue loan is returned.
+type FineEntry struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UserID     uint            `gorm:"index"`
+	LoanID     *uint           `gorm:"index"`
+	Kind       string          `gorm:"size:20"`
+	Amount     decimal.Decimal `gorm:"type:decimal(12,2)"`
+	Note       string          `gorm:"size:255"`
+	RecordedBy *uint
+}
+
+// FeeSchedule is what an overdue loan costs: PerDay for every started day
+// overdue, up to Cap per loan. A zero Cap means no cap.
+type FeeSchedule struct {
+	PerDay decimal.Decimal
+	Cap    decimal.Decimal
+}
+
+// Fee is the fee for a loan returned daysOverdue days late.
+func (s FeeSchedule) Fee(daysOverdue int) decimal.Decimal {
+	if daysOverdue <= 0 {
+		return decimal.Zero
+	}
+
+	fee := s.PerDay.Mul(decimal.New(int64(daysOverdue), 0))
+	if s.Cap.IsPositive() && fee.GreaterThan(s.Cap) {
+		return s.Cap
+	}
+	return fee
+}
+
+// OverdueCharge is the charge for a loan returned late, or nil when the
+// loan was returned on time or costs nothing.
+func (s FeeSchedule) OverdueCharge(loan Loan) *FineEntry {
+	days := loan.DaysOverdue(*loan.ReturnedAt)
+	fee := s.Fee(days)
+	if !fee.IsPositive() {
+		return nil
+	}
+
+	return &FineEntry{
+		UserID: loan.UserID,
+		LoanID: &loan.ID,
+		Kind:   FineCharge,
+		Amount: fee,
+		Note:   fmt.Sprintf("%d days overdue", days),
+	}
+}
+
+// FineBalance is what a user owes: Balance by their ledger and Accruing on
+// overdue loans still out, which is charged when they come back. New
+// checkouts are blocked while the two together exceed Limit.
+type FineBalance struct {
+	UserID   uint
+	Balance  decimal.Decimal
+	Accruing decimal.Decimal
+	Limit    decimal.Decimal
+}
+
+// Blocked reports whether the user owes more than the limit.
+func (b FineBalance) Blocked() bool {
+	return b.Balance.Add(b.Accruing).GreaterThan(b.Limit)
+}
+
+// CreateFineEntryInput is the request body of POST /jwt/users/:id/fines.
+// The amount is always given as a positive number of the library's
+// currency, with at most two decimal places.
+type CreateFineEntryInput struct {
+	Kind   string `json:"kind" form:"kind" validate:"required,oneof=charge payment waiver"`
+	Amount string `json:"amount" form:"amount" validate:"required,amount"`
+	Note   string `json:"note" form:"note" validate:"omitempty,max=255"`
+	LoanID uint   `json:"loanId" form:"loanId" validate:"omitempty,min=1"`
+}
+
+// FineEntry maps the request onto a new entry, negating payments and
+// waivers.
+func (in CreateFineEntryInput) FineEntry() FineEntry {
+	amount, _ := decimal.NewFromString(in.Amount)
+	if in.Kind != FineCharge {
+		amount = amount.Neg()
+	}
+
+	entry := FineEntry{Kind: in.Kind, Amount: amount, Note: in.Note}
+	if in.LoanID != 0 {
+		entry.LoanID = &in.LoanID
+	}
+	return entry
+}
+
+// FineListQuery is the query string of GET /jwt/users/:id/fines.
+type FineListQuery struct {
+	ListQuery
+	Sort string `query:"sort" validate:"omitempty,sort=id created_at"`
+	Kind string `query:"kind" validate:"omitempty,oneof=charge payment waiver"`
+}
+
+// OutputFineEntry gives amounts as strings with two decimal places, so
+// clients never read them into floats by accident.
+type OutputFineEntry struct {
+	ID         uint
+	CreatedAt  time.Time
+	Kind       string
+	Amount     string
+	Note       string
+	LoanID     *uint
+	RecordedBy *uint
+}
+
+func (e FineEntry) Output() OutputFineEntry {
+	return OutputFineEntry{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		Kind:       e.Kind,
+		Amount:     e.Amount.StringFixed(2),
+		Note:       e.Note,
+		LoanID:     e.LoanID,
+		RecordedBy: e.RecordedBy,
+	}
+}
+
+func OutputFineEntries(entries []FineEntry) []OutputFineEntry {
+	outputs := make([]OutputFineEntry, 0, len(entries))
+	for _, entry := range entries {
+		outputs = append(outputs, entry.Output())
+	}
+	return outputs
+}
+
+type OutputFineBalance struct {
+	UserID   uint
+	Balance  string
+	Accruing string
+	Limit    string
+	Blocked  bool
+}
+
+func (b FineBalance) Output() OutputFineBalance {
+	return OutputFineBalance{
+		UserID:   b.UserID,
+		Balance:  b.Balance.StringFixed(2),
+		Accruing: b.Accruing.StringFixed(2),
+		Limit:    b.Limit.StringFixed(2),
+		Blocked:  b.Blocked(),
+	}
+}
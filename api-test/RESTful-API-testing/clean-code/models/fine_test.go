@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeSchedule(t *testing.T) {
+	capped := FeeSchedule{PerDay: decimal.RequireFromString("0.10"), Cap: decimal.RequireFromString("1.00")}
+	uncapped := FeeSchedule{PerDay: decimal.RequireFromString("0.10")}
+
+	for days, expected := range map[int]string{0: "0.00", 1: "0.10", 3: "0.30", 10: "1.00", 11: "1.00"} {
+		assert.Equal(t, expected, capped.Fee(days).StringFixed(2), "%d days", days)
+	}
+	assert.Equal(t, "1.10", uncapped.Fee(11).StringFixed(2))
+
+	// tenths add up exactly, where float64 would drift
+	sum := decimal.Zero
+	for i := 0; i < 3; i++ {
+		sum = sum.Add(uncapped.Fee(1))
+	}
+	assert.True(t, sum.Equal(decimal.RequireFromString("0.3")))
+}
+
+func TestOverdueCharge(t *testing.T) {
+	fees := FeeSchedule{PerDay: decimal.RequireFromString("0.25")}
+	due := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	onTime := due.Add(-time.Hour)
+	late := due.Add(49 * time.Hour)
+
+	assert.Nil(t, fees.OverdueCharge(Loan{DueAt: due, ReturnedAt: &onTime}))
+	assert.Nil(t, FeeSchedule{}.OverdueCharge(Loan{DueAt: due, ReturnedAt: &late}))
+
+	charge := fees.OverdueCharge(Loan{ID: 7, UserID: 2, DueAt: due, ReturnedAt: &late})
+	if assert.NotNil(t, charge) {
+		assert.Equal(t, FineCharge, charge.Kind)
+		assert.Equal(t, uint(2), charge.UserID)
+		assert.Equal(t, uint(7), *charge.LoanID)
+		assert.Equal(t, "0.75", charge.Amount.StringFixed(2))
+		assert.Equal(t, "3 days overdue", charge.Note)
+	}
+}
+
+func TestFineBalanceBlocked(t *testing.T) {
+	limit := decimal.RequireFromString("5")
+	assert.False(t, FineBalance{Balance: decimal.RequireFromString("5"), Limit: limit}.Blocked())
+	assert.True(t, FineBalance{Balance: decimal.RequireFromString("4.50"), Accruing: decimal.RequireFromString("0.51"), Limit: limit}.Blocked())
+}
+
+func TestCreateFineEntryInput(t *testing.T) {
+	charge := CreateFineEntryInput{Kind: FineCharge, Amount: "2.50", LoanID: 3}.FineEntry()
+	assert.Equal(t, "2.5", charge.Amount.String())
+	assert.Equal(t, uint(3), *charge.LoanID)
+
+	payment := CreateFineEntryInput{Kind: FinePayment, Amount: "2.50"}.FineEntry()
+	assert.Equal(t, "-2.5", payment.Amount.String())
+	assert.Nil(t, payment.LoanID)
+	assert.Equal(t, "-2.50", payment.Output().Amount)
+}
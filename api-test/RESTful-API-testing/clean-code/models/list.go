@@ -0,0 +1,9 @@
+package models
+
+// ListQuery is the paging part of the query string of a list endpoint. A
+// page is selected either by number or by the cursor of the previous page.
+type ListQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Page   int    `query:"page" validate:"omitempty,min=1,excluded_with=Cursor"`
+	Cursor string `query:"cursor" validate:"omitempty,max=1024"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Loan statuses as reported by Loan.Status.
+const (
+	LoanActive   = "active"
+	LoanOverdue  = "overdue"
+	LoanReturned = "returned"
+)
+
+// Loan is one copy of a book lent to a user. ReturnedAt is nil while the
+// copy is out. CopyID is nil for loans older than the copy inventory that
+// were returned before it was taken.
+type Loan struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uint `gorm:"index"`
+	BookID     uint `gorm:"index"`
+	Book       Book
+	CopyID     *uint `gorm:"index"`
+	BorrowedAt time.Time
+	DueAt      time.Time
+	ReturnedAt *time.Time
+}
+
+// Status reports whether the loan is returned, or else whether it is past
+// due at now.
+func (l Loan) Status(now time.Time) string {
+	switch {
+	case l.ReturnedAt != nil:
+		return LoanReturned
+	case now.After(l.DueAt):
+		return LoanOverdue
+	}
+	return LoanActive
+}
+
+// DaysOverdue counts the started days between the due date and the return,
+// or now for a loan that is still out.
+func (l Loan) DaysOverdue(now time.Time) int {
+	end := now
+	if l.ReturnedAt != nil {
+		end = *l.ReturnedAt
+	}
+	if !end.After(l.DueAt) {
+		return 0
+	}
+
+	late := end.Sub(l.DueAt)
+	days := int(late / (24 * time.Hour))
+	if late%(24*time.Hour) > 0 {
+		days++
+	}
+	return days
+}
+
+// LoanListQuery is the query string of GET /jwt/loans.
+type LoanListQuery struct {
+	ListQuery
+	Sort string `query:"sort" validate:"omitempty,sort=id due_at borrowed_at"`
+}
+
+type OutputLoan struct {
+	ID          uint
+	BookID      uint
+	CopyID      *uint
+	Title       string
+	BorrowedAt  time.Time
+	DueAt       time.Time
+	ReturnedAt  *time.Time
+	Status      string
+	DaysOverdue int
+}
+
+func (l Loan) Output() OutputLoan {
+	now := time.Now()
+	return OutputLoan{
+		ID:          l.ID,
+		BookID:      l.BookID,
+		CopyID:      l.CopyID,
+		Title:       l.Book.Title,
+		BorrowedAt:  l.BorrowedAt,
+		DueAt:       l.DueAt,
+		ReturnedAt:  l.ReturnedAt,
+		Status:      l.Status(now),
+		DaysOverdue: l.DaysOverdue(now),
+	}
+}
+
+func OutputLoans(loans []Loan) []OutputLoan {
+	outputs := make([]OutputLoan, 0, len(loans))
+	for _, loan := range loans {
+		outputs = append(outputs, loan.Output())
+	}
+	return outputs
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoanStatus(t *testing.T) {
+	due := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	returned := due.Add(-time.Hour)
+	lateReturn := due.Add(49 * time.Hour)
+
+	testCases := []struct {
+		name        string
+		loan        Loan
+		now         time.Time
+		status      string
+		daysOverdue int
+	}{
+		{"before due", Loan{DueAt: due}, due.Add(-time.Minute), LoanActive, 0},
+		{"at due", Loan{DueAt: due}, due, LoanActive, 0},
+		{"a minute late", Loan{DueAt: due}, due.Add(time.Minute), LoanOverdue, 1},
+		{"a day late", Loan{DueAt: due}, due.Add(24 * time.Hour), LoanOverdue, 1},
+		{"returned in time", Loan{DueAt: due, ReturnedAt: &returned}, due.Add(72 * time.Hour), LoanReturned, 0},
+		{"returned late", Loan{DueAt: due, ReturnedAt: &lateReturn}, due.Add(720 * time.Hour), LoanReturned, 3},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.status, testCase.loan.Status(testCase.now), testCase.name)
+		assert.Equal(t, testCase.daysOverdue, testCase.loan.DaysOverdue(testCase.now), testCase.name)
+	}
+}
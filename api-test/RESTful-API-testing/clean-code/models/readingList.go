@@ -0,0 +1,193 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Reading list kinds. A user has at most one list of each kind but custom,
+// which they may have any number of.
+const (
+	ListWantToRead = "want-to-read"
+	ListReading    = "reading"
+	ListFinished   = "finished"
+	ListFavourites = "favourites"
+	ListCustom     = "custom"
+)
+
+// MaxListBooks is the most books one reading list holds.
+const MaxListBooks = 500
+
+// defaultListNames name the lists of the fixed kinds when they are created
+// without a name.
+var defaultListNames = map[string]string{
+	ListWantToRead: "Want to read",
+	ListReading:    "Reading",
+	ListFinished:   "Finished",
+	ListFavourites: "Favourites",
+}
+
+// ReadingList is a named list of books kept by a user. Slug is set when the
+// list is created and is kept on renames, so shared links keep working;
+// only public lists can be read through it by others. BookCount counts
+// Items and is kept by the repository.
+type ReadingList struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint `gorm:"index:idx_reading_lists_user_id,priority:1"`
+	User      User
+	Name      string `gorm:"size:100"`
+	Slug      string `gorm:"size:120;uniqueIndex"`
+	Kind      string `gorm:"size:20;index:idx_reading_lists_user_id,priority:2"`
+	Public    bool
+	BookCount int
+	Items     []ReadingListItem
+}
+
+// ReadingListItem is a book on a reading list. Position orders the books
+// of a list, counting from 1.
+type ReadingListItem struct {
+	ID            uint `gorm:"primarykey"`
+	CreatedAt     time.Time
+	ReadingListID uint `gorm:"uniqueIndex:idx_reading_list_items_book,priority:1"`
+	BookID        uint `gorm:"uniqueIndex:idx_reading_list_items_book,priority:2"`
+	Book          Book
+	Position      int
+}
+
+// ReadingListChanges are the fields of a reading list a user may change;
+// nil and empty fields are left unchanged.
+type ReadingListChanges struct {
+	Name   string
+	Public *bool
+}
+
+// Slugify turns a list name into the readable part of its slug: lowercase
+// ASCII letters and digits, with every other run of characters replaced by
+// a single dash.
+func Slugify(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if slug.Len() >= 100 {
+			break
+		}
+	}
+	if slug.Len() == 0 {
+		return "list"
+	}
+	return slug.String()
+}
+
+// CreateReadingListInput is the request body of POST /jwt/lists. Lists of
+// the fixed kinds are named after their kind unless a name is given.
+type CreateReadingListInput struct {
+	Name   string `json:"name" form:"name" validate:"required_if=Kind custom,max=100"`
+	Kind   string `json:"kind" form:"kind" validate:"required,oneof=want-to-read reading finished favourites custom"`
+	Public bool   `json:"public" form:"public"`
+}
+
+// ReadingList maps the request onto a new list.
+func (in CreateReadingListInput) ReadingList() ReadingList {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		name = defaultListNames[in.Kind]
+	}
+	return ReadingList{Name: name, Kind: in.Kind, Public: in.Public}
+}
+
+// UpdateReadingListInput is the request body of PUT /jwt/lists/:id; absent
+// fields are left unchanged. A list's kind cannot be changed.
+type UpdateReadingListInput struct {
+	Name   string `json:"name" form:"name" validate:"omitempty,max=100"`
+	Public *bool  `json:"public" form:"public"`
+}
+
+func (in UpdateReadingListInput) ReadingListChanges() ReadingListChanges {
+	return ReadingListChanges{Name: strings.TrimSpace(in.Name), Public: in.Public}
+}
+
+// AddListBookInput is the request body of POST /jwt/lists/:id/books. The
+// book goes to the end of the list.
+type AddListBookInput struct {
+	BookID uint `json:"bookId" form:"bookId" validate:"required,min=1"`
+}
+
+// ReorderListInput is the request body of PUT /jwt/lists/:id/books. It
+// names every book of the list once, in their new order.
+type ReorderListInput struct {
+	BookIDs []uint `json:"bookIds" form:"bookIds" validate:"required,max=500,dive,min=1"`
+}
+
+// ReadingListQuery is the query string of GET /jwt/lists.
+type ReadingListQuery struct {
+	ListQuery
+	Sort string `query:"sort" validate:"omitempty,sort=id name created_at"`
+	Kind string `query:"kind" validate:"omitempty,oneof=want-to-read reading finished favourites custom"`
+}
+
+type OutputReadingList struct {
+	ID        uint
+	Name      string
+	Slug      string
+	Kind      string
+	Public    bool
+	Owner     string
+	BookCount int
+	Books     []OutputListBook
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type OutputListBook struct {
+	BookID   uint
+	Position int
+	AddedAt  time.Time
+	Book     OutputBook
+}
+
+// Output lists the books of the list when its items are loaded; lists are
+// listed without them.
+func (l ReadingList) Output() OutputReadingList {
+	output := OutputReadingList{
+		ID:        l.ID,
+		Name:      l.Name,
+		Slug:      l.Slug,
+		Kind:      l.Kind,
+		Public:    l.Public,
+		Owner:     l.User.Name,
+		BookCount: l.BookCount,
+		CreatedAt: l.CreatedAt,
+		UpdatedAt: l.UpdatedAt,
+	}
+	if l.Items != nil {
+		output.Books = make([]OutputListBook, 0, len(l.Items))
+		for _, item := range l.Items {
+			output.Books = append(output.Books, OutputListBook{
+				BookID:   item.BookID,
+				Position: item.Position,
+				AddedAt:  item.CreatedAt,
+				Book:     item.Book.Output(),
+			})
+		}
+	}
+	return output
+}
+
+func OutputReadingLists(lists []ReadingList) []OutputReadingList {
+	outputs := make([]OutputReadingList, 0, len(lists))
+	for _, list := range lists {
+		outputs = append(outputs, list.Output())
+	}
+	return outputs
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Summer Reading":       "summer-reading",
+		"  Sci-Fi & Fantasy!!": "sci-fi-fantasy",
+		"Books 2021":           "books-2021",
+		"Café crème":           "caf-cr-me",
+		"???":                  "list",
+	} {
+		assert.Equal(t, expected, Slugify(name), name)
+	}
+
+	long := Slugify("a very long name that keeps going and going and going and going and going and going and going and going")
+	assert.LessOrEqual(t, len(long), 100)
+}
+
+func TestCreateReadingListInputNamesFixedKinds(t *testing.T) {
+	list := CreateReadingListInput{Kind: ListWantToRead}.ReadingList()
+	assert.Equal(t, "Want to read", list.Name)
+
+	list = CreateReadingListInput{Name: " To buy ", Kind: ListCustom, Public: true}.ReadingList()
+	assert.Equal(t, "To buy", list.Name)
+	assert.True(t, list.Public)
+}
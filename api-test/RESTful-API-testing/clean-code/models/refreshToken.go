@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RefreshToken is the server-side record of an opaque refresh token. Only the
+// SHA-256 hash of the token is stored. Every token issued by rotating another
+// one shares its FamilyID, so a replayed token can revoke the whole chain.
+type RefreshToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	FamilyID  string `gorm:"size:64;index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refreshToken" form:"refreshToken" validate:"required"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Reservation statuses. A reservation waits in line for its book until a
+// returned copy is held for it, then is either fulfilled by a checkout or
+// expires when the hold lapses.
+const (
+	ReservationWaiting   = "waiting"
+	ReservationReady     = "ready"
+	ReservationFulfilled = "fulfilled"
+	ReservationExpired   = "expired"
+)
+
+// Reservation is a user's place in the queue for a book whose copies are
+// all out. ReadyAt and ExpiresAt are set once a copy is held for it.
+// Position is the place in the queue of a waiting reservation, counting
+// from 1; it is not stored.
+type Reservation struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint `gorm:"index"`
+	BookID    uint `gorm:"index"`
+	Book      Book
+	Status    string `gorm:"size:20"`
+	ReadyAt   *time.Time
+	ExpiresAt *time.Time
+	Position  int `gorm:"-"`
+}
+
+// ReservationListQuery is the query string of GET /jwt/users/:id/reservations.
+type ReservationListQuery struct {
+	ListQuery
+	Sort   string `query:"sort" validate:"omitempty,sort=id created_at"`
+	Status string `query:"status" validate:"omitempty,oneof=waiting ready fulfilled expired"`
+}
+
+type OutputReservation struct {
+	ID        uint
+	BookID    uint
+	Title     string
+	Status    string
+	Position  int
+	CreatedAt time.Time
+	ReadyAt   *time.Time
+	ExpiresAt *time.Time
+}
+
+func (r Reservation) Output() OutputReservation {
+	return OutputReservation{
+		ID:        r.ID,
+		BookID:    r.BookID,
+		Title:     r.Book.Title,
+		Status:    r.Status,
+		Position:  r.Position,
+		CreatedAt: r.CreatedAt,
+		ReadyAt:   r.ReadyAt,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+func OutputReservations(reservations []Reservation) []OutputReservation {
+	outputs := make([]OutputReservation, 0, len(reservations))
+	for _, reservation := range reservations {
+		outputs = append(outputs, reservation.Output())
+	}
+	return outputs
+}
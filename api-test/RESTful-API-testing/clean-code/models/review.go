@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Review is a user's rating of a book from 1 to 5 stars, with optional
+// text. A user reviews a book at most once; the review is edited instead.
+// User is loaded for the reviewer's name.
+type Review struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint `gorm:"uniqueIndex:idx_reviews_book_user,priority:2;index"`
+	User      User
+	BookID    uint `gorm:"uniqueIndex:idx_reviews_book_user,priority:1"`
+	Rating    int
+	Text      string
+}
+
+// CreateReviewInput is the request body of POST /jwt/books/:id/reviews.
+type CreateReviewInput struct {
+	Rating int    `json:"rating" form:"rating" validate:"required,min=1,max=5"`
+	Text   string `json:"text" form:"text" validate:"omitempty,max=5000"`
+}
+
+// UpdateReviewInput is the request body of PUT /jwt/reviews/:id; empty
+// fields are left unchanged.
+type UpdateReviewInput struct {
+	Rating int    `json:"rating" form:"rating" validate:"omitempty,min=1,max=5"`
+	Text   string `json:"text" form:"text" validate:"omitempty,max=5000"`
+}
+
+func (in CreateReviewInput) Review() Review {
+	return Review{Rating: in.Rating, Text: in.Text}
+}
+
+func (in UpdateReviewInput) Review() Review {
+	return Review{Rating: in.Rating, Text: in.Text}
+}
+
+// ReviewListQuery is the query string of GET /books/:id/reviews.
+type ReviewListQuery struct {
+	ListQuery
+	Sort string `query:"sort" validate:"omitempty,sort=id created_at rating"`
+}
+
+type OutputReview struct {
+	ID        uint
+	BookID    uint
+	UserID    uint
+	Reviewer  string
+	Rating    int
+	Text      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (r Review) Output() OutputReview {
+	return OutputReview{
+		ID:        r.ID,
+		BookID:    r.BookID,
+		UserID:    r.UserID,
+		Reviewer:  r.User.Name,
+		Rating:    r.Rating,
+		Text:      r.Text,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+func OutputReviews(reviews []Review) []OutputReview {
+	outputs := make([]OutputReview, 0, len(reviews))
+	for _, review := range reviews {
+		outputs = append(outputs, review.Output())
+	}
+	return outputs
+}
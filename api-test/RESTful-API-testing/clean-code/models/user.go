@@ -1,16 +1,136 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdmin     = "admin"
+	RoleLibrarian = "librarian"
+	RoleMember    = "member"
+)
 
 type User struct {
 	gorm.Model
 	Name     string `json:"name" form:"name"`
-	Email    string `json:"email" form:"email"`
+	Email    string `json:"email" form:"email" gorm:"size:255;uniqueIndex"`
 	Password string `json:"password" form:"password"`
-	Token    string `json:"token" form:"token"`
+	Role     string `json:"-" form:"-" gorm:"size:20;not null;default:member"`
+}
+
+// MarshalJSON leaves Password out of every encoded User, so no handler can
+// leak it by returning the model directly. Decoding is unaffected and still
+// reads the password from request bodies.
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	return json.Marshal(struct {
+		user
+		Password string `json:"password,omitempty"`
+	}{user: user(u)})
+}
+
+// NormalizeEmail is the form emails are stored and looked up in, so
+// addresses differing only in case belong to the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ReleasedEmail is what the email of a deleted account is rewritten to, so
+// the address can be registered again while the old row stays unique. It is
+// cut to the 255 characters the column holds; the id prefix keeps it unique.
+// The unique_user_email migration releases the emails of accounts deleted
+// before it in the same form.
+func ReleasedEmail(id uint, email string) string {
+	released := fmt.Sprintf("deleted:%d:%s", id, email)
+	if len(released) > 255 {
+		released = released[:255]
+	}
+	return released
+}
+
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleLibrarian || role == RoleMember
 }
 
 type OutputUser struct {
+	ID    uint
 	Name  string
 	Email string
+	Role  string
+}
+
+func (u User) Output() OutputUser {
+	return OutputUser{
+		ID:    u.ID,
+		Name:  u.Name,
+		Email: u.Email,
+		Role:  u.Role,
+	}
+}
+
+func OutputUsers(users []User) []OutputUser {
+	outputs := make([]OutputUser, 0, len(users))
+	for _, user := range users {
+		outputs = append(outputs, user.Output())
+	}
+	return outputs
+}
+
+// CreateUserInput is the request body of POST /users. Passwords are limited
+// to the 72 bytes bcrypt reads, since it ignores any bytes after them.
+type CreateUserInput struct {
+	Name     string `json:"name" form:"name" validate:"required,max=100"`
+	Email    string `json:"email" form:"email" validate:"required,email,max=255"`
+	Password string `json:"password" form:"password" validate:"required,min=8,max_bytes=72"`
+}
+
+// UpdateUserInput is the request body of PUT /jwt/users/:id; empty fields are
+// left unchanged.
+type UpdateUserInput struct {
+	Name     string `json:"name" form:"name" validate:"omitempty,max=100"`
+	Email    string `json:"email" form:"email" validate:"omitempty,email,max=255"`
+	Password string `json:"password" form:"password" validate:"omitempty,min=8,max_bytes=72"`
+}
+
+// User maps the whitelisted fields of the request onto a new member. The
+// password is left for the caller to hash.
+func (in CreateUserInput) User() User {
+	return User{
+		Name:  in.Name,
+		Email: in.Email,
+		Role:  RoleMember,
+	}
+}
+
+// User maps the whitelisted fields of the request onto the changes for an
+// update. The password is left for the caller to hash.
+func (in UpdateUserInput) User() User {
+	return User{
+		Name:  in.Name,
+		Email: in.Email,
+	}
+}
+
+// LoginInput has no minimum password length, so accounts created before the
+// rule existed can still sign in.
+type LoginInput struct {
+	Email    string `json:"email" form:"email" validate:"required,email"`
+	Password string `json:"password" form:"password" validate:"required,max_bytes=72"`
+}
+
+type RoleInput struct {
+	Role string `json:"role" form:"role" validate:"required,oneof=admin librarian member"`
+}
+
+// UserListQuery is the query string of GET /jwt/users.
+type UserListQuery struct {
+	ListQuery
+	Sort  string `query:"sort" validate:"omitempty,sort=id name email created_at"`
+	Name  string `query:"name" validate:"omitempty,max=100"`
+	Email string `query:"email" validate:"omitempty,max=255"`
+	Role  string `query:"role" validate:"omitempty,oneof=admin librarian member"`
 }
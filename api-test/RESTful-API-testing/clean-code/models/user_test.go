@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserMarshalJSONOmitsSecrets(t *testing.T) {
+	user := User{
+		Name:     "alta",
+		Email:    "alta@gmail.com",
+		Password: "$2a$10$secret",
+	}
+
+	body, err := json.Marshal(user)
+	if assert.NoError(t, err) {
+		assert.NotContains(t, string(body), "password")
+		assert.NotContains(t, string(body), "secret")
+		assert.Contains(t, string(body), `"email":"alta@gmail.com"`)
+	}
+
+	body, err = json.Marshal(&user)
+	if assert.NoError(t, err) {
+		assert.NotContains(t, string(body), "password")
+	}
+}
+
+func TestUserUnmarshalJSONReadsPassword(t *testing.T) {
+	user := User{}
+	err := json.Unmarshal([]byte(`{"email":"alta@gmail.com","password":"123"}`), &user)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "123", user.Password)
+	}
+}
+
+func TestReleasedEmail(t *testing.T) {
+	assert.Equal(t, "deleted:7:alta@gmail.com", ReleasedEmail(7, "alta@gmail.com"))
+
+	long := ReleasedEmail(7, strings.Repeat("a", 250)+"@gmail.com")
+	assert.Len(t, long, 255)
+	assert.True(t, strings.HasPrefix(long, "deleted:7:aaa"))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"cleancode/config"
+	"cleancode/lib/databases"
+	"cleancode/models"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+const promoteUsage = `usage: cleancode promote [-role admin|librarian|member] <email>
+
+  gives the account registered with email the role, admin by default; this
+  is how the first administrator is made, since only admins change roles`
+
+// runPromote implements the promote subcommand. It connects to the database
+// from the usual configuration and refuses a schema with pending migrations.
+func runPromote(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("promote", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() { fmt.Fprintln(out, promoteUsage) }
+	role := flags.String("role", models.RoleAdmin, "role to give the account")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+		return errors.New("promote: expected an email")
+	}
+	if !models.IsValidRole(*role) {
+		return fmt.Errorf("promote: invalid role %q", *role)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	db, err := config.OpenDatabase(cfg.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+
+	user, err := promote(databases.NewGormUserRepository(db), args[0], *role)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s is now %s\n", user.Email, user.Role)
+	return nil
+}
+
+// promote gives the account registered with email the role.
+func promote(users databases.UserRepository, email string, role string) (models.User, error) {
+	user, err := users.GetByEmail(email)
+	if err != nil {
+		return user, fmt.Errorf("promote %s: %w", email, err)
+	}
+	return users.UpdateRole(user.ID, role)
+}
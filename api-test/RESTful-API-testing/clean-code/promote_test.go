@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"cleancode/config"
+	"cleancode/lib/databases"
+	"cleancode/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromote(t *testing.T) {
+	users := databases.NewGormUserRepository(config.InitDbTest())
+	user := models.User{Name: "alta", Email: "alta@gmail.com", Password: "hash", Role: models.RoleMember}
+	require.NoError(t, users.Create(&user))
+
+	promoted, err := promote(users, "Alta@Gmail.com", models.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, promoted.Role)
+
+	stored, err := users.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, stored.Role)
+
+	_, err = promote(users, "nobody@gmail.com", models.RoleAdmin)
+	assert.ErrorIs(t, err, databases.ErrUserNotFound)
+}
+
+func TestRunPromoteRejectsBadArguments(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.EqualError(t, runPromote(nil, out), "promote: expected an email")
+	assert.Contains(t, out.String(), "usage: cleancode promote")
+
+	err := runPromote([]string{"-role", "owner", "alta@gmail.com"}, &bytes.Buffer{})
+	assert.EqualError(t, err, `promote: invalid role "owner"`)
+}
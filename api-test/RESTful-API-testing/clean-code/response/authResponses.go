@@ -0,0 +1,44 @@
+package response
+
+import (
+	"cleancode/models"
+	"time"
+)
+
+type UserSummary struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+type LoginResponse struct {
+	TokenResponse
+	User UserSummary `json:"user"`
+}
+
+func NewTokenResponse(accessToken string, refreshToken string, tokenType string, lifetime time.Duration) TokenResponse {
+	return TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(lifetime / time.Second),
+	}
+}
+
+func NewLoginResponse(tokens TokenResponse, user models.User) LoginResponse {
+	return LoginResponse{
+		TokenResponse: tokens,
+		User: UserSummary{
+			ID:    user.ID,
+			Name:  user.Name,
+			Email: user.Email,
+		},
+	}
+}
@@ -1,5 +1,10 @@
 package response
 
+import (
+	"cleancode/lib/databases"
+	"cleancode/models"
+)
+
 func SuccessResponseBook(message string, book interface{}) map[string]interface{} {
 	var response = map[string]interface{}{
 		"message": message,
@@ -14,3 +19,23 @@ func ErrorResponseBook(message string) map[string]interface{} {
 	}
 	return response
 }
+
+// BookSearchResult is a book found by a search, with its relevance and the
+// matching fragments of its fields, the matched terms wrapped in <mark>.
+type BookSearchResult struct {
+	Book       models.OutputBook   `json:"book"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights"`
+}
+
+func NewBookSearchResult(match databases.BookMatch) BookSearchResult {
+	highlights := match.Highlights
+	if highlights == nil {
+		highlights = map[string][]string{}
+	}
+	return BookSearchResult{
+		Book:       match.Book.Output(),
+		Score:      match.Score,
+		Highlights: highlights,
+	}
+}
@@ -0,0 +1,35 @@
+package response
+
+import "cleancode/lib/databases"
+
+// PageMeta describes the page of a list response.
+type PageMeta struct {
+	Limit      int    `json:"limit"`
+	Page       int    `json:"page,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+func NewPageMeta(page databases.Page) PageMeta {
+	meta := PageMeta{
+		Limit:      page.Limit,
+		Page:       page.Page,
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
+	}
+	if page.Page > 0 {
+		meta.Total = &page.Total
+	}
+	return meta
+}
+
+// PageResponse is a list response with its pagination metadata.
+func PageResponse(message string, data interface{}, page databases.Page) map[string]interface{} {
+	var response = map[string]interface{}{
+		"message": message,
+		"data":    data,
+		"meta":    NewPageMeta(page),
+	}
+	return response
+}
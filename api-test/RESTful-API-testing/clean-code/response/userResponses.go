@@ -14,3 +14,12 @@ func ErrorResponse(message string) map[string]interface{} {
 	}
 	return response
 }
+
+// ValidationErrorResponse lists the invalid fields of a rejected request.
+func ValidationErrorResponse(message string, errors interface{}) map[string]interface{} {
+	var response = map[string]interface{}{
+		"message": message,
+		"errors":  errors,
+	}
+	return response
+}
@@ -1,37 +1,110 @@
 package routes
 
 import (
-	"cleancode/constants"
+	"cleancode/app"
 	"cleancode/controllers"
+	"cleancode/lib/validation"
+	"cleancode/middlewares"
+	"cleancode/models"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 )
 
-func New() *echo.Echo {
+func New(a *app.App) *echo.Echo {
+	bookController := controllers.NewBookController(a.Books)
+	userController := controllers.NewUserController(a.Users, a.RefreshTokens, a.Tokens)
+	authController := controllers.NewAuthController(a.Users, a.RefreshTokens, a.Tokens)
+	authorController := controllers.NewAuthorController(a.Authors)
+	loanController := controllers.NewLoanController(a.Loans)
+	reservationController := controllers.NewReservationController(a.Reservations)
+	copyController := controllers.NewBookCopyController(a.Copies)
+	fineController := controllers.NewFineController(a.Fines)
+	reviewController := controllers.NewReviewController(a.Reviews)
+	listController := controllers.NewReadingListController(a.Lists)
+
 	e := echo.New()
-	e.POST("/login", controllers.LoginUserController)
+	e.Logger = a.Logger
+	e.HTTPErrorHandler = controllers.HTTPErrorHandler
+	e.Validator = validation.New()
+	e.GET("/.well-known/jwks.json", authController.JWKS)
+	e.POST("/login", userController.Login)
+	e.POST("/auth/refresh", authController.Refresh)
+	e.POST("/auth/logout", authController.Logout)
 
 	r := e.Group("/jwt")
-	r.Use(middleware.JWT([]byte(constants.SECRET_JWT)))
+	r.Use(a.Tokens.JWT())
 
 	// // user controller with auth
-	r.GET("/users/:id", controllers.GetSingleUserController)
-	r.GET("/users", controllers.GetAllUsersController)
-	r.DELETE("/users/:id", controllers.DeleteUserController)
-	r.PUT("/users/:id", controllers.UpdateUserController)
+	r.GET("/users/:id", userController.GetSingleUser)
+	r.GET("/users", userController.GetAllUsers)
+	r.DELETE("/users/:id", userController.DeleteUser)
+	r.PUT("/users/:id", userController.UpdateUser)
+
+	// role management, admins only
+	r.PUT("/users/:id/role", userController.UpdateUserRole, middlewares.RequireRole(models.RoleAdmin))
+
+	// // book controller with auth, staff only
+	staff := r.Group("/books", middlewares.RequireRole(models.RoleAdmin, models.RoleLibrarian))
+	staff.POST("", bookController.CreateBook)
+	staff.PUT("/:id", bookController.UpdateBook)
+	staff.DELETE("/:id", bookController.DeleteBook)
+	staff.GET("/:id/copies", copyController.GetBookCopies)
+	staff.GET("/:id/copies/:copyId", copyController.GetSingleBookCopy)
+	staff.POST("/:id/copies", copyController.CreateBookCopy)
+	staff.PUT("/:id/copies/:copyId", copyController.UpdateBookCopy)
+	staff.DELETE("/:id/copies/:copyId", copyController.DeleteBookCopy)
+
+	// loan controller with auth
+	r.GET("/loans", loanController.GetMyLoans)
+	r.POST("/books/:id/loans", loanController.CheckoutBook)
+	r.POST("/loans/:id/return", loanController.ReturnLoan)
+
+	// reservation controller with auth
+	r.POST("/books/:id/reservations", reservationController.ReserveBook)
+	r.GET("/users/:id/reservations", reservationController.GetUserReservations)
 
-	// // book controller with auth
-	r.POST("/books", controllers.CreateBookControllers)
-	r.PUT("/books/:id", controllers.UpdateBookController)
-	r.DELETE("/books/:id", controllers.DeleteBookController)
+	// fine controller with auth, entries by staff only
+	r.GET("/users/:id/fines", fineController.GetUserFines)
+	r.GET("/users/:id/fines/balance", fineController.GetUserFineBalance)
+	r.POST("/users/:id/fines", fineController.RecordFine, middlewares.RequireRole(models.RoleAdmin, models.RoleLibrarian))
+
+	// review controller with auth, changes by the author only
+	r.POST("/books/:id/reviews", reviewController.CreateReview)
+	r.PUT("/reviews/:id", reviewController.UpdateReview)
+	r.DELETE("/reviews/:id", reviewController.DeleteReview)
+
+	// reading list controller with auth, changes by the owner only
+	r.GET("/lists", listController.GetMyLists)
+	r.POST("/lists", listController.CreateList)
+	r.GET("/lists/:id", listController.GetList)
+	r.PUT("/lists/:id", listController.UpdateList)
+	r.DELETE("/lists/:id", listController.DeleteList)
+	r.POST("/lists/:id/books", listController.AddListBook)
+	r.PUT("/lists/:id/books", listController.ReorderList)
+	r.DELETE("/lists/:id/books/:bookId", listController.RemoveListBook)
+
+	// author controller with auth, staff only
+	authorStaff := r.Group("/authors", middlewares.RequireRole(models.RoleAdmin, models.RoleLibrarian))
+	authorStaff.POST("", authorController.CreateAuthor)
+	authorStaff.PUT("/:id", authorController.UpdateAuthor)
+	authorStaff.DELETE("/:id", authorController.DeleteAuthor)
 
 	// user controller without auth
-	e.POST("/users", controllers.CreateUserControllers)
+	e.POST("/users", userController.CreateUser)
 
 	// book controller without auth
-	e.GET("/books", controllers.GetAllBooksController)
-	e.GET("/books/:id", controllers.GetSingleBookController)
+	e.GET("/books", bookController.GetAllBooks)
+	e.GET("/books/search", bookController.SearchBooks)
+	e.GET("/books/:id", bookController.GetSingleBook)
+	e.GET("/books/:id/reviews", reviewController.GetBookReviews)
+
+	// reading list controller without auth, public lists only
+	e.GET("/lists/:slug", listController.GetPublicList)
+
+	// author controller without auth
+	e.GET("/authors", authorController.GetAllAuthors)
+	e.GET("/authors/:id", authorController.GetSingleAuthor)
+	e.GET("/authors/:id/books", authorController.GetAuthorBooks)
 
 	return e
 }
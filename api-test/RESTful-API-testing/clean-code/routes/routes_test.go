@@ -0,0 +1,467 @@
+package routes
+
+import (
+	"bytes"
+	"cleancode/app"
+	"cleancode/config"
+	"cleancode/middlewares"
+	"cleancode/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var configTest = config.Config{
+	JWT: config.JWTConfig{
+		Secret:          "0123456789abcdef0123456789abcdef",
+		Issuer:          "cleancode-test",
+		AccessTokenTTL:  time.Hour * 1,
+		RefreshTokenTTL: time.Hour * 24,
+	},
+	Loans: config.LoanConfig{
+		Period:     time.Hour * 24 * 14,
+		Limit:      2,
+		HoldPeriod: time.Hour * 24 * 3,
+	},
+	Fines: config.FineConfig{
+		PerDay: decimal.New(50, -2),
+		Limit:  decimal.New(1, 0),
+	},
+}
+
+func InitAppTest(t *testing.T) *app.App {
+	a, err := app.New(configTest, config.InitDbTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func InsertDataForRoutes(db *gorm.DB) {
+	db.Save(&models.User{Name: "Alta", Email: "alta@gmail.com", Password: "123", Role: models.RoleMember})
+	db.Save(&models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")})
+	db.Save(&models.Author{Name: "budi"})
+}
+
+func TestRoleAccessPerRoute(t *testing.T) {
+	routes := []struct {
+		method      string
+		path        string
+		body        string
+		allowed     []string
+		allowedCode int
+	}{
+		{http.MethodPost, "/jwt/books", `{"title":"physics","author":"alta","publishedAt":"2021-01-01"}`, []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusCreated},
+		{http.MethodPut, "/jwt/books/1", `{"title":"biology"}`, []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusOK},
+		{http.MethodDelete, "/jwt/books/1", "", []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusOK},
+		{http.MethodGet, "/jwt/books/1/copies", "", []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusOK},
+		{http.MethodPost, "/jwt/books/1/copies", `{"barcode":"ab-1","shelfLocation":"S1"}`, []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusCreated},
+		{http.MethodPost, "/jwt/authors", `{"name":"alta"}`, []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusCreated},
+		{http.MethodPut, "/jwt/authors/1", `{"bio":"chemist"}`, []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusOK},
+		{http.MethodDelete, "/jwt/authors/1", "", []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusOK},
+		{http.MethodPost, "/jwt/users/1/fines", `{"kind":"charge","amount":"2.50"}`, []string{models.RoleAdmin, models.RoleLibrarian}, http.StatusCreated},
+		{http.MethodPut, "/jwt/users/1/role", `{"role":"librarian"}`, []string{models.RoleAdmin}, http.StatusOK},
+	}
+
+	roles := []string{models.RoleAdmin, models.RoleLibrarian, models.RoleMember, ""}
+
+	tokens, err := middlewares.NewTokenManager(configTest.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range routes {
+		for _, role := range roles {
+			name := fmt.Sprintf("%s %s as %q", route.method, route.path, role)
+
+			a := InitAppTest(t)
+			InsertDataForRoutes(a.DB)
+			e := New(a)
+
+			token, err := tokens.CreateToken(1, role)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(route.method, route.path, bytes.NewBufferString(route.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			expectedCode := http.StatusForbidden
+			for _, allowed := range route.allowed {
+				if role == allowed {
+					expectedCode = route.allowedCode
+				}
+			}
+
+			assert.Equal(t, expectedCode, rec.Code, name)
+			if expectedCode != http.StatusForbidden {
+				assert.Contains(t, rec.Body.String(), `"message":"success"`, name)
+			} else {
+				assert.Contains(t, rec.Body.String(), `"message":"forbidden"`, name)
+			}
+		}
+	}
+}
+
+func TestRoleRoutesRequireToken(t *testing.T) {
+	e := New(InitAppTest(t))
+
+	req := httptest.NewRequest(http.MethodDelete, "/jwt/books/1", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAppsAreIsolated(t *testing.T) {
+	first := InitAppTest(t)
+	second := InitAppTest(t)
+	InsertDataForRoutes(first.DB)
+
+	for _, testCase := range []struct {
+		app      *app.App
+		expected string
+	}{
+		{first, "chemistry"},
+		{second, `"data":[]`},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		rec := httptest.NewRecorder()
+		New(testCase.app).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), testCase.expected)
+	}
+}
+
+func TestSearchBooksRoute(t *testing.T) {
+	a := InitAppTest(t)
+	if err := a.Books.Create(&models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/books/search?q=chemistry", nil)
+	rec := httptest.NewRecorder()
+	New(a).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"title":["\u003cmark\u003echemistry\u003c/mark\u003e"]`)
+}
+
+func TestAuthorBooksRoute(t *testing.T) {
+	a := InitAppTest(t)
+	book := models.Book{Title: "the c programming language", Author: "Kernighan & Ritchie", Published_at: models.MustParseDate("1978-02-22")}
+	if err := a.Books.Create(&book); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		"/books/1":           `"Authors":[{"ID":1,"Name":"Kernighan","Bio":""},{"ID":2,"Name":"Ritchie","Bio":""}]`,
+		"/authors?name=ritc": `"data":[{"ID":2,"Name":"Ritchie","Bio":""}]`,
+		"/authors/2/books":   `"Title":"the c programming language"`,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		New(a).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), expected, path)
+	}
+}
+
+func TestLoanRoutes(t *testing.T) {
+	a := InitAppTest(t)
+	e := New(a)
+	users := []models.User{{Name: "Alta", Email: "alta@gmail.com", Role: models.RoleMember}, {Name: "Budi", Email: "budi@gmail.com", Role: models.RoleMember}}
+	if err := a.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Books.Create(&models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Copies.Create(&models.BookCopy{BookID: 1, Barcode: "AB-1", Status: models.CopyAvailable}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := middlewares.NewTokenManager(configTest.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		method   string
+		path     string
+		userId   uint
+		code     int
+		expected string
+	}{
+		{http.MethodPost, "/jwt/books/1/loans", 1, http.StatusCreated, `"Status":"active"`},
+		{http.MethodPost, "/jwt/books/1/loans", 2, http.StatusConflict, "no copy of this book is available"},
+		{http.MethodGet, "/jwt/loans", 1, http.StatusOK, `"Title":"chemistry"`},
+		{http.MethodGet, "/jwt/loans", 2, http.StatusOK, `"data":[]`},
+		{http.MethodGet, "/books/1", 0, http.StatusOK, `"Copies":1,"AvailableCopies":0`},
+		{http.MethodGet, "/jwt/loans", 1, http.StatusOK, `"CopyID":1`},
+		{http.MethodPost, "/jwt/loans/1/return", 2, http.StatusForbidden, "forbidden"},
+		{http.MethodPost, "/jwt/loans/1/return", 1, http.StatusOK, `"Status":"returned"`},
+		{http.MethodPost, "/jwt/loans/1/return", 1, http.StatusConflict, "loan is already returned"},
+		{http.MethodPost, "/jwt/books/1/loans", 2, http.StatusCreated, `"Status":"active"`},
+	} {
+		name := fmt.Sprintf("%s %s as user %d", step.method, step.path, step.userId)
+		req := httptest.NewRequest(step.method, step.path, nil)
+		if step.userId != 0 {
+			token, err := tokens.CreateToken(int(step.userId), models.RoleMember)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, step.code, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), step.expected, name)
+	}
+}
+
+func TestReservationRoutes(t *testing.T) {
+	a := InitAppTest(t)
+	e := New(a)
+	users := []models.User{{Name: "Alta", Email: "alta@gmail.com", Role: models.RoleMember}, {Name: "Budi", Email: "budi@gmail.com", Role: models.RoleMember}}
+	if err := a.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Books.Create(&models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Copies.Create(&models.BookCopy{BookID: 1, Barcode: "AB-1", Status: models.CopyAvailable}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := middlewares.NewTokenManager(configTest.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		method   string
+		path     string
+		userId   uint
+		code     int
+		expected string
+	}{
+		{http.MethodPost, "/jwt/books/1/reservations", 2, http.StatusConflict, "a copy of this book is available to borrow"},
+		{http.MethodPost, "/jwt/books/1/loans", 1, http.StatusCreated, `"Status":"active"`},
+		{http.MethodPost, "/jwt/books/1/reservations", 2, http.StatusCreated, `"Status":"waiting","Position":1`},
+		{http.MethodGet, "/jwt/users/2/reservations", 1, http.StatusForbidden, "forbidden"},
+		{http.MethodPost, "/jwt/loans/1/return", 1, http.StatusOK, `"Status":"returned"`},
+		{http.MethodGet, "/jwt/users/2/reservations?status=ready", 2, http.StatusOK, `"Title":"chemistry","Status":"ready"`},
+		{http.MethodPost, "/jwt/books/1/loans", 1, http.StatusConflict, "no copy of this book is available"},
+		{http.MethodPost, "/jwt/books/1/loans", 2, http.StatusCreated, `"Status":"active"`},
+		{http.MethodGet, "/jwt/users/2/reservations", 2, http.StatusOK, `"Status":"fulfilled"`},
+	} {
+		name := fmt.Sprintf("%s %s as user %d", step.method, step.path, step.userId)
+		token, err := tokens.CreateToken(int(step.userId), models.RoleMember)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(step.method, step.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, step.code, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), step.expected, name)
+	}
+}
+
+func TestFineRoutes(t *testing.T) {
+	a := InitAppTest(t)
+	e := New(a)
+	users := []models.User{{Name: "Alta", Email: "alta@gmail.com", Role: models.RoleMember}, {Name: "Budi", Email: "budi@gmail.com", Role: models.RoleLibrarian}}
+	if err := a.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Books.Create(&models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Copies.Create(&models.BookCopy{BookID: 1, Barcode: "AB-1", Status: models.CopyAvailable}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := middlewares.NewTokenManager(configTest.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		method   string
+		path     string
+		body     string
+		userId   uint
+		role     string
+		code     int
+		expected string
+	}{
+		{http.MethodPost, "/jwt/users/1/fines", `{"kind":"charge","amount":"1.10","note":"torn page"}`, 2, models.RoleLibrarian, http.StatusCreated, `"Amount":"1.10"`},
+		{http.MethodGet, "/jwt/users/1/fines/balance", "", 1, models.RoleMember, http.StatusOK, `"Balance":"1.10","Accruing":"0.00","Limit":"1.00","Blocked":true`},
+		{http.MethodPost, "/jwt/books/1/loans", "", 1, models.RoleMember, http.StatusConflict, "outstanding fines exceed the limit for borrowing"},
+		{http.MethodPost, "/jwt/users/1/fines", `{"kind":"payment","amount":"0.10"}`, 1, models.RoleMember, http.StatusForbidden, "forbidden"},
+		{http.MethodPost, "/jwt/users/1/fines", `{"kind":"payment","amount":"0.10"}`, 2, models.RoleLibrarian, http.StatusCreated, `"Amount":"-0.10"`},
+		{http.MethodPost, "/jwt/books/1/loans", "", 1, models.RoleMember, http.StatusCreated, `"Status":"active"`},
+		{http.MethodGet, "/jwt/users/1/fines?kind=payment", "", 1, models.RoleMember, http.StatusOK, `"RecordedBy":2`},
+		{http.MethodGet, "/jwt/users/1/fines/balance", "", 2, models.RoleMember, http.StatusForbidden, "forbidden"},
+	} {
+		name := fmt.Sprintf("%s %s as user %d", step.method, step.path, step.userId)
+		token, err := tokens.CreateToken(int(step.userId), step.role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(step.method, step.path, bytes.NewBufferString(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, step.code, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), step.expected, name)
+	}
+}
+
+func TestReviewRoutes(t *testing.T) {
+	a := InitAppTest(t)
+	e := New(a)
+	users := []models.User{{Name: "Alta", Email: "alta@gmail.com", Role: models.RoleMember}, {Name: "Budi", Email: "budi@gmail.com", Role: models.RoleMember}}
+	if err := a.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Books.Create(&models.Book{Title: "chemistry", Author: "urnik", Published_at: models.MustParseDate("2021-01-01")}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := middlewares.NewTokenManager(configTest.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		method   string
+		path     string
+		body     string
+		userId   uint
+		code     int
+		expected string
+	}{
+		{http.MethodPost, "/jwt/books/1/reviews", `{"rating":5,"text":"clear and thorough"}`, 1, http.StatusCreated, `"Reviewer":"Alta","Rating":5`},
+		{http.MethodPost, "/jwt/books/1/reviews", `{"rating":2}`, 2, http.StatusCreated, `"Reviewer":"Budi","Rating":2`},
+		{http.MethodPost, "/jwt/books/1/reviews", `{"rating":1}`, 2, http.StatusConflict, "a review of this book by this user already exists"},
+		{http.MethodGet, "/books/1", "", 0, http.StatusOK, `"AverageRating":3.5,"ReviewCount":2`},
+		{http.MethodPut, "/jwt/reviews/2", `{"rating":4}`, 1, http.StatusForbidden, "forbidden"},
+		{http.MethodPut, "/jwt/reviews/2", `{"rating":4}`, 2, http.StatusOK, `"Rating":4`},
+		{http.MethodGet, "/books/1/reviews?sort=rating&limit=1", "", 0, http.StatusOK, `"Rating":4`},
+		{http.MethodDelete, "/jwt/reviews/1", "", 2, http.StatusForbidden, "forbidden"},
+		{http.MethodDelete, "/jwt/reviews/1", "", 1, http.StatusOK, "deleted"},
+		{http.MethodGet, "/books/1", "", 0, http.StatusOK, `"AverageRating":4,"ReviewCount":1`},
+		{http.MethodGet, "/books/9/reviews", "", 0, http.StatusNotFound, "book not found"},
+	} {
+		name := fmt.Sprintf("%s %s as user %d", step.method, step.path, step.userId)
+		req := httptest.NewRequest(step.method, step.path, bytes.NewBufferString(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		if step.userId != 0 {
+			token, err := tokens.CreateToken(int(step.userId), models.RoleMember)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, step.code, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), step.expected, name)
+	}
+}
+
+func TestReadingListRoutes(t *testing.T) {
+	a := InitAppTest(t)
+	e := New(a)
+	users := []models.User{{Name: "Alta", Email: "alta@gmail.com", Role: models.RoleMember}, {Name: "Budi", Email: "budi@gmail.com", Role: models.RoleMember}}
+	if err := a.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"chemistry", "physics"} {
+		if err := a.Books.Create(&models.Book{Title: title, Author: "urnik", Published_at: models.MustParseDate("2021-01-01")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tokens, err := middlewares.NewTokenManager(configTest.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(method string, path string, body string, userId uint) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if userId != 0 {
+			token, err := tokens.CreateToken(int(userId), models.RoleMember)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/jwt/lists", `{"name":"Summer Reading","kind":"custom"}`, 1)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var created struct {
+		Data struct {
+			Slug string
+		}
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Regexp(t, `^summer-reading-[0-9a-f]{8}$`, created.Data.Slug)
+	shared := "/lists/" + created.Data.Slug
+
+	for _, step := range []struct {
+		method   string
+		path     string
+		body     string
+		userId   uint
+		code     int
+		expected string
+	}{
+		{http.MethodPost, "/jwt/lists/1/books", `{"bookId":1}`, 1, http.StatusOK, `"BookCount":1`},
+		{http.MethodPost, "/jwt/lists/1/books", `{"bookId":2}`, 1, http.StatusOK, `"BookCount":2`},
+		{http.MethodPost, "/jwt/lists/1/books", `{"bookId":2}`, 2, http.StatusForbidden, "forbidden"},
+		{http.MethodPut, "/jwt/lists/1/books", `{"bookIds":[2,1]}`, 1, http.StatusOK, `"BookID":2,"Position":1`},
+		{http.MethodGet, shared, "", 0, http.StatusNotFound, "reading list not found"},
+		{http.MethodGet, "/jwt/lists/1", "", 2, http.StatusForbidden, "forbidden"},
+		{http.MethodPut, "/jwt/lists/1", `{"public":true}`, 1, http.StatusOK, `"Public":true`},
+		{http.MethodGet, shared, "", 0, http.StatusOK, `"Owner":"Alta"`},
+		{http.MethodGet, "/jwt/lists/1", "", 2, http.StatusOK, `"Title":"physics"`},
+		{http.MethodDelete, "/jwt/lists/1/books/2", "", 1, http.StatusOK, `"BookID":1,"Position":1`},
+		{http.MethodGet, "/jwt/lists?kind=custom", "", 1, http.StatusOK, `"Name":"Summer Reading"`},
+		{http.MethodGet, "/jwt/lists", "", 2, http.StatusOK, `"data":[]`},
+		{http.MethodDelete, "/jwt/lists/1", "", 1, http.StatusOK, "deleted"},
+		{http.MethodGet, shared, "", 0, http.StatusNotFound, "reading list not found"},
+	} {
+		name := fmt.Sprintf("%s %s as user %d", step.method, step.path, step.userId)
+		rec := serve(step.method, step.path, step.body, step.userId)
+
+		assert.Equal(t, step.code, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), step.expected, name)
+	}
+}